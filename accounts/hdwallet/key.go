@@ -0,0 +1,329 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package hdwallet derives keys deterministically from a BIP-39 mnemonic,
+// following BIP-32 and the BIP-44 paths of MOAC accounts.
+package hdwallet
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"math/big"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/crypto"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"golang.org/x/crypto/ripemd160"
+)
+
+// HardenedKeyStart is the index of the first hardened child key
+const HardenedKeyStart uint32 = 0x80000000
+
+const (
+	serializedKeyLen = 78
+	minSeedLen       = 16
+	maxSeedLen       = 64
+)
+
+var (
+	// PrivateVersion and PublicVersion are the version bytes of serialized
+	// extended keys (xprv and xpub)
+	PrivateVersion = [4]byte{0x04, 0x88, 0xad, 0xe4}
+	PublicVersion  = [4]byte{0x04, 0x88, 0xb2, 0x1e}
+
+	masterKey = []byte("Bitcoin seed")
+)
+
+var (
+	// ErrInvalidSeed is returned for seeds shorter than 128 bits or longer
+	// than 512 bits
+	ErrInvalidSeed = errors.New("Invalid seed length")
+	// ErrInvalidChild is returned in the very unlikely case where the
+	// derived key is not valid, the next index should be used instead
+	ErrInvalidChild = errors.New("Invalid child key, use the next index")
+	// ErrHardenedFromPublic is returned when deriving a hardened child from
+	// a public extended key
+	ErrHardenedFromPublic = errors.New("Cannot derive a hardened key from a public key")
+	// ErrNotPrivate is returned when asking the private key of a public
+	// extended key
+	ErrNotPrivate = errors.New("Not a private extended key")
+	// ErrInvalidKey is returned for malformed serialized extended keys
+	ErrInvalidKey = errors.New("Invalid extended key")
+	// ErrDeriveBeyondMaxDepth is returned when deriving a child of a key at
+	// depth 255
+	ErrDeriveBeyondMaxDepth = errors.New("Cannot derive a key with more than 255 indices in its path")
+)
+
+var secp256k1N = secp256k1.S256().N
+
+// ExtendedKey is a BIP-32 private or public extended key
+type ExtendedKey struct {
+	key       []byte // 32 bytes private key, or 33 bytes compressed public key
+	chainCode []byte
+	parentFP  []byte
+	depth     uint8
+	childNum  uint32
+	isPrivate bool
+}
+
+// NewMaster returns the master private key of seed
+func NewMaster(seed []byte) (*ExtendedKey, error) {
+	if len(seed) < minSeedLen || len(seed) > maxSeedLen {
+		return nil, ErrInvalidSeed
+	}
+	i := hmacSHA512(masterKey, seed)
+	if !validPrivateKey(i[:32]) {
+		return nil, ErrInvalidSeed
+	}
+	return &ExtendedKey{
+		key:       i[:32],
+		chainCode: i[32:],
+		parentFP:  []byte{0, 0, 0, 0},
+		isPrivate: true,
+	}, nil
+}
+
+// IsPrivate tells if the extended key holds a private key
+func (k *ExtendedKey) IsPrivate() bool {
+	return k.isPrivate
+}
+
+// Depth returns the number of derivations from the master key
+func (k *ExtendedKey) Depth() uint8 {
+	return k.depth
+}
+
+// ChildIndex returns the index the key was derived with, 0 for the master key
+func (k *ExtendedKey) ChildIndex() uint32 {
+	return k.childNum
+}
+
+// Child derives the child key of index i, which is hardened from
+// HardenedKeyStart on. The child of a public key is a public key.
+func (k *ExtendedKey) Child(i uint32) (*ExtendedKey, error) {
+	if k.depth == 255 {
+		return nil, ErrDeriveBeyondMaxDepth
+	}
+	hardened := i >= HardenedKeyStart
+	if hardened && !k.isPrivate {
+		return nil, ErrHardenedFromPublic
+	}
+
+	data := make([]byte, 0, 37)
+	if hardened {
+		data = append(data, 0)
+		data = append(data, k.key...)
+	} else {
+		data = append(data, k.pubKeyBytes()...)
+	}
+	data = appendUint32(data, i)
+	h := hmacSHA512(k.chainCode, data)
+	il, chainCode := h[:32], h[32:]
+
+	var tweak secp256k1.ModNScalar
+	if overflow := tweak.SetByteSlice(il); overflow {
+		return nil, ErrInvalidChild
+	}
+
+	var childKey []byte
+	if k.isPrivate {
+		var key secp256k1.ModNScalar
+		key.SetByteSlice(k.key)
+		key.Add(&tweak)
+		if key.IsZero() {
+			return nil, ErrInvalidChild
+		}
+		b := key.Bytes()
+		childKey = b[:]
+	} else {
+		pub, err := secp256k1.ParsePubKey(k.key)
+		if err != nil {
+			return nil, err
+		}
+		var point, result secp256k1.JacobianPoint
+		pub.AsJacobian(&point)
+		secp256k1.ScalarBaseMultNonConst(&tweak, &result)
+		secp256k1.AddNonConst(&point, &result, &result)
+		if (result.X.IsZero() && result.Y.IsZero()) || result.Z.IsZero() {
+			return nil, ErrInvalidChild
+		}
+		result.ToAffine()
+		childKey = secp256k1.NewPublicKey(&result.X, &result.Y).SerializeCompressed()
+	}
+
+	return &ExtendedKey{
+		key:       childKey,
+		chainCode: chainCode,
+		parentFP:  hash160(k.pubKeyBytes())[:4],
+		depth:     k.depth + 1,
+		childNum:  i,
+		isPrivate: k.isPrivate,
+	}, nil
+}
+
+// Neuter returns the public extended key of k
+func (k *ExtendedKey) Neuter() *ExtendedKey {
+	if !k.isPrivate {
+		return k
+	}
+	return &ExtendedKey{
+		key:       k.pubKeyBytes(),
+		chainCode: k.chainCode,
+		parentFP:  k.parentFP,
+		depth:     k.depth,
+		childNum:  k.childNum,
+	}
+}
+
+// ECPrivKey returns the private key of a private extended key
+func (k *ExtendedKey) ECPrivKey() (*ecdsa.PrivateKey, error) {
+	if !k.isPrivate {
+		return nil, ErrNotPrivate
+	}
+	return crypto.ToECDSA(k.key)
+}
+
+// ECPubKey returns the public key of the extended key
+func (k *ExtendedKey) ECPubKey() (*ecdsa.PublicKey, error) {
+	pub, err := secp256k1.ParsePubKey(k.pubKeyBytes())
+	if err != nil {
+		return nil, err
+	}
+	return pub.ToECDSA(), nil
+}
+
+// Address returns the account of the extended key
+func (k *ExtendedKey) Address() (common.Address, error) {
+	pub, err := k.ECPubKey()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// String returns the base58 serialization of the key, xprv... or xpub...
+func (k *ExtendedKey) String() string {
+	b := make([]byte, 0, serializedKeyLen+4)
+	if k.isPrivate {
+		b = append(b, PrivateVersion[:]...)
+	} else {
+		b = append(b, PublicVersion[:]...)
+	}
+	b = append(b, k.depth)
+	b = append(b, k.parentFP...)
+	b = appendUint32(b, k.childNum)
+	b = append(b, k.chainCode...)
+	if k.isPrivate {
+		b = append(b, 0)
+	}
+	b = append(b, k.key...)
+	b = append(b, checksum(b)...)
+	return base58Encode(b)
+}
+
+// ParseExtendedKey parses the base58 serialization of an extended key
+func ParseExtendedKey(s string) (*ExtendedKey, error) {
+	b, err := base58Decode(s)
+	if err != nil || len(b) != serializedKeyLen+4 {
+		return nil, ErrInvalidKey
+	}
+	payload := b[:serializedKeyLen]
+	if !bytes.Equal(checksum(payload), b[serializedKeyLen:]) {
+		return nil, ErrInvalidKey
+	}
+
+	k := &ExtendedKey{
+		depth:     payload[4],
+		parentFP:  payload[5:9],
+		childNum:  binary.BigEndian.Uint32(payload[9:13]),
+		chainCode: payload[13:45],
+	}
+	if k.depth == 0 && (!bytes.Equal(k.parentFP, []byte{0, 0, 0, 0}) || k.childNum != 0) {
+		return nil, ErrInvalidKey
+	}
+	keyData := payload[45:]
+	switch {
+	case bytes.Equal(payload[:4], PrivateVersion[:]):
+		if keyData[0] != 0 || !validPrivateKey(keyData[1:]) {
+			return nil, ErrInvalidKey
+		}
+		k.key = keyData[1:]
+		k.isPrivate = true
+	case bytes.Equal(payload[:4], PublicVersion[:]):
+		if _, err := secp256k1.ParsePubKey(keyData); err != nil || len(keyData) != 33 {
+			return nil, ErrInvalidKey
+		}
+		k.key = keyData
+	default:
+		return nil, ErrInvalidKey
+	}
+	return k, nil
+}
+
+func (k *ExtendedKey) pubKeyBytes() []byte {
+	if !k.isPrivate {
+		return k.key
+	}
+	return secp256k1.PrivKeyFromBytes(k.key).PubKey().SerializeCompressed()
+}
+
+func validPrivateKey(b []byte) bool {
+	d := new(big.Int).SetBytes(b)
+	return d.Sign() > 0 && d.Cmp(secp256k1N) < 0
+}
+
+func hmacSHA512(key, data []byte) []byte {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	return mac.Sum(nil)
+}
+
+func hash160(b []byte) []byte {
+	h := sha256.Sum256(b)
+	r := ripemd160.New()
+	r.Write(h[:])
+	return r.Sum(nil)
+}
+
+func appendUint32(b []byte, i uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], i)
+	return append(b, buf[:]...)
+}
+
+func checksum(b []byte) []byte {
+	h := sha256.Sum256(b)
+	h = sha256.Sum256(h[:])
+	return h[:4]
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package hdwallet
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type KeyTestSuite struct {
+	suite.Suite
+}
+
+// the test vectors of BIP-32
+var keyVectors = []struct {
+	seed string
+	path string
+	xpub string
+	xprv string
+}{
+	{
+		"000102030405060708090a0b0c0d0e0f", "m",
+		"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet8",
+		"xprv9s21ZrQH143K3QTDL4LXw2F7HEK3wJUD2nW2nRk4stbPy6cq3jPPqjiChkVvvNKmPGJxWUtg6LnF5kejMRNNU3TGtRBeJgk33yuGBxrMPHi",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f", "m/0'",
+		"xpub68Gmy5EdvgibQVfPdqkBBCHxA5htiqg55crXYuXoQRKfDBFA1WEjWgP6LHhwBZeNK1VTsfTFUHCdrfp1bgwQ9xv5ski8PX9rL2dZXvgGDnw",
+		"xprv9uHRZZhk6KAJC1avXpDAp4MDc3sQKNxDiPvvkX8Br5ngLNv1TxvUxt4cV1rGL5hj6KCesnDYUhd7oWgT11eZG7XnxHrnYeSvkzY7d2bhkJ7",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f", "m/0'/1",
+		"xpub6ASuArnXKPbfEwhqN6e3mwBcDTgzisQN1wXN9BJcM47sSikHjJf3UFHKkNAWbWMiGj7Wf5uMash7SyYq527Hqck2AxYysAA7xmALppuCkwQ",
+		"xprv9wTYmMFdV23N2TdNG573QoEsfRrWKQgWeibmLntzniatZvR9BmLnvSxqu53Kw1UmYPxLgboyZQaXwTCg8MSY3H2EU4pWcQDnRnrVA1xe8fs",
+	},
+	{
+		"000102030405060708090a0b0c0d0e0f", "m/0'/1/2'/2/1000000000",
+		"xpub6H1LXWLaKsWFhvm6RVpEL9P4KfRZSW7abD2ttkWP3SSQvnyA8FSVqNTEcYFgJS2UaFcxupHiYkro49S8yGasTvXEYBVPamhGW6cFJodrTHy",
+		"xprvA41z7zogVVwxVSgdKUHDy1SKmdb533PjDz7J6N6mV6uS3ze1ai8FHa8kmHScGpWmj4WggLyQjgPie1rFSruoUihUZREPSL39UNdE3BBDu76",
+	},
+	{
+		"fffcf9f6f3f0edeae7e4e1dedbd8d5d2cfccc9c6c3c0bdbab7b4b1aeaba8a5a29f9c999693908d8a8784817e7b7875726f6c696663605d5a5754514e4b484542", "m",
+		"xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB",
+		"xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U",
+	},
+	{
+		"4b381541583be4423346c643850da4b320e46a87ae3d2a4e6da11eba819cd4acba45d239319ac14f863b8d5ab5a0d0c64d2e8a1e7d1457df2e5a3c51c73235be", "m",
+		"xpub661MyMwAqRbcEZVB4dScxMAdx6d4nFc9nvyvH3v4gJL378CSRZiYmhRoP7mBy6gSPSCYk6SzXPTf3ND1cZAceL7SfJ1Z3GC8vBgp2epUt13",
+		"xprv9s21ZrQH143K25QhxbucbDDuQ4naNntJRi4KUfWT7xo4EKsHt2QJDu7KXp1A3u7Bi1j8ph3EGsZ9Xvz9dGuVrtHHs7pXeTzjuxBrCmmhgC6",
+	},
+	{
+		"4b381541583be4423346c643850da4b320e46a87ae3d2a4e6da11eba819cd4acba45d239319ac14f863b8d5ab5a0d0c64d2e8a1e7d1457df2e5a3c51c73235be", "m/0'",
+		"xpub68NZiKmJWnxxS6aaHmn81bvJeTESw724CRDs6HbuccFQN9Ku14VQrADWgqbhhTHBaohPX4CjNLf9fq9MYo6oDaPPLPxSb7gwQN3ih19Zm4Y",
+		"xprv9uPDJpEQgRQfDcW7BkF7eTya6RPxXeJCqCJGHuCJ4GiRVLzkTXBAJMu2qaMWPrS7AANYqdq6vcBcBUdJCVVFceUvJFjaPdGZ2y9WACViL4L",
+	},
+}
+
+func (suite *KeyTestSuite) Test_Vectors() {
+	for _, v := range keyVectors {
+		seed, _ := hex.DecodeString(v.seed)
+		master, err := NewMaster(seed)
+		suite.Require().NoError(err)
+		path, err := ParseDerivationPath(v.path)
+		suite.Require().NoError(err)
+		key, err := master.Derive(path)
+		suite.Require().NoError(err)
+
+		assert.Equal(suite.T(), v.xprv, key.String(), "should be equal")
+		assert.Equal(suite.T(), v.xpub, key.Neuter().String(), "should be equal")
+		assert.Equal(suite.T(), uint8(len(path)), key.Depth(), "should be equal")
+
+		parsed, err := ParseExtendedKey(v.xprv)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), key, parsed, "should be equal")
+		parsed, err = ParseExtendedKey(v.xpub)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), key.Neuter(), parsed, "should be equal")
+	}
+}
+
+func (suite *KeyTestSuite) Test_PublicDerivation() {
+	// the public child of a public key is the public key of the private child
+	xpub, err := ParseExtendedKey("xpub661MyMwAqRbcFW31YEwpkMuc5THy2PSt5bDMsktWQcFF8syAmRUapSCGu8ED9W6oDMSgv6Zz8idoc4a6mr8BDzTJY47LJhkJ8UB7WEGuduB")
+	suite.Require().NoError(err)
+	child, err := xpub.Child(0)
+	suite.Require().NoError(err)
+	assert.False(suite.T(), child.IsPrivate(), "should be public")
+	assert.Equal(suite.T(), "xpub69H7F5d8KSRgmmdJg2KhpAK8SR3DjMwAdkxj3ZuxV27CprR9LgpeyGmXUbC6wb7ERfvrnKZjXoUmmDznezpbZb7ap6r1D3tgFxHmwMkQTPH", child.String(), "should be equal")
+
+	xprv, _ := ParseExtendedKey("xprv9s21ZrQH143K31xYSDQpPDxsXRTUcvj2iNHm5NUtrGiGG5e2DtALGdso3pGz6ssrdK4PFmM8NSpSBHNqPqm55Qn3LqFtT2emdEXVYsCzC2U")
+	for i := uint32(0); i < 4; i++ {
+		private, err := xprv.Child(i)
+		suite.Require().NoError(err)
+		public, err := xpub.Child(i)
+		suite.Require().NoError(err)
+		privateAddress, _ := private.Address()
+		publicAddress, _ := public.Address()
+		assert.Equal(suite.T(), privateAddress, publicAddress, "should be equal")
+	}
+
+	_, err = xpub.Child(HardenedKeyStart)
+	assert.Equal(suite.T(), ErrHardenedFromPublic, err, "should be equal")
+	_, err = xpub.ECPrivKey()
+	assert.Equal(suite.T(), ErrNotPrivate, err, "should be equal")
+}
+
+func (suite *KeyTestSuite) Test_Invalid() {
+	_, err := NewMaster(make([]byte, 15))
+	assert.Equal(suite.T(), ErrInvalidSeed, err, "should be equal")
+	_, err = NewMaster(make([]byte, 65))
+	assert.Equal(suite.T(), ErrInvalidSeed, err, "should be equal")
+
+	for _, s := range []string{
+		"",
+		"0OIl",
+		// wrong checksum
+		"xpub661MyMwAqRbcFtXgS5sYJABqqG9YLmC4Q1Rdap9gSE8NqtwybGhePY2gZ29ESFjqJoCu1Rupje8YtGqsefD265TMg7usUDFdp6W1EGMcet7",
+		// unknown version
+		base58Encode(append(make([]byte, serializedKeyLen), checksum(make([]byte, serializedKeyLen))...)),
+	} {
+		_, err = ParseExtendedKey(s)
+		assert.Equal(suite.T(), ErrInvalidKey, err, "should be equal")
+	}
+}
+
+func Test_KeyTestSuite(t *testing.T) {
+	suite.Run(t, new(KeyTestSuite))
+}
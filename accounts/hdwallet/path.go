@@ -0,0 +1,110 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package hdwallet
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// MoacCoinType is the BIP-44 coin type of MOAC, registered in SLIP-44
+const MoacCoinType = 314
+
+// DefaultBaseDerivationPath is the BIP-44 path of the external chain of the
+// first MOAC account, its keys are derived by appending their index
+var DefaultBaseDerivationPath = DerivationPath{
+	HardenedKeyStart + 44,
+	HardenedKeyStart + MoacCoinType,
+	HardenedKeyStart + 0,
+	0,
+}
+
+// ErrInvalidPath is returned for malformed derivation paths
+var ErrInvalidPath = errors.New("Invalid derivation path")
+
+// DerivationPath is the list of the child indices from the master key to a
+// key, e.g. m/44'/314'/0'/0/0
+type DerivationPath []uint32
+
+// ParseDerivationPath parses a path such as m/44'/314'/0'/0/0, where
+// hardened indices end with ' or h
+func ParseDerivationPath(path string) (DerivationPath, error) {
+	components := strings.Split(strings.TrimSpace(path), "/")
+	if len(components) == 0 || components[0] != "m" {
+		return nil, ErrInvalidPath
+	}
+
+	var result DerivationPath
+	for _, component := range components[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(component, "'") || strings.HasSuffix(component, "h") {
+			offset = HardenedKeyStart
+			component = component[:len(component)-1]
+		}
+		index, err := strconv.ParseUint(component, 10, 32)
+		if err != nil || uint32(index) >= HardenedKeyStart {
+			return nil, ErrInvalidPath
+		}
+		result = append(result, uint32(index)+offset)
+	}
+	return result, nil
+}
+
+// String returns the path as m/44'/314'/0'/0/0
+func (path DerivationPath) String() string {
+	result := "m"
+	for _, index := range path {
+		if index >= HardenedKeyStart {
+			result += fmt.Sprintf("/%d'", index-HardenedKeyStart)
+		} else {
+			result += fmt.Sprintf("/%d", index)
+		}
+	}
+	return result
+}
+
+// Child returns a copy of the path with index appended
+func (path DerivationPath) Child(index uint32) DerivationPath {
+	return append(append(DerivationPath(nil), path...), index)
+}
+
+// Derive derives the key at path, relative to k
+func (k *ExtendedKey) Derive(path DerivationPath) (*ExtendedKey, error) {
+	key := k
+	for _, index := range path {
+		var err error
+		if key, err = key.Child(index); err != nil {
+			return nil, err
+		}
+	}
+	return key, nil
+}
@@ -0,0 +1,142 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package hdwallet
+
+import (
+	"crypto/ecdsa"
+	"math/big"
+	"sync"
+
+	"github.com/caivega/chain3go/accounts"
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/transaction"
+)
+
+var _ accounts.Signer = (*Wallet)(nil)
+
+// Wallet is a Signer with the keys derived from a master key
+type Wallet struct {
+	master *ExtendedKey
+	signer *accounts.KeySigner
+
+	mu    sync.RWMutex
+	paths map[common.Address]DerivationPath
+}
+
+// NewFromMnemonic creates a wallet with the seed of mnemonic and passphrase
+func NewFromMnemonic(mnemonic, passphrase string) (*Wallet, error) {
+	seed, err := NewSeed(mnemonic, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return NewFromSeed(seed)
+}
+
+// NewFromSeed creates a wallet with the master key of seed
+func NewFromSeed(seed []byte) (*Wallet, error) {
+	master, err := NewMaster(seed)
+	if err != nil {
+		return nil, err
+	}
+	return &Wallet{
+		master: master,
+		signer: accounts.NewKeySigner(),
+		paths:  map[common.Address]DerivationPath{},
+	}, nil
+}
+
+// Derive derives the key at path and adds its account to the wallet
+func (w *Wallet) Derive(path DerivationPath) (common.Address, error) {
+	key, err := w.master.Derive(path)
+	if err != nil {
+		return common.Address{}, err
+	}
+	privateKey, err := key.ECPrivKey()
+	if err != nil {
+		return common.Address{}, err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	address := w.signer.Add(privateKey)
+	w.paths[address] = append(DerivationPath(nil), path...)
+	return address, nil
+}
+
+// DeriveIndex derives the key of index under DefaultBaseDerivationPath,
+// i.e. m/44'/314'/0'/0/index
+func (w *Wallet) DeriveIndex(index uint32) (common.Address, error) {
+	return w.Derive(DefaultBaseDerivationPath.Child(index))
+}
+
+// ExtendedKey returns the extended key at path, e.g. to give the public key
+// of DefaultBaseDerivationPath to a server deriving deposit addresses
+func (w *Wallet) ExtendedKey(path DerivationPath) (*ExtendedKey, error) {
+	return w.master.Derive(path)
+}
+
+// Path returns the derivation path of a derived account
+func (w *Wallet) Path(account common.Address) (DerivationPath, error) {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	path, ok := w.paths[account]
+	if !ok {
+		return nil, accounts.ErrUnknownAccount
+	}
+	return append(DerivationPath(nil), path...), nil
+}
+
+// PrivateKey returns the private key of a derived account
+func (w *Wallet) PrivateKey(account common.Address) (*ecdsa.PrivateKey, error) {
+	path, err := w.Path(account)
+	if err != nil {
+		return nil, err
+	}
+	key, err := w.master.Derive(path)
+	if err != nil {
+		return nil, err
+	}
+	return key.ECPrivKey()
+}
+
+// Accounts returns the derived accounts, in the order of their derivation
+func (w *Wallet) Accounts() ([]common.Address, error) {
+	return w.signer.Accounts()
+}
+
+// SignTx ...
+func (w *Wallet) SignTx(account common.Address, tx *transaction.Transaction, chainID *big.Int) (*transaction.Transaction, error) {
+	return w.signer.SignTx(account, tx, chainID)
+}
+
+// SignMessage ...
+func (w *Wallet) SignMessage(account common.Address, message []byte) ([]byte, error) {
+	return w.signer.SignMessage(account, message)
+}
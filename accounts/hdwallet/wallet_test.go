@@ -0,0 +1,138 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package hdwallet
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/caivega/chain3go/accounts"
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const testMnemonic = "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon about"
+
+type WalletTestSuite struct {
+	suite.Suite
+	wallet *Wallet
+}
+
+func (suite *WalletTestSuite) Test_Path() {
+	path, err := ParseDerivationPath("m/44'/314'/0'/0/7")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), DefaultBaseDerivationPath.Child(7), path, "should be equal")
+	assert.Equal(suite.T(), "m/44'/314'/0'/0/7", path.String(), "should be equal")
+
+	path, err = ParseDerivationPath("m/44h/314h/1h")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), DerivationPath{HardenedKeyStart + 44, HardenedKeyStart + 314, HardenedKeyStart + 1}, path, "should be equal")
+
+	path, err = ParseDerivationPath("m")
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), path, "should be empty")
+
+	for _, s := range []string{"", "44'/314'", "m/", "m/a", "m/-1", "m/2147483648", "m/1''"} {
+		_, err = ParseDerivationPath(s)
+		assert.Equal(suite.T(), ErrInvalidPath, err, s)
+	}
+}
+
+func (suite *WalletTestSuite) Test_Derive() {
+	// the well known account of the mnemonic at the path of Ethereum
+	path, _ := ParseDerivationPath("m/44'/60'/0'/0/0")
+	address, err := suite.wallet.Derive(path)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), common.StringToAddress("0x9858effd232b4033e47d90003d41ec34ecaeda94"), address, "should be equal")
+
+	first, err := suite.wallet.DeriveIndex(0)
+	suite.Require().NoError(err)
+	second, err := suite.wallet.DeriveIndex(1)
+	suite.Require().NoError(err)
+	assert.NotEqual(suite.T(), first, second, "should be different")
+	derived, err := suite.wallet.Accounts()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []common.Address{address, first, second}, derived, "should be equal")
+
+	path, err = suite.wallet.Path(second)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "m/44'/314'/0'/0/1", path.String(), "should be equal")
+	_, err = suite.wallet.Path(common.Address{1})
+	assert.Equal(suite.T(), accounts.ErrUnknownAccount, err, "should be equal")
+
+	// a watch only server derives the same deposit addresses from the xpub
+	base, err := suite.wallet.ExtendedKey(DefaultBaseDerivationPath)
+	suite.Require().NoError(err)
+	xpub, err := ParseExtendedKey(base.Neuter().String())
+	suite.Require().NoError(err)
+	child, err := xpub.Child(1)
+	suite.Require().NoError(err)
+	deposit, err := child.Address()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), second, deposit, "should be equal")
+}
+
+func (suite *WalletTestSuite) Test_Sign() {
+	account, err := suite.wallet.DeriveIndex(0)
+	suite.Require().NoError(err)
+
+	tx := transaction.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	signed, err := suite.wallet.SignTx(account, tx, transaction.MainnetChainID)
+	suite.Require().NoError(err)
+	sender, err := signed.Sender()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), account, sender, "should be equal")
+
+	sig, err := suite.wallet.SignMessage(account, []byte("hello"))
+	suite.Require().NoError(err)
+	signer, _ := accounts.RecoverMessage([]byte("hello"), sig)
+	assert.Equal(suite.T(), account, signer, "should be equal")
+
+	key, err := suite.wallet.PrivateKey(account)
+	suite.Require().NoError(err)
+	keyAccounts, err := accounts.NewKeySigner(key).Accounts()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []common.Address{account}, keyAccounts, "should be equal")
+
+	_, err = suite.wallet.SignTx(common.Address{1}, tx, transaction.MainnetChainID)
+	assert.Equal(suite.T(), accounts.ErrUnknownAccount, err, "should be equal")
+}
+
+func (suite *WalletTestSuite) SetupTest() {
+	var err error
+	suite.wallet, err = NewFromMnemonic(testMnemonic, "")
+	suite.Require().NoError(err)
+}
+
+func Test_WalletTestSuite(t *testing.T) {
+	suite.Run(t, new(WalletTestSuite))
+}
@@ -0,0 +1,312 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package keystore reads and writes keys in the JSON key files of MOAC nodes
+// (Web3 Secret Storage version 3), and signs with the unlocked ones.
+package keystore
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/crypto"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	// StandardScryptN and StandardScryptP are the scrypt parameters of the
+	// key files of the nodes
+	StandardScryptN = 1 << 18
+	StandardScryptP = 1
+	// LightScryptN and LightScryptP are faster scrypt parameters, which use
+	// less memory, for constrained environments
+	LightScryptN = 1 << 12
+	LightScryptP = 6
+
+	scryptR     = 8
+	scryptDKLen = 32
+
+	// PBKDF2Iterations is the iteration count of pbkdf2 key files
+	PBKDF2Iterations = 1 << 18
+
+	version = 3
+)
+
+var (
+	// ErrDecrypt is returned when the passphrase doesn't match the MAC of a
+	// key file
+	ErrDecrypt = errors.New("Could not decrypt key with given passphrase")
+	// ErrUnsupported is returned for key files with an unknown version,
+	// cipher or key derivation function
+	ErrUnsupported = errors.New("Unsupported key file")
+)
+
+// Key is a decrypted key
+type Key struct {
+	ID         string
+	Address    common.Address
+	PrivateKey *ecdsa.PrivateKey
+}
+
+type keyJSON struct {
+	Address string     `json:"address"`
+	Crypto  cryptoJSON `json:"crypto"`
+	ID      string     `json:"id"`
+	Version int        `json:"version"`
+}
+
+type cryptoJSON struct {
+	Cipher       string                 `json:"cipher"`
+	CipherText   string                 `json:"ciphertext"`
+	CipherParams cipherParamsJSON       `json:"cipherparams"`
+	KDF          string                 `json:"kdf"`
+	KDFParams    map[string]interface{} `json:"kdfparams"`
+	MAC          string                 `json:"mac"`
+}
+
+type cipherParamsJSON struct {
+	IV string `json:"iv"`
+}
+
+// NewKey creates a key with a random private key
+func NewKey() (*Key, error) {
+	privateKey, err := crypto.GenerateKey()
+	if err != nil {
+		return nil, err
+	}
+	return newKey(privateKey)
+}
+
+func newKey(privateKey *ecdsa.PrivateKey) (*Key, error) {
+	id, err := newID()
+	if err != nil {
+		return nil, err
+	}
+	return &Key{
+		ID:         id,
+		Address:    crypto.PubkeyToAddress(privateKey.PublicKey),
+		PrivateKey: privateKey,
+	}, nil
+}
+
+// EncryptKey encrypts a key with scrypt, returning its key file
+func EncryptKey(key *Key, passphrase string, scryptN, scryptP int) ([]byte, error) {
+	salt, err := random(32)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey, err := scrypt.Key([]byte(passphrase), salt, scryptN, scryptR, scryptP, scryptDKLen)
+	if err != nil {
+		return nil, err
+	}
+	return encrypt(key, derivedKey, "scrypt", map[string]interface{}{
+		"n":     scryptN,
+		"r":     scryptR,
+		"p":     scryptP,
+		"dklen": scryptDKLen,
+		"salt":  hex.EncodeToString(salt),
+	})
+}
+
+// EncryptKeyPBKDF2 encrypts a key with pbkdf2, returning its key file
+func EncryptKeyPBKDF2(key *Key, passphrase string, iterations int) ([]byte, error) {
+	salt, err := random(32)
+	if err != nil {
+		return nil, err
+	}
+	derivedKey := pbkdf2.Key([]byte(passphrase), salt, iterations, scryptDKLen, sha256.New)
+	return encrypt(key, derivedKey, "pbkdf2", map[string]interface{}{
+		"c":     iterations,
+		"prf":   "hmac-sha256",
+		"dklen": scryptDKLen,
+		"salt":  hex.EncodeToString(salt),
+	})
+}
+
+func encrypt(key *Key, derivedKey []byte, kdf string, kdfParams map[string]interface{}) ([]byte, error) {
+	iv, err := random(aes.BlockSize)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := aesCTR(derivedKey[:16], crypto.FromECDSA(key.PrivateKey), iv)
+	if err != nil {
+		return nil, err
+	}
+	mac := crypto.Keccak256(derivedKey[16:32], cipherText)
+
+	return json.Marshal(&keyJSON{
+		Address: hex.EncodeToString(key.Address[:]),
+		Crypto: cryptoJSON{
+			Cipher:       "aes-128-ctr",
+			CipherText:   hex.EncodeToString(cipherText),
+			CipherParams: cipherParamsJSON{IV: hex.EncodeToString(iv)},
+			KDF:          kdf,
+			KDFParams:    kdfParams,
+			MAC:          hex.EncodeToString(mac),
+		},
+		ID:      key.ID,
+		Version: version,
+	})
+}
+
+// DecryptKey decrypts a key file with its passphrase
+func DecryptKey(keyFile []byte, passphrase string) (*Key, error) {
+	k := &keyJSON{}
+	if err := json.Unmarshal(keyFile, k); err != nil {
+		return nil, err
+	}
+	if k.Version != version || k.Crypto.Cipher != "aes-128-ctr" {
+		return nil, ErrUnsupported
+	}
+
+	mac, err := hex.DecodeString(k.Crypto.MAC)
+	if err != nil {
+		return nil, err
+	}
+	iv, err := hex.DecodeString(k.Crypto.CipherParams.IV)
+	if err != nil {
+		return nil, err
+	}
+	cipherText, err := hex.DecodeString(k.Crypto.CipherText)
+	if err != nil {
+		return nil, err
+	}
+
+	derivedKey, err := deriveKey(&k.Crypto, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare(crypto.Keccak256(derivedKey[16:32], cipherText), mac) != 1 {
+		return nil, ErrDecrypt
+	}
+
+	plainText, err := aesCTR(derivedKey[:16], cipherText, iv)
+	if err != nil {
+		return nil, err
+	}
+	privateKey, err := crypto.ToECDSA(plainText)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := newKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if k.ID != "" {
+		key.ID = k.ID
+	}
+	if k.Address != "" && !strings.EqualFold(strings.TrimPrefix(k.Address, "0x"), hex.EncodeToString(key.Address[:])) {
+		return nil, fmt.Errorf("Key file address %s doesn't match its key %s", k.Address, key.Address.String())
+	}
+	return key, nil
+}
+
+func deriveKey(c *cryptoJSON, passphrase string) ([]byte, error) {
+	salt, err := hex.DecodeString(stringParam(c.KDFParams, "salt"))
+	if err != nil {
+		return nil, err
+	}
+	dkLen := intParam(c.KDFParams, "dklen")
+	if dkLen < 32 {
+		return nil, ErrUnsupported
+	}
+
+	switch c.KDF {
+	case "scrypt":
+		n := intParam(c.KDFParams, "n")
+		r := intParam(c.KDFParams, "r")
+		p := intParam(c.KDFParams, "p")
+		return scrypt.Key([]byte(passphrase), salt, n, r, p, dkLen)
+	case "pbkdf2":
+		if stringParam(c.KDFParams, "prf") != "hmac-sha256" {
+			return nil, ErrUnsupported
+		}
+		iterations := intParam(c.KDFParams, "c")
+		if iterations <= 0 {
+			return nil, ErrUnsupported
+		}
+		return pbkdf2.Key([]byte(passphrase), salt, iterations, dkLen, sha256.New), nil
+	}
+	return nil, ErrUnsupported
+}
+
+func stringParam(params map[string]interface{}, name string) string {
+	s, _ := params[name].(string)
+	return s
+}
+
+func intParam(params map[string]interface{}, name string) int {
+	f, _ := params[name].(float64)
+	return int(f)
+}
+
+func aesCTR(key, input, iv []byte) ([]byte, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(iv) != aes.BlockSize {
+		return nil, ErrUnsupported
+	}
+	output := make([]byte, len(input))
+	cipher.NewCTR(block, iv).XORKeyStream(output, input)
+	return output, nil
+}
+
+func random(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := io.ReadFull(rand.Reader, b); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// newID returns a random (version 4) UUID
+func newID() (string, error) {
+	b, err := random(16)
+	if err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -0,0 +1,118 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/caivega/chain3go/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// the test vectors of the Web3 Secret Storage definition
+const (
+	vectorPassphrase = "testpassword"
+	vectorPrivateKey = "7a28b5ba57c53603b0b07b56bba752f7784bf506fa95edc395f5cf6c7514fe9d"
+	vectorPBKDF2     = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"6087dab2f9fdbbfaddc31a909735c1e6"},"ciphertext":"5318b4d5bcd28de64ee5559e671353e16f075ecae9f99c7a79a38af5f869aa46","kdf":"pbkdf2","kdfparams":{"c":262144,"dklen":32,"prf":"hmac-sha256","salt":"ae3cd4e7013836a3df6bd7241b12db061dbe2c6785853cce422d148a624ce0bd"},"mac":"517ead924a9d0dc3124507e3393d175ce3ff7c1e96529c6c555ce9e51205e9b2"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+	vectorScrypt     = `{"crypto":{"cipher":"aes-128-ctr","cipherparams":{"iv":"83dbcc02d8ccb40e466191a123791e0e"},"ciphertext":"d172bf743a674da9cdad04534d56926ef8358534d458fffccd4e6ad2fbde479c","kdf":"scrypt","kdfparams":{"dklen":32,"n":262144,"r":1,"p":8,"salt":"ab0c7876052600dd703518d6fc3fe8984592145b591fc8fb5c6d43190334ba19"},"mac":"2103ac29920d71da29f15d75b4a16dbe95cfd7ff8faea1056c33131d846e3097"},"id":"3198bc9c-6672-5ab3-d995-4942343ae5b6","version":3}`
+)
+
+type KeyTestSuite struct {
+	suite.Suite
+}
+
+func (suite *KeyTestSuite) Test_Vectors() {
+	for _, keyFile := range []string{vectorPBKDF2, vectorScrypt} {
+		key, err := DecryptKey([]byte(keyFile), vectorPassphrase)
+		suite.Require().NoError(err)
+		assert.Equal(suite.T(), vectorPrivateKey, hex.EncodeToString(crypto.FromECDSA(key.PrivateKey)), "should be equal")
+		assert.Equal(suite.T(), "3198bc9c-6672-5ab3-d995-4942343ae5b6", key.ID, "should be equal")
+		assert.Equal(suite.T(), crypto.PubkeyToAddress(key.PrivateKey.PublicKey), key.Address, "should be equal")
+
+		_, err = DecryptKey([]byte(keyFile), "wrong")
+		assert.Equal(suite.T(), ErrDecrypt, err, "should be equal")
+	}
+}
+
+func (suite *KeyTestSuite) Test_Encrypt() {
+	key, err := NewKey()
+	suite.Require().NoError(err)
+
+	keyFile, err := EncryptKey(key, "secret", LightScryptN, LightScryptP)
+	suite.Require().NoError(err)
+	decrypted, err := DecryptKey(keyFile, "secret")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), key.Address, decrypted.Address, "should be equal")
+	assert.Equal(suite.T(), key.ID, decrypted.ID, "should be equal")
+	assert.Equal(suite.T(), crypto.FromECDSA(key.PrivateKey), crypto.FromECDSA(decrypted.PrivateKey), "should be equal")
+
+	keyFile, err = EncryptKeyPBKDF2(key, "secret", 1024)
+	suite.Require().NoError(err)
+	decrypted, err = DecryptKey(keyFile, "secret")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), key.Address, decrypted.Address, "should be equal")
+
+	k := map[string]interface{}{}
+	suite.Require().NoError(json.Unmarshal(keyFile, &k))
+	assert.Equal(suite.T(), hex.EncodeToString(key.Address[:]), k["address"], "should be equal")
+	assert.EqualValues(suite.T(), 3, k["version"], "should be equal")
+	assert.Len(suite.T(), k["id"], 36, "should be a uuid")
+}
+
+func (suite *KeyTestSuite) Test_Invalid() {
+	key, _ := NewKey()
+	keyFile, _ := EncryptKey(key, "secret", LightScryptN, LightScryptP)
+	k := map[string]interface{}{}
+	json.Unmarshal(keyFile, &k)
+
+	k["version"] = 2
+	b, _ := json.Marshal(k)
+	_, err := DecryptKey(b, "secret")
+	assert.Equal(suite.T(), ErrUnsupported, err, "should be equal")
+
+	k["version"] = 3
+	k["crypto"].(map[string]interface{})["kdf"] = "argon2"
+	b, _ = json.Marshal(k)
+	_, err = DecryptKey(b, "secret")
+	assert.Equal(suite.T(), ErrUnsupported, err, "should be equal")
+
+	k["crypto"].(map[string]interface{})["kdf"] = "scrypt"
+	k["address"] = "0000000000000000000000000000000000000000"
+	b, _ = json.Marshal(k)
+	_, err = DecryptKey(b, "secret")
+	assert.Error(suite.T(), err, "should not match the address")
+}
+
+func Test_KeyTestSuite(t *testing.T) {
+	suite.Run(t, new(KeyTestSuite))
+}
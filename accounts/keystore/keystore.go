@@ -0,0 +1,323 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package keystore
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/caivega/chain3go/accounts"
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/transaction"
+)
+
+var (
+	// ErrNoMatch is returned when no key file has the requested address
+	ErrNoMatch = errors.New("No key for given address")
+	// ErrLocked is returned when signing with an account which isn't
+	// unlocked
+	ErrLocked = errors.New("Account is locked")
+)
+
+var _ accounts.Signer = (*KeyStore)(nil)
+
+// KeyStore manages the key files of a directory. It signs with the accounts
+// which were unlocked with their passphrase.
+type KeyStore struct {
+	dir     string
+	scryptN int
+	scryptP int
+
+	mu       sync.Mutex
+	unlocked map[common.Address]*unlocked
+}
+
+type unlocked struct {
+	key   *Key
+	timer *time.Timer
+}
+
+// NewKeyStore creates a key store on the directory dir, where new keys are
+// encrypted with the given scrypt parameters
+func NewKeyStore(dir string, scryptN, scryptP int) *KeyStore {
+	return &KeyStore{
+		dir:      dir,
+		scryptN:  scryptN,
+		scryptP:  scryptP,
+		unlocked: map[common.Address]*unlocked{},
+	}
+}
+
+// Accounts returns the addresses of the key files, in the order of their
+// file names, which is their creation order for the files written by nodes.
+// It fails if the directory or one of its files cannot be read.
+func (ks *KeyStore) Accounts() ([]common.Address, error) {
+	files, err := ks.scan()
+	if err != nil {
+		return nil, err
+	}
+	addresses := make([]common.Address, len(files))
+	for i, file := range files {
+		addresses[i] = file.address
+	}
+	return addresses, nil
+}
+
+// Find returns the path of the key file of an address
+func (ks *KeyStore) Find(address common.Address) (string, error) {
+	files, err := ks.scan()
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		if file.address == address {
+			return file.path, nil
+		}
+	}
+	return "", ErrNoMatch
+}
+
+// NewAccount creates a key, and stores it encrypted with passphrase
+func (ks *KeyStore) NewAccount(passphrase string) (common.Address, error) {
+	key, err := NewKey()
+	if err != nil {
+		return common.Address{}, err
+	}
+	return key.Address, ks.store(key, passphrase)
+}
+
+// Import stores the key of a key file, encrypted with newPassphrase
+func (ks *KeyStore) Import(keyFile []byte, passphrase, newPassphrase string) (common.Address, error) {
+	key, err := DecryptKey(keyFile, passphrase)
+	if err != nil {
+		return common.Address{}, err
+	}
+	if _, err := ks.Find(key.Address); err == nil {
+		return common.Address{}, errors.New("Account already exists")
+	}
+	return key.Address, ks.store(key, newPassphrase)
+}
+
+// Export returns the key file of an address, encrypted with newPassphrase
+func (ks *KeyStore) Export(address common.Address, passphrase, newPassphrase string) ([]byte, error) {
+	key, err := ks.getKey(address, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return EncryptKey(key, newPassphrase, ks.scryptN, ks.scryptP)
+}
+
+// Delete removes the key file of an address, after checking its passphrase
+func (ks *KeyStore) Delete(address common.Address, passphrase string) error {
+	path, err := ks.Find(address)
+	if err != nil {
+		return err
+	}
+	if _, err := ks.decrypt(path, passphrase); err != nil {
+		return err
+	}
+	ks.Lock(address)
+	return os.Remove(path)
+}
+
+// Unlock decrypts the key of an address, which signs until Lock is called
+func (ks *KeyStore) Unlock(address common.Address, passphrase string) error {
+	return ks.TimedUnlock(address, passphrase, 0)
+}
+
+// TimedUnlock decrypts the key of an address, which signs until the timeout
+// elapses or Lock is called. A timeout of 0 never elapses. Unlocking an
+// unlocked account replaces its timeout.
+func (ks *KeyStore) TimedUnlock(address common.Address, passphrase string, timeout time.Duration) error {
+	key, err := ks.getKey(address, passphrase)
+	if err != nil {
+		return err
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.lock(address)
+	u := &unlocked{key: key}
+	if timeout > 0 {
+		u.timer = time.AfterFunc(timeout, func() {
+			ks.mu.Lock()
+			defer ks.mu.Unlock()
+			// only lock if the account wasn't unlocked again since
+			if ks.unlocked[address] == u {
+				ks.lock(address)
+			}
+		})
+	}
+	ks.unlocked[address] = u
+	return nil
+}
+
+// Lock removes the decrypted key of an address from memory
+func (ks *KeyStore) Lock(address common.Address) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.lock(address)
+}
+
+// Unlocked reports whether an address is unlocked
+func (ks *KeyStore) Unlocked(address common.Address) bool {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	_, ok := ks.unlocked[address]
+	return ok
+}
+
+// SignTx signs tx with an unlocked account
+func (ks *KeyStore) SignTx(account common.Address, tx *transaction.Transaction, chainID *big.Int) (*transaction.Transaction, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	u, ok := ks.unlocked[account]
+	if !ok {
+		return nil, ErrLocked
+	}
+	return transaction.SignTx(tx, chainID, u.key.PrivateKey)
+}
+
+// SignMessage signs a message with an unlocked account, like
+// accounts.KeySigner
+func (ks *KeyStore) SignMessage(account common.Address, message []byte) ([]byte, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	u, ok := ks.unlocked[account]
+	if !ok {
+		return nil, ErrLocked
+	}
+	return accounts.NewKeySigner(u.key.PrivateKey).SignMessage(account, message)
+}
+
+// lock must be called with the lock held
+func (ks *KeyStore) lock(address common.Address) {
+	u, ok := ks.unlocked[address]
+	if !ok {
+		return
+	}
+	if u.timer != nil {
+		u.timer.Stop()
+	}
+	// clear the private key, which may still be referenced
+	u.key.PrivateKey.D.SetInt64(0)
+	delete(ks.unlocked, address)
+}
+
+func (ks *KeyStore) getKey(address common.Address, passphrase string) (*Key, error) {
+	path, err := ks.Find(address)
+	if err != nil {
+		return nil, err
+	}
+	return ks.decrypt(path, passphrase)
+}
+
+func (ks *KeyStore) decrypt(path, passphrase string) (*Key, error) {
+	keyFile, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return DecryptKey(keyFile, passphrase)
+}
+
+// store writes the key file of key, named like the files of the nodes
+func (ks *KeyStore) store(key *Key, passphrase string) error {
+	keyFile, err := EncryptKey(key, passphrase, ks.scryptN, ks.scryptP)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(ks.dir, 0700); err != nil {
+		return err
+	}
+
+	name := "UTC--" + time.Now().UTC().Format("2006-01-02T15-04-05.000000000Z") + "--" + hex.EncodeToString(key.Address[:])
+	path := filepath.Join(ks.dir, name)
+	// write to a temporary file first, so that a key file is never partial
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, keyFile, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+type keyFileInfo struct {
+	address common.Address
+	path    string
+}
+
+// scan reads the addresses of the key files of the directory, skipping the
+// files which are not key files, or were removed during the scan
+func (ks *KeyStore) scan() ([]keyFileInfo, error) {
+	entries, err := ioutil.ReadDir(ks.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var files []keyFileInfo
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".tmp") {
+			continue
+		}
+		path := filepath.Join(ks.dir, name)
+		b, err := ioutil.ReadFile(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		k := struct {
+			Address string `json:"address"`
+		}{}
+		if err := json.Unmarshal(b, &k); err != nil {
+			continue
+		}
+		address, err := hex.DecodeString(strings.TrimPrefix(k.Address, "0x"))
+		if err != nil || len(address) != len(common.Address{}) {
+			continue
+		}
+		files = append(files, keyFileInfo{address: common.NewAddress(address), path: path})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].path < files[j].path })
+	return files, nil
+}
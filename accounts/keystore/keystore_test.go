@@ -0,0 +1,184 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package keystore
+
+import (
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/caivega/chain3go/accounts"
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type KeyStoreTestSuite struct {
+	suite.Suite
+	dir string
+	ks  *KeyStore
+}
+
+func (suite *KeyStoreTestSuite) accounts() []common.Address {
+	accounts, err := suite.ks.Accounts()
+	suite.Require().NoError(err)
+	return accounts
+}
+
+func (suite *KeyStoreTestSuite) Test_Accounts() {
+	assert.Empty(suite.T(), suite.accounts(), "should be empty")
+
+	a, err := suite.ks.NewAccount("a")
+	suite.Require().NoError(err)
+	time.Sleep(time.Millisecond)
+	b, err := suite.ks.NewAccount("b")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []common.Address{a, b}, suite.accounts(), "should be equal")
+
+	// other files are skipped
+	ioutil.WriteFile(filepath.Join(suite.dir, "README"), []byte("not a key"), 0600)
+	assert.Len(suite.T(), suite.accounts(), 2, "should be equal")
+
+	path, err := suite.ks.Find(a)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Contains(suite.T(), filepath.Base(path), "UTC--", "should be named like node key files")
+	_, err = suite.ks.Find(common.Address{1})
+	assert.Equal(suite.T(), ErrNoMatch, err, "should be equal")
+
+	assert.Equal(suite.T(), ErrDecrypt, suite.ks.Delete(a, "b"), "should be equal")
+	assert.NoError(suite.T(), suite.ks.Delete(a, "a"), "Should be no error")
+	assert.Equal(suite.T(), []common.Address{b}, suite.accounts(), "should be equal")
+}
+
+func (suite *KeyStoreTestSuite) Test_AccountsUnreadable() {
+	// a missing directory has no accounts
+	accounts, err := NewKeyStore(filepath.Join(suite.dir, "missing"), LightScryptN, LightScryptP).Accounts()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Empty(suite.T(), accounts, "should be empty")
+
+	file := filepath.Join(suite.dir, "file")
+	suite.Require().NoError(ioutil.WriteFile(file, nil, 0600))
+	_, err = NewKeyStore(file, LightScryptN, LightScryptP).Accounts()
+	assert.Error(suite.T(), err, "should fail to read the directory")
+	_, err = NewKeyStore(file, LightScryptN, LightScryptP).Find(common.Address{1})
+	assert.Error(suite.T(), err, "should fail to read the directory")
+}
+
+func (suite *KeyStoreTestSuite) Test_ImportExport() {
+	address, err := suite.ks.Import([]byte(vectorPBKDF2), vectorPassphrase, "new")
+	suite.Require().NoError(err)
+	_, err = suite.ks.Import([]byte(vectorPBKDF2), vectorPassphrase, "new")
+	assert.Error(suite.T(), err, "should already exist")
+
+	keyFile, err := suite.ks.Export(address, "new", "exported")
+	suite.Require().NoError(err)
+	key, err := DecryptKey(keyFile, "exported")
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), address, key.Address, "should be equal")
+}
+
+func (suite *KeyStoreTestSuite) Test_Unlock() {
+	address, err := suite.ks.NewAccount("secret")
+	suite.Require().NoError(err)
+	tx := transaction.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+
+	_, err = suite.ks.SignTx(address, tx, transaction.MainnetChainID)
+	assert.Equal(suite.T(), ErrLocked, err, "should be equal")
+	assert.Equal(suite.T(), ErrDecrypt, suite.ks.Unlock(address, "wrong"), "should be equal")
+
+	suite.Require().NoError(suite.ks.Unlock(address, "secret"))
+	signed, err := suite.ks.SignTx(address, tx, transaction.MainnetChainID)
+	suite.Require().NoError(err)
+	sender, _ := signed.Sender()
+	assert.Equal(suite.T(), address, sender, "should be equal")
+
+	sig, err := suite.ks.SignMessage(address, []byte("hello"))
+	suite.Require().NoError(err)
+	signer, _ := accounts.RecoverMessage([]byte("hello"), sig)
+	assert.Equal(suite.T(), address, signer, "should be equal")
+
+	suite.ks.Lock(address)
+	assert.False(suite.T(), suite.ks.Unlocked(address), "should be locked")
+	_, err = suite.ks.SignMessage(address, []byte("hello"))
+	assert.Equal(suite.T(), ErrLocked, err, "should be equal")
+}
+
+func (suite *KeyStoreTestSuite) Test_TimedUnlock() {
+	address, err := suite.ks.NewAccount("secret")
+	suite.Require().NoError(err)
+
+	suite.Require().NoError(suite.ks.TimedUnlock(address, "secret", 50*time.Millisecond))
+	assert.True(suite.T(), suite.ks.Unlocked(address), "should be unlocked")
+	time.Sleep(100 * time.Millisecond)
+	assert.False(suite.T(), suite.ks.Unlocked(address), "should be locked")
+
+	// unlocking again replaces the timeout
+	suite.Require().NoError(suite.ks.TimedUnlock(address, "secret", 50*time.Millisecond))
+	suite.Require().NoError(suite.ks.Unlock(address, "secret"))
+	time.Sleep(100 * time.Millisecond)
+	assert.True(suite.T(), suite.ks.Unlocked(address), "should be unlocked")
+}
+
+func (suite *KeyStoreTestSuite) Test_Transactor() {
+	address, err := suite.ks.NewAccount("secret")
+	suite.Require().NoError(err)
+	suite.Require().NoError(suite.ks.Unlock(address, "secret"))
+
+	transactor := accounts.NewTransactor(nil, suite.ks, transaction.TestnetChainID)
+	tx, err := transactor.SignTransaction(&common.TransactionRequest{
+		From:     address,
+		To:       common.Address{1},
+		Gas:      "21000",
+		GasPrice: "1",
+		Nonce:    "0",
+	})
+	suite.Require().NoError(err)
+	sender, _ := tx.Sender()
+	assert.Equal(suite.T(), address, sender, "should be equal")
+}
+
+func (suite *KeyStoreTestSuite) SetupTest() {
+	var err error
+	suite.dir, err = ioutil.TempDir("", "keystore")
+	suite.Require().NoError(err)
+	suite.ks = NewKeyStore(suite.dir, LightScryptN, LightScryptP)
+}
+
+func (suite *KeyStoreTestSuite) TearDownTest() {
+	os.RemoveAll(suite.dir)
+}
+
+func Test_KeyStoreTestSuite(t *testing.T) {
+	suite.Run(t, new(KeyStoreTestSuite))
+}
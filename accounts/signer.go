@@ -0,0 +1,154 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package accounts signs transactions and messages with keys held in the
+// process, and sends the signed transactions.
+package accounts
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/crypto"
+	"github.com/caivega/chain3go/transaction"
+)
+
+// ErrUnknownAccount is returned when a signer doesn't hold the key of an
+// account
+var ErrUnknownAccount = errors.New("Unknown account")
+
+// MessagePrefix is prepended to messages before hashing them, so that a
+// signed message can't be a signed transaction
+var MessagePrefix = "\x19MoacNode Signed Message:\n"
+
+// Signer signs with the keys of its accounts
+type Signer interface {
+	// Accounts returns the addresses of the keys held by the signer
+	Accounts() ([]common.Address, error)
+	// SignTx returns a copy of tx signed by account, for the chain chainID
+	SignTx(account common.Address, tx *transaction.Transaction, chainID *big.Int) (*transaction.Transaction, error)
+	// SignMessage signs the TextHash of message, returning R || S || V with
+	// V being 27 or 28
+	SignMessage(account common.Address, message []byte) ([]byte, error)
+}
+
+// TextHash returns the hash signed by SignMessage for message
+func TextHash(message []byte) []byte {
+	prefix := fmt.Sprintf("%s%d", MessagePrefix, len(message))
+	return crypto.Keccak256([]byte(prefix), message)
+}
+
+// RecoverMessage returns the account which signed message
+func RecoverMessage(message, sig []byte) (common.Address, error) {
+	if len(sig) != crypto.SignatureLength || (sig[64] != 27 && sig[64] != 28) {
+		return common.Address{}, crypto.ErrInvalidSignature
+	}
+	rsv := make([]byte, crypto.SignatureLength)
+	copy(rsv, sig)
+	rsv[64] -= 27
+
+	pub, err := crypto.SigToPub(TextHash(message), rsv)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// KeySigner is a Signer holding private keys in memory
+type KeySigner struct {
+	mu       sync.RWMutex
+	keys     map[common.Address]*ecdsa.PrivateKey
+	accounts []common.Address
+}
+
+// NewKeySigner creates a signer with the given keys
+func NewKeySigner(keys ...*ecdsa.PrivateKey) *KeySigner {
+	s := &KeySigner{keys: map[common.Address]*ecdsa.PrivateKey{}}
+	for _, key := range keys {
+		s.Add(key)
+	}
+	return s
+}
+
+// Add adds a key to the signer, returning its account
+func (s *KeySigner) Add(key *ecdsa.PrivateKey) common.Address {
+	account := crypto.PubkeyToAddress(key.PublicKey)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[account]; !ok {
+		s.accounts = append(s.accounts, account)
+	}
+	s.keys[account] = key
+	return account
+}
+
+// Accounts returns the accounts in the order their keys were added
+func (s *KeySigner) Accounts() ([]common.Address, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]common.Address(nil), s.accounts...), nil
+}
+
+// SignTx ...
+func (s *KeySigner) SignTx(account common.Address, tx *transaction.Transaction, chainID *big.Int) (*transaction.Transaction, error) {
+	key, err := s.key(account)
+	if err != nil {
+		return nil, err
+	}
+	return transaction.SignTx(tx, chainID, key)
+}
+
+// SignMessage ...
+func (s *KeySigner) SignMessage(account common.Address, message []byte) ([]byte, error) {
+	key, err := s.key(account)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(TextHash(message), key)
+	if err != nil {
+		return nil, err
+	}
+	sig[64] += 27
+	return sig, nil
+}
+
+func (s *KeySigner) key(account common.Address) (*ecdsa.PrivateKey, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	key, ok := s.keys[account]
+	if !ok {
+		return nil, ErrUnknownAccount
+	}
+	return key, nil
+}
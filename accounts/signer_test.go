@@ -0,0 +1,120 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package accounts
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/crypto"
+	"github.com/caivega/chain3go/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SignerTestSuite struct {
+	suite.Suite
+}
+
+func (suite *SignerTestSuite) Test_Accounts() {
+	key1, _ := crypto.GenerateKey()
+	key2, _ := crypto.GenerateKey()
+	signer := NewKeySigner(key1)
+	account2 := signer.Add(key2)
+	signer.Add(key1)
+
+	accounts, err := signer.Accounts()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), []common.Address{crypto.PubkeyToAddress(key1.PublicKey), account2},
+		accounts, "should be equal")
+}
+
+func (suite *SignerTestSuite) Test_SignTx() {
+	key, _ := crypto.GenerateKey()
+	signer := NewKeySigner(key)
+	account := crypto.PubkeyToAddress(key.PublicKey)
+
+	tx := transaction.NewTransaction(0, common.Address{1}, big.NewInt(1), 21000, big.NewInt(1), nil)
+	signed, err := signer.SignTx(account, tx, transaction.TestnetChainID)
+	suite.Require().NoError(err)
+	sender, err := signed.Sender()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), account, sender, "should be equal")
+	chainID, err := signed.ChainID()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), transaction.TestnetChainID, chainID, "should be equal")
+
+	_, err = signer.SignTx(common.Address{2}, tx, transaction.TestnetChainID)
+	assert.Equal(suite.T(), ErrUnknownAccount, err, "should be equal")
+}
+
+func (suite *SignerTestSuite) Test_SignMessage() {
+	key, _ := crypto.HexToECDSA("4646464646464646464646464646464646464646464646464646464646464646")
+	signer := NewKeySigner(key)
+	account := crypto.PubkeyToAddress(key.PublicKey)
+
+	message := []byte("hello")
+	sig, err := signer.SignMessage(account, message)
+	suite.Require().NoError(err)
+	assert.True(suite.T(), sig[64] == 27 || sig[64] == 28, "should be 27 or 28")
+
+	recovered, err := RecoverMessage(message, sig)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), account, recovered, "should be equal")
+
+	recovered, err = RecoverMessage([]byte("other"), sig)
+	if err == nil {
+		assert.NotEqual(suite.T(), account, recovered, "should be another account")
+	}
+	_, err = RecoverMessage(message, sig[:64])
+	assert.Equal(suite.T(), crypto.ErrInvalidSignature, err, "should be equal")
+
+	_, err = signer.SignMessage(common.Address{}, message)
+	assert.Equal(suite.T(), ErrUnknownAccount, err, "should be equal")
+}
+
+func (suite *SignerTestSuite) Test_RecoverMessage() {
+	// "hello" signed by key 0x4646...46 over the MOAC message prefix, computed
+	// with secp256k1 and keccak outside this package
+	hash, _ := hex.DecodeString("78e568db7dd497a30a71f211ea317d3c3bf7c6776c3e1ed6cec472e06db65fb4")
+	sig, _ := hex.DecodeString("d68a6357e1cf6e07bdf9cd43ba50607404600b6ee751f00a6344c7bdc858a722" +
+		"217a0318484dcd5d8f76e83f1f3c8ebd79d67e2ff323e30353961a64ededfd251b")
+
+	assert.Equal(suite.T(), hash, TextHash([]byte("hello")), "should be equal")
+	recovered, err := RecoverMessage([]byte("hello"), sig)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), common.StringToAddress("0x9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f"), recovered, "should be equal")
+}
+
+func Test_SignerTestSuite(t *testing.T) {
+	suite.Run(t, new(SignerTestSuite))
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package accounts
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/caivega/chain3go/chain3"
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/transaction"
+)
+
+// ErrInvalidQuantity is returned for request fields which are not numbers
+var ErrInvalidQuantity = errors.New("Invalid quantity")
+
+// Transactor sends transaction requests signed by a Signer, filling the
+// missing nonce, gas and gas price from the node.
+type Transactor struct {
+	mc      chain3.Mc
+	signer  Signer
+	chainID *big.Int
+}
+
+// NewTransactor creates a transactor signing for the chain chainID, which
+// is chain3go/transaction.MainnetChainID or TestnetChainID for the MOAC
+// networks
+func NewTransactor(mc chain3.Mc, signer Signer, chainID *big.Int) *Transactor {
+	return &Transactor{
+		mc:      mc,
+		signer:  signer,
+		chainID: chainID,
+	}
+}
+
+// SendTransaction signs the request by its From account, and sends it with
+// Mc.SendRawTransaction. A zero To creates a contract.
+func (t *Transactor) SendTransaction(req *common.TransactionRequest) (common.Hash, error) {
+	return t.SendTransactionContext(context.Background(), req)
+}
+
+// SendTransactionContext is like SendTransaction but with a context.
+func (t *Transactor) SendTransactionContext(ctx context.Context, req *common.TransactionRequest) (common.Hash, error) {
+	tx, err := t.SignTransactionContext(ctx, req)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	raw, err := tx.MarshalBinary()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return t.mc.SendRawTransactionContext(ctx, raw)
+}
+
+// SignTransaction fills and signs the request, without sending it
+func (t *Transactor) SignTransaction(req *common.TransactionRequest) (*transaction.Transaction, error) {
+	return t.SignTransactionContext(context.Background(), req)
+}
+
+// SignTransactionContext is like SignTransaction but with a context.
+func (t *Transactor) SignTransactionContext(ctx context.Context, req *common.TransactionRequest) (*transaction.Transaction, error) {
+	tx, err := t.fill(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return t.signer.SignTx(req.From, tx, t.chainID)
+}
+
+// fill builds the transaction of the request, asking the node for the
+// missing values
+func (t *Transactor) fill(ctx context.Context, req *common.TransactionRequest) (*transaction.Transaction, error) {
+	tx := &transaction.Transaction{Data: req.Data}
+	if req.To != (common.Address{}) {
+		to := req.To
+		tx.To = &to
+	}
+
+	var err error
+	if tx.Value, err = quantity(req.Value); err != nil {
+		return nil, err
+	}
+	if tx.Value == nil {
+		tx.Value = new(big.Int)
+	}
+
+	nonce, err := quantity(req.Nonce)
+	if err != nil {
+		return nil, err
+	}
+	if nonce == nil {
+		if nonce, err = t.mc.GetTransactionCountContext(ctx, req.From, "pending"); err != nil {
+			return nil, err
+		}
+	}
+	if !nonce.IsUint64() {
+		return nil, ErrInvalidQuantity
+	}
+	tx.Nonce = nonce.Uint64()
+
+	if tx.GasPrice, err = quantity(req.GasPrice); err != nil {
+		return nil, err
+	}
+	if tx.GasPrice == nil {
+		if tx.GasPrice, err = t.mc.GasPriceContext(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	gas, err := quantity(req.Gas)
+	if err != nil {
+		return nil, err
+	}
+	if gas == nil {
+		if gas, err = t.mc.EstimateGasContext(ctx, req, "latest"); err != nil {
+			return nil, err
+		}
+	}
+	if !gas.IsUint64() {
+		return nil, ErrInvalidQuantity
+	}
+	tx.Gas = gas.Uint64()
+	return tx, nil
+}
+
+// quantity parses a number of the request, hex with 0x or decimal, or returns
+// nil when it is empty
+func quantity(s string) (*big.Int, error) {
+	if s == "" {
+		return nil, nil
+	}
+	base := 10
+	if len(s) > 2 && (s[:2] == "0x" || s[:2] == "0X") {
+		s, base = s[2:], 16
+	}
+	if s[0] == '+' || s[0] == '-' {
+		return nil, ErrInvalidQuantity
+	}
+	i, ok := new(big.Int).SetString(s, base)
+	if !ok {
+		return nil, ErrInvalidQuantity
+	}
+	return i, nil
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package accounts
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/caivega/chain3go/chain3"
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/crypto"
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+	"github.com/caivega/chain3go/transaction"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type TransactorTestSuite struct {
+	suite.Suite
+	server     *httptest.Server
+	transactor *Transactor
+	account    common.Address
+
+	mu       sync.Mutex
+	methods  []string
+	estimate map[string]interface{}
+	raw      *transaction.Transaction
+}
+
+func (suite *TransactorTestSuite) Test_Fill() {
+	hash, err := suite.transactor.SendTransaction(&common.TransactionRequest{
+		From:  suite.account,
+		To:    common.Address{0x35},
+		Value: "0x10",
+		Data:  common.Data{0xca, 0xfe},
+	})
+	suite.Require().NoError(err)
+
+	suite.mu.Lock()
+	defer suite.mu.Unlock()
+	assert.Equal(suite.T(), []string{"mc_getTransactionCount", "mc_gasPrice", "mc_estimateGas", "mc_sendRawTransaction"},
+		suite.methods, "should be equal")
+	assert.Equal(suite.T(), "0xcafe", suite.estimate["data"], "should send the request as an object")
+	assert.Equal(suite.T(), "0x10", suite.estimate["value"], "should be equal")
+	assert.Nil(suite.T(), suite.estimate["gas"], "should be nil")
+
+	tx := suite.raw
+	rawHash, err := suite.raw.Hash()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), rawHash, hash, "should be equal")
+	assert.EqualValues(suite.T(), 7, tx.Nonce, "should be equal")
+	assert.EqualValues(suite.T(), 1000, tx.GasPrice.Int64(), "should be equal")
+	assert.EqualValues(suite.T(), 21000, tx.Gas, "should be equal")
+	assert.EqualValues(suite.T(), 16, tx.Value.Int64(), "should be equal")
+	assert.Equal(suite.T(), common.Address{0x35}, *tx.To, "should be equal")
+	sender, err := tx.Sender()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), suite.account, sender, "should be equal")
+	chainID, err := tx.ChainID()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), transaction.MainnetChainID, chainID, "should be equal")
+}
+
+func (suite *TransactorTestSuite) Test_Given() {
+	tx, err := suite.transactor.SignTransaction(&common.TransactionRequest{
+		From:     suite.account,
+		Gas:      "100000",
+		GasPrice: "0x1",
+		Nonce:    "0x2",
+		Data:     common.Data{0x60},
+	})
+	suite.Require().NoError(err)
+	assert.Empty(suite.T(), suite.methods, "should not ask the node")
+	assert.Nil(suite.T(), tx.To, "should create a contract")
+	assert.EqualValues(suite.T(), 100000, tx.Gas, "should be equal")
+	assert.EqualValues(suite.T(), 2, tx.Nonce, "should be equal")
+	assert.EqualValues(suite.T(), 0, tx.Value.Int64(), "should be equal")
+
+	_, err = suite.transactor.SignTransaction(&common.TransactionRequest{From: suite.account, Gas: "lots"})
+	assert.Equal(suite.T(), ErrInvalidQuantity, err, "should be equal")
+
+	for _, gas := range []string{"0b101", "0o17", "1_000", "+1", "0x", "0x-1", "0x10000000000000000"} {
+		_, err = suite.transactor.SignTransaction(&common.TransactionRequest{From: suite.account, Gas: gas, GasPrice: "1", Nonce: "1"})
+		assert.Equal(suite.T(), ErrInvalidQuantity, err, "should reject "+gas)
+	}
+	_, err = suite.transactor.SignTransaction(&common.TransactionRequest{From: suite.account, Gas: "1", GasPrice: "1", Nonce: "18446744073709551616"})
+	assert.Equal(suite.T(), ErrInvalidQuantity, err, "should be equal")
+
+	_, err = suite.transactor.SignTransaction(&common.TransactionRequest{From: common.Address{1}, Gas: "1", GasPrice: "1", Nonce: "1"})
+	assert.Equal(suite.T(), ErrUnknownAccount, err, "should be equal")
+}
+
+func (suite *TransactorTestSuite) SetupTest() {
+	suite.methods = nil
+	suite.estimate = nil
+	suite.raw = nil
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			rpc.JSONRPCRequest
+			Params []json.RawMessage `json:"params"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&req)
+
+		suite.mu.Lock()
+		defer suite.mu.Unlock()
+		suite.methods = append(suite.methods, req.Method)
+		resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier}
+		switch req.Method {
+		case "mc_getTransactionCount":
+			resp.Result = "0x7"
+		case "mc_gasPrice":
+			resp.Result = "0x3e8"
+		case "mc_estimateGas":
+			json.Unmarshal(req.Params[0], &suite.estimate)
+			resp.Result = "0x5208"
+		case "mc_sendRawTransaction":
+			var raw string
+			json.Unmarshal(req.Params[0], &raw)
+			suite.raw, _ = transaction.DecodeTransaction(common.HexToBytes(raw))
+			hash, _ := suite.raw.Hash()
+			resp.Result = hash.String()
+		}
+		jsonBlob, _ := json.Marshal(resp)
+		w.Write(jsonBlob)
+	}))
+
+	key, _ := crypto.GenerateKey()
+	signer := NewKeySigner(key)
+	suite.account = crypto.PubkeyToAddress(key.PublicKey)
+	mc := chain3.NewChain3(provider.NewHTTPProvider(suite.server.URL, nil)).Mc
+	suite.transactor = NewTransactor(mc, signer, transaction.MainnetChainID)
+}
+
+func (suite *TransactorTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func Test_TransactorTestSuite(t *testing.T) {
+	suite.Run(t, new(TransactorTestSuite))
+}
@@ -0,0 +1,211 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package chain3
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/rpc"
+)
+
+// BatchResult is the outcome of one request of a batch
+type BatchResult struct {
+	Response rpc.Response
+	Err      error
+}
+
+// BatchError reports which requests of a batch failed, by index. The results
+// of the other requests are still returned alongside it.
+type BatchError map[int]error
+
+func (e BatchError) Error() string {
+	indexes := make([]int, 0, len(e))
+	for i := range e {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+
+	buffer := new(bytes.Buffer)
+	fmt.Fprintf(buffer, "%d of the batch requests failed", len(e))
+	for _, i := range indexes {
+		fmt.Fprintf(buffer, "; [%d] %v", i, e[i])
+	}
+	return buffer.String()
+}
+
+// sendBatch sends one request per params with the given method, and decodes
+// each result with decode. A null result is left to decode.
+func (mc *MoacAPI) sendBatch(ctx context.Context, method string, params [][]interface{}, decode func(i int, result []byte) error) error {
+	requests := make([]rpc.Request, len(params))
+	for i, p := range params {
+		requests[i] = mc.requestManager.NewRequest(method)
+		requests[i].Set("params", p)
+	}
+
+	results, err := mc.requestManager.SendBatchContext(ctx, requests)
+	if err != nil {
+		return err
+	}
+
+	batchErr := make(BatchError)
+	for i, r := range results {
+		if r.Err != nil {
+			batchErr[i] = r.Err
+			continue
+		}
+		result, err := json.Marshal(r.Response.Get("result"))
+		if err == nil {
+			err = decode(i, result)
+		}
+		if err != nil {
+			batchErr[i] = err
+		}
+	}
+
+	if len(batchErr) > 0 {
+		return batchErr
+	}
+	return nil
+}
+
+func decodeBlock(result []byte) (*common.Block, error) {
+	if string(result) == "null" {
+		return nil, nil
+	}
+	block := &JSONBlock{}
+	if err := json.Unmarshal(result, block); err != nil {
+		return nil, err
+	}
+	return block.ToBlock(), nil
+}
+
+// GetBlocksByHash returns information about blocks by hash, in one round trip
+// if the provider supports batches. Unknown blocks are nil.
+func (mc *MoacAPI) GetBlocksByHash(hashes []common.Hash, full bool) ([]*common.Block, error) {
+	return mc.GetBlocksByHashContext(context.Background(), hashes, full)
+}
+
+// GetBlocksByHashContext is like GetBlocksByHash but with a context.
+func (mc *MoacAPI) GetBlocksByHashContext(ctx context.Context, hashes []common.Hash, full bool) ([]*common.Block, error) {
+	params := make([][]interface{}, len(hashes))
+	for i, hash := range hashes {
+		params[i] = []interface{}{hash.String(), full}
+	}
+
+	blocks := make([]*common.Block, len(hashes))
+	err := mc.sendBatch(ctx, "mc_getBlockByHash", params, func(i int, result []byte) (err error) {
+		blocks[i], err = decodeBlock(result)
+		return err
+	})
+	return blocks, err
+}
+
+// GetBlocksByNumber returns information about blocks by block number, in one
+// round trip if the provider supports batches. Unknown blocks are nil.
+func (mc *MoacAPI) GetBlocksByNumber(quantities []string, full bool) ([]*common.Block, error) {
+	return mc.GetBlocksByNumberContext(context.Background(), quantities, full)
+}
+
+// GetBlocksByNumberContext is like GetBlocksByNumber but with a context.
+func (mc *MoacAPI) GetBlocksByNumberContext(ctx context.Context, quantities []string, full bool) ([]*common.Block, error) {
+	params := make([][]interface{}, len(quantities))
+	for i, quantity := range quantities {
+		params[i] = []interface{}{quantity, full}
+	}
+
+	blocks := make([]*common.Block, len(quantities))
+	err := mc.sendBatch(ctx, "mc_getBlockByNumber", params, func(i int, result []byte) (err error) {
+		blocks[i], err = decodeBlock(result)
+		return err
+	})
+	return blocks, err
+}
+
+// GetTransactionsByHash returns information about transactions by hash, in one
+// round trip if the provider supports batches. Unknown transactions are nil.
+func (mc *MoacAPI) GetTransactionsByHash(hashes []common.Hash) ([]*common.Transaction, error) {
+	return mc.GetTransactionsByHashContext(context.Background(), hashes)
+}
+
+// GetTransactionsByHashContext is like GetTransactionsByHash but with a context.
+func (mc *MoacAPI) GetTransactionsByHashContext(ctx context.Context, hashes []common.Hash) ([]*common.Transaction, error) {
+	params := make([][]interface{}, len(hashes))
+	for i, hash := range hashes {
+		params[i] = []interface{}{hash.String()}
+	}
+
+	txs := make([]*common.Transaction, len(hashes))
+	err := mc.sendBatch(ctx, "mc_getTransactionByHash", params, func(i int, result []byte) error {
+		if string(result) == "null" {
+			return nil
+		}
+		tx := &JSONTransaction{}
+		if err := json.Unmarshal(result, tx); err != nil {
+			return err
+		}
+		txs[i] = tx.ToTransaction()
+		return nil
+	})
+	return txs, err
+}
+
+// GetTransactionReceipts returns the receipts of transactions by hash, in one
+// round trip if the provider supports batches. The receipts of pending or
+// unknown transactions are nil.
+func (mc *MoacAPI) GetTransactionReceipts(hashes []common.Hash) ([]*common.TransactionReceipt, error) {
+	return mc.GetTransactionReceiptsContext(context.Background(), hashes)
+}
+
+// GetTransactionReceiptsContext is like GetTransactionReceipts but with a context.
+func (mc *MoacAPI) GetTransactionReceiptsContext(ctx context.Context, hashes []common.Hash) ([]*common.TransactionReceipt, error) {
+	params := make([][]interface{}, len(hashes))
+	for i, hash := range hashes {
+		params[i] = []interface{}{hash.String()}
+	}
+
+	receipts := make([]*common.TransactionReceipt, len(hashes))
+	err := mc.sendBatch(ctx, "mc_getTransactionReceipt", params, func(i int, result []byte) error {
+		if string(result) == "null" {
+			return nil
+		}
+		receipt := &JSONTransactionReceipt{}
+		if err := json.Unmarshal(result, receipt); err != nil {
+			return err
+		}
+		receipts[i] = receipt.ToTransactionReceipt()
+		return nil
+	})
+	return receipts, err
+}
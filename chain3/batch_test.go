@@ -0,0 +1,179 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package chain3
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// singleProvider hides the batch support of the provider it wraps
+type singleProvider struct {
+	provider.Provider
+}
+
+type BatchTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	provider provider.Provider
+	batches  int
+}
+
+func (suite *BatchTestSuite) Test_GetBlocksByNumber() {
+	mc := NewChain3(suite.provider).Mc
+	blocks, err := mc.GetBlocksByNumber([]string{"0x1", "0x2", "0x3"}, false)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), 1, suite.batches, "should be sent at once")
+	if assert.Len(suite.T(), blocks, 3) {
+		for i, block := range blocks {
+			assert.EqualValues(suite.T(), big.NewInt(int64(i+1)), block.Number, "should be equal")
+		}
+	}
+}
+
+func (suite *BatchTestSuite) Test_GetBlocksByHash() {
+	mc := NewChain3(suite.provider).Mc
+	blocks, err := mc.GetBlocksByHash([]common.Hash{
+		common.StringToHash("0x01"),
+		common.StringToHash("0xff"),
+	}, false)
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), blocks, 2) {
+		assert.EqualValues(suite.T(), big.NewInt(1), blocks[0].Number, "should be equal")
+		assert.Nil(suite.T(), blocks[1], "should be unknown")
+	}
+}
+
+func (suite *BatchTestSuite) Test_GetTransactionReceipts() {
+	mc := NewChain3(suite.provider).Mc
+	receipts, err := mc.GetTransactionReceipts([]common.Hash{
+		common.StringToHash("0x01"),
+		common.StringToHash("0x02"),
+		common.StringToHash("0x03"),
+	})
+	if assert.IsType(suite.T(), BatchError{}, err) {
+		batchErr := err.(BatchError)
+		assert.Len(suite.T(), batchErr, 1)
+		assert.EqualError(suite.T(), batchErr[1], "unknown transaction")
+	}
+	if assert.Len(suite.T(), receipts, 3) {
+		assert.EqualValues(suite.T(), big.NewInt(0x21), receipts[0].GasUsed, "should be equal")
+		assert.Nil(suite.T(), receipts[1], "should be failed")
+		assert.EqualValues(suite.T(), big.NewInt(0x23), receipts[2].GasUsed, "should be equal")
+	}
+}
+
+func (suite *BatchTestSuite) Test_SendBatchFallback() {
+	mc := NewChain3(&singleProvider{suite.provider}).Mc
+	blocks, err := mc.GetBlocksByNumber([]string{"0x1", "0x2"}, false)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), 0, suite.batches, "should be sent one by one")
+	assert.Len(suite.T(), blocks, 2)
+}
+
+func (suite *BatchTestSuite) Test_MissingResponse() {
+	rm := NewRequestManager(suite.provider)
+	requests := []rpc.Request{rm.NewRequest("mc_blockNumber"), rm.NewRequest("test_skipped")}
+	results, err := rm.SendBatch(requests)
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), results, 2) {
+		assert.NoError(suite.T(), results[0].Err, "Should be no error")
+		assert.Equal(suite.T(), ErrMissingResponse, results[1].Err, "should be equal")
+	}
+}
+
+func (suite *BatchTestSuite) answer(req rpc.JSONRPCRequest) *rpc.JSONRPCResponse {
+	resp := &rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier}
+	switch req.Method {
+	case "mc_blockNumber":
+		resp.Result = "0x3"
+	case "mc_getBlockByNumber":
+		resp.Result = map[string]interface{}{"number": req.Params[0]}
+	case "mc_getBlockByHash":
+		if strings.HasPrefix(req.Params[0].(string), "0x01") {
+			resp.Result = map[string]interface{}{"number": "0x1"}
+		}
+	case "mc_getTransactionReceipt":
+		hash := req.Params[0].(string)
+		if strings.HasPrefix(hash, "0x02") {
+			resp.Err = &rpc.JSONRPCError{Code: -32000, Message: "unknown transaction"}
+		} else {
+			resp.Result = map[string]interface{}{"gasUsed": "0x2" + hash[3:4]}
+		}
+	case "test_skipped":
+		return nil
+	}
+	return resp
+}
+
+func (suite *BatchTestSuite) SetupTest() {
+	suite.batches = 0
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		reqs := []rpc.JSONRPCRequest{}
+		if err := json.Unmarshal(body, &reqs); err == nil {
+			suite.batches++
+			resps := []*rpc.JSONRPCResponse{}
+			for i := len(reqs) - 1; i >= 0; i-- {
+				if resp := suite.answer(reqs[i]); resp != nil {
+					resps = append(resps, resp)
+				}
+			}
+			jsonBlob, _ := json.Marshal(resps)
+			w.Write(jsonBlob)
+			return
+		}
+
+		req := rpc.JSONRPCRequest{}
+		json.Unmarshal(body, &req)
+		jsonBlob, _ := json.Marshal(suite.answer(req))
+		w.Write(jsonBlob)
+	}))
+	suite.provider = provider.NewHTTPProvider(suite.server.URL, nil)
+}
+
+func (suite *BatchTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func Test_BatchTestSuite(t *testing.T) {
+	suite.Run(t, new(BatchTestSuite))
+}
@@ -36,10 +36,14 @@ import (
 	"time"
 
 	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/rpc"
 )
 
 var (
 	ErrChannelClosed = errors.New("Channel is closed")
+	// ErrFilterType is returned when reading values of the wrong kind from a
+	// filter, like logs from a block filter
+	ErrFilterType = errors.New("Wrong filter type")
 )
 
 const (
@@ -47,20 +51,30 @@ const (
 	dataBufferSize = 16
 )
 
+// FilterType is the kind of a filter, which determines what it yields
 type FilterType int
 
 const (
+	// TypeNormal filters yield logs
 	TypeNormal FilterType = iota
+	// TypeBlockFilter filters yield the hashes of new blocks
 	TypeBlockFilter
+	// TypeTransactionFilter filters yield the hashes of new pending
+	// transactions
 	TypeTransactionFilter
 )
 
 // FilterOption ...
 type FilterOption struct {
-	FromBlock string        `json:"fromBlock,omitempty"`
-	ToBlock   string        `json:"toBlock,omitempty"`
-	Address   interface{}   `json:"address,omitempty"`
-	Topics    []common.Data `json:"topics,omitempty"`
+	FromBlock string `json:"fromBlock,omitempty"`
+	ToBlock   string `json:"toBlock,omitempty"`
+	// BlockHash restricts a query to a single block. It excludes FromBlock
+	// and ToBlock.
+	BlockHash string `json:"blockHash,omitempty"`
+	// Address is a hex string, a common.Address, or a slice of either
+	Address interface{} `json:"address,omitempty"`
+	// Topics match by position, a nil topic matching anything
+	Topics []common.Data `json:"topics,omitempty"`
 }
 
 func (opt *FilterOption) String() string {
@@ -68,89 +82,302 @@ func (opt *FilterOption) String() string {
 	return string(rawBytes)
 }
 
+// MarshalJSON encodes addresses and topics as hex strings
+func (opt FilterOption) MarshalJSON() ([]byte, error) {
+	topics := make([]*string, len(opt.Topics))
+	for i, topic := range opt.Topics {
+		if topic != nil {
+			hex := topic.String()
+			topics[i] = &hex
+		}
+	}
+
+	address := opt.Address
+	switch a := address.(type) {
+	case common.Address:
+		address = a.String()
+	case []common.Address:
+		addresses := make([]string, len(a))
+		for i := range a {
+			addresses[i] = a[i].String()
+		}
+		address = addresses
+	}
+
+	return json.Marshal(&struct {
+		FromBlock string      `json:"fromBlock,omitempty"`
+		ToBlock   string      `json:"toBlock,omitempty"`
+		BlockHash string      `json:"blockHash,omitempty"`
+		Address   interface{} `json:"address,omitempty"`
+		Topics    []*string   `json:"topics,omitempty"`
+	}{opt.FromBlock, opt.ToBlock, opt.BlockHash, address, topics})
+}
+
 // Filter ...
 type Filter interface {
-	Watch() WatchChannel
+	Watch(opts ...WatchOption) WatchChannel
 	ID() string
+	Type() FilterType
+}
+
+// FilterChanges are the changes returned by a filter poll
+type FilterChanges struct {
+	// Hashes holds the hashes of new blocks or pending transactions, for
+	// block and pending transaction filters
+	Hashes []common.Hash
+	// Logs holds the new logs, for log filters
+	Logs []common.Log
 }
 
 type baseFilter struct {
 	mc         Mc
 	filterType FilterType
-	filterID   string
+	option     *FilterOption
+
+	mu       sync.RWMutex
+	filterID string
+}
+
+// OverflowPolicy decides what a watch does with new changes while its buffer
+// is full
+type OverflowPolicy int
+
+const (
+	// OverflowBlock stops polling until the consumer reads
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDrop discards the changes that don't fit in the buffer. Errors
+	// are never dropped, polling stops until they fit.
+	OverflowDrop
+)
+
+type watchOptions struct {
+	pollInterval time.Duration
+	bufferSize   int
+	overflow     OverflowPolicy
 }
 
-// WatchChannel ...
+// WatchOption configures a filter watch
+type WatchOption func(*watchOptions)
+
+// WithPollInterval sets how often the filter is polled for changes
+func WithPollInterval(interval time.Duration) WatchOption {
+	return func(opts *watchOptions) {
+		if interval > 0 {
+			opts.pollInterval = interval
+		}
+	}
+}
+
+// WithBufferSize sets how many changes are buffered for the consumer
+func WithBufferSize(size int) WatchOption {
+	return func(opts *watchOptions) {
+		if size >= 0 {
+			opts.bufferSize = size
+		}
+	}
+}
+
+// WithOverflowPolicy sets what happens to changes while the buffer is full
+func WithOverflowPolicy(policy OverflowPolicy) WatchOption {
+	return func(opts *watchOptions) {
+		opts.overflow = policy
+	}
+}
+
+// WatchChannel delivers the changes of a filter
 type WatchChannel interface {
+	// Next returns the next change: a common.Hash for block and pending
+	// transaction filters, a common.Log for log filters. Polling errors are
+	// returned in order with the changes, and the watch keeps polling after
+	// them.
 	Next() (interface{}, error)
+	// NextHash returns the next hash of a block or pending transaction filter
+	NextHash() (common.Hash, error)
+	// NextLog returns the next log of a log filter
+	NextLog() (common.Log, error)
+	// Close stops the watch. It doesn't block and may be called more than
+	// once.
 	Close()
 }
 
 type watchChannel struct {
-	dataCh  chan interface{}
-	closeCh chan struct{}
+	dataCh    chan interface{}
+	closeCh   chan struct{}
+	closeOnce sync.Once
 }
 
 // -----------------------------------------------------------------------------
 // Filter
 
 // newFilter creates a filter object, based on filter options and filter id.
-func newFilter(mc Mc, filterType FilterType, id string) Filter {
+func newFilter(mc Mc, filterType FilterType, option *FilterOption, id string) Filter {
 	return &baseFilter{
 		mc:         mc,
 		filterType: filterType,
+		option:     option,
 		filterID:   id,
 	}
 }
 
-func (f *baseFilter) Watch() WatchChannel {
-	dataCh := make(chan interface{}, dataBufferSize)
-	closeCh := make(chan struct{})
-	var wg sync.WaitGroup
-	wg.Add(1)
+// Watch polls the filter for changes until the returned channel is closed.
+// When the node reports the filter as expired it is created again, with the
+// changes in between being lost.
+func (f *baseFilter) Watch(opts ...WatchOption) WatchChannel {
+	options := watchOptions{
+		pollInterval: pollInterval,
+		bufferSize:   dataBufferSize,
+		overflow:     OverflowBlock,
+	}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	wc := &watchChannel{
+		dataCh:  make(chan interface{}, options.bufferSize),
+		closeCh: make(chan struct{}),
+	}
+	go f.poll(wc, &options)
+	return wc
+}
 
-	go func(wg *sync.WaitGroup, closeCh <-chan struct{}, dataCh chan<- interface{}) {
-		// TODO: configurable timer
-		ticker := time.NewTicker(pollInterval)
-		defer ticker.Stop()
+func (f *baseFilter) poll(wc *watchChannel, options *watchOptions) {
+	defer close(wc.dataCh)
 
-		wg.Done()
+	ticker := time.NewTicker(options.pollInterval)
+	defer ticker.Stop()
 
-		for {
+	// push hands data to the consumer, and reports false once the watch is
+	// closed
+	push := func(data interface{}) bool {
+		select {
+		case <-wc.closeCh:
+			return false
+		default:
+		}
+		if _, isErr := data.(error); !isErr && options.overflow == OverflowDrop {
 			select {
-			case <-closeCh:
-				close(dataCh)
+			case wc.dataCh <- data:
+			default:
+			}
+			return true
+		}
+		select {
+		case <-wc.closeCh:
+			return false
+		case wc.dataCh <- data:
+			return true
+		}
+	}
+
+	for {
+		select {
+		case <-wc.closeCh:
+			return
+		case <-ticker.C:
+		}
+
+		changes, err := f.mc.GetFilterChanges(f)
+		if err != nil && errors.Is(err, rpc.ErrFilterNotFound) {
+			if err = f.reinstall(); err == nil {
+				continue
+			}
+		}
+		if err != nil {
+			if !push(err) {
 				return
-			case <-ticker.C:
-				results, _ := f.mc.GetFilterChanges(f)
-				for _, r := range results {
-					dataCh <- r
-				}
 			}
+			continue
 		}
-	}(&wg, closeCh, dataCh)
 
-	return &watchChannel{
-		dataCh:  dataCh,
-		closeCh: closeCh,
+		for _, hash := range changes.Hashes {
+			if !push(hash) {
+				return
+			}
+		}
+		for _, log := range changes.Logs {
+			if !push(log) {
+				return
+			}
+		}
 	}
 }
 
+// reinstall creates the filter again in the node, after it expired
+func (f *baseFilter) reinstall() error {
+	var (
+		filter Filter
+		err    error
+	)
+	switch f.filterType {
+	case TypeBlockFilter:
+		filter, err = f.mc.NewBlockFilter()
+	case TypeTransactionFilter:
+		filter, err = f.mc.NewPendingTransactionFilter()
+	default:
+		filter, err = f.mc.NewFilter(f.option)
+	}
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	f.filterID = filter.ID()
+	f.mu.Unlock()
+	return nil
+}
+
 // ID returns the filter identifier
 func (f *baseFilter) ID() string {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
 	return f.filterID
 }
 
+// Type returns the kind of the filter
+func (f *baseFilter) Type() FilterType {
+	return f.filterType
+}
+
 // -----------------------------------------------------------------------------
 // WatchChannel
 
 func (wc *watchChannel) Next() (interface{}, error) {
-	if data, ok := <-wc.dataCh; ok {
-		return data, nil
+	data, ok := <-wc.dataCh
+	if !ok {
+		return nil, ErrChannelClosed
+	}
+	if err, ok := data.(error); ok {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (wc *watchChannel) NextHash() (common.Hash, error) {
+	data, err := wc.Next()
+	if err != nil {
+		return common.Hash{}, err
+	}
+	hash, ok := data.(common.Hash)
+	if !ok {
+		return common.Hash{}, ErrFilterType
+	}
+	return hash, nil
+}
+
+func (wc *watchChannel) NextLog() (common.Log, error) {
+	data, err := wc.Next()
+	if err != nil {
+		return common.Log{}, err
+	}
+	log, ok := data.(common.Log)
+	if !ok {
+		return common.Log{}, ErrFilterType
 	}
-	return nil, ErrChannelClosed
+	return log, nil
 }
 
 func (wc *watchChannel) Close() {
-	wc.closeCh <- struct{}{}
+	wc.closeOnce.Do(func() {
+		close(wc.closeCh)
+	})
 }
@@ -0,0 +1,293 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package chain3
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const (
+	testBlockHash = "0x8216c5785ac562ff41e2dcfdf5785ac562ff41e2dcfdf829c5a142f1fccd7d00"
+	testTxHash    = "0xdf829c5a142f1fccd7d8216c5785ac562ff41e2dcfdf5785ac562ff41e2dcf00"
+	testAddress   = "0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d"
+)
+
+type FilterTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	chain3 *Chain3
+
+	mu           sync.Mutex
+	expired      map[string]bool
+	failing      bool
+	blockFilters int
+}
+
+func (suite *FilterTestSuite) Test_BlockFilter() {
+	filter, err := suite.chain3.Mc.NewBlockFilter()
+	suite.Require().NoError(err)
+	assert.EqualValues(suite.T(), TypeBlockFilter, filter.Type(), "should be equal")
+
+	changes, err := suite.chain3.Mc.GetFilterChanges(filter)
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), changes.Hashes, 1) {
+		assert.EqualValues(suite.T(), testBlockHash, changes.Hashes[0].String(), "should be equal")
+	}
+	assert.Empty(suite.T(), changes.Logs, "should be empty")
+
+	_, err = suite.chain3.Mc.GetFilterLogs(filter)
+	assert.Equal(suite.T(), ErrFilterType, err, "should be equal")
+}
+
+func (suite *FilterTestSuite) Test_LogFilter() {
+	filter, err := suite.chain3.Mc.NewFilter(&FilterOption{Address: testAddress})
+	suite.Require().NoError(err)
+	assert.EqualValues(suite.T(), TypeNormal, filter.Type(), "should be equal")
+
+	changes, err := suite.chain3.Mc.GetFilterChanges(filter)
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), changes.Logs, 1) {
+		assert.EqualValues(suite.T(), testAddress, changes.Logs[0].Address.String(), "should be equal")
+		assert.EqualValues(suite.T(), 0x1b4, changes.Logs[0].BlockNumber.Int64(), "should be equal")
+	}
+
+	logs, err := suite.chain3.Mc.GetFilterLogs(filter)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), logs, 1, "should be equal")
+}
+
+func (suite *FilterTestSuite) Test_Watch() {
+	filter, err := suite.chain3.Mc.NewPendingTransactionFilter()
+	suite.Require().NoError(err)
+
+	watch := filter.Watch()
+	hash, err := watch.NextHash()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), testTxHash, hash.String(), "should be equal")
+	_, err = watch.NextLog()
+	assert.Equal(suite.T(), ErrFilterType, err, "should be equal")
+	watch.Close()
+
+	filter, err = suite.chain3.Mc.NewFilter(nil)
+	suite.Require().NoError(err)
+	watch = filter.Watch()
+	log, err := watch.NextLog()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), testAddress, log.Address.String(), "should be equal")
+	next, err := watch.Next()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.IsType(suite.T(), log, next, "should be a log")
+	watch.Close()
+}
+
+func (suite *FilterTestSuite) Test_WatchOptions() {
+	filter, err := suite.chain3.Mc.NewPendingTransactionFilter()
+	suite.Require().NoError(err)
+
+	watch := filter.Watch(WithPollInterval(time.Millisecond), WithBufferSize(2),
+		WithOverflowPolicy(OverflowDrop))
+	time.Sleep(50 * time.Millisecond)
+	watch.Close()
+
+	count := 0
+	for {
+		_, err := watch.NextHash()
+		if err != nil {
+			assert.Equal(suite.T(), ErrChannelClosed, err, "should be equal")
+			break
+		}
+		count++
+	}
+	assert.True(suite.T(), count <= 2, "should drop what doesn't fit")
+}
+
+func (suite *FilterTestSuite) Test_WatchDropKeepsErrors() {
+	filter, err := suite.chain3.Mc.NewPendingTransactionFilter()
+	suite.Require().NoError(err)
+
+	watch := filter.Watch(WithPollInterval(time.Millisecond), WithBufferSize(2),
+		WithOverflowPolicy(OverflowDrop))
+	defer watch.Close()
+	time.Sleep(20 * time.Millisecond)
+
+	// the node fails while the buffer is full, then recovers
+	suite.mu.Lock()
+	suite.failing = true
+	suite.mu.Unlock()
+	time.Sleep(20 * time.Millisecond)
+	suite.mu.Lock()
+	suite.failing = false
+	suite.mu.Unlock()
+
+	for i := 0; i < 2; i++ {
+		_, err := watch.NextHash()
+		assert.NoError(suite.T(), err, "Should be no error")
+	}
+	_, err = watch.Next()
+	assert.True(suite.T(), errors.Is(err, rpc.ErrInternal), "should not drop the error")
+}
+
+func (suite *FilterTestSuite) Test_WatchClose() {
+	filter, err := suite.chain3.Mc.NewPendingTransactionFilter()
+	suite.Require().NoError(err)
+
+	// nothing reads, so the watch blocks on a full buffer
+	watch := filter.Watch(WithPollInterval(time.Millisecond), WithBufferSize(0))
+	time.Sleep(10 * time.Millisecond)
+
+	done := make(chan struct{})
+	go func() {
+		watch.Close()
+		watch.Close()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		suite.T().Fatal("Close should not block")
+	}
+
+	for {
+		if _, err := watch.Next(); err != nil {
+			assert.Equal(suite.T(), ErrChannelClosed, err, "should be equal")
+			break
+		}
+	}
+	watch.Close()
+}
+
+func (suite *FilterTestSuite) Test_WatchError() {
+	filter := newFilter(suite.chain3.Mc, TypeBlockFilter, nil, "0x9")
+
+	watch := filter.Watch(WithPollInterval(time.Millisecond))
+	defer watch.Close()
+	_, err := watch.Next()
+	assert.True(suite.T(), errors.Is(err, rpc.ErrInternal), "should be a node error")
+	_, err = watch.Next()
+	assert.Error(suite.T(), err, "should keep polling after errors")
+}
+
+func (suite *FilterTestSuite) Test_WatchReinstall() {
+	filter, err := suite.chain3.Mc.NewBlockFilter()
+	suite.Require().NoError(err)
+	assert.EqualValues(suite.T(), "0x2", filter.ID(), "should be equal")
+
+	suite.mu.Lock()
+	suite.expired["0x2"] = true
+	suite.mu.Unlock()
+
+	watch := filter.Watch(WithPollInterval(time.Millisecond))
+	defer watch.Close()
+	hash, err := watch.NextHash()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), testTxHash, hash.String(), "should be equal")
+	assert.EqualValues(suite.T(), "0x4", filter.ID(), "should be recreated")
+}
+
+func (suite *FilterTestSuite) SetupTest() {
+	suite.expired = map[string]bool{}
+	suite.failing = false
+	suite.blockFilters = 0
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := rpc.JSONRPCRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier}
+		switch req.Method {
+		case "mc_newFilter":
+			resp.Result = "0x1"
+		case "mc_newBlockFilter":
+			suite.mu.Lock()
+			suite.blockFilters++
+			if suite.blockFilters == 1 {
+				resp.Result = "0x2"
+			} else {
+				resp.Result = "0x4"
+			}
+			suite.mu.Unlock()
+		case "mc_newPendingTransactionFilter":
+			resp.Result = "0x3"
+		case "mc_getFilterChanges", "mc_getFilterLogs":
+			suite.mu.Lock()
+			expired := suite.expired[req.Params[0].(string)]
+			suite.mu.Unlock()
+			if expired {
+				resp.Err = &rpc.JSONRPCError{Code: -32000, Message: "filter not found"}
+				break
+			}
+			suite.mu.Lock()
+			failing := suite.failing
+			suite.mu.Unlock()
+			if failing {
+				resp.Err = &rpc.JSONRPCError{Code: -32603, Message: "internal error"}
+				break
+			}
+			switch req.Params[0] {
+			case "0x1":
+				resp.Result = []map[string]interface{}{{
+					"address":     testAddress,
+					"blockHash":   testBlockHash,
+					"blockNumber": "0x1b4",
+					"logIndex":    "0x1",
+					"topics":      []string{"0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5"},
+				}}
+			case "0x2":
+				resp.Result = []string{testBlockHash}
+			case "0x3":
+				resp.Result = []string{testTxHash}
+			case "0x4":
+				resp.Result = []string{testTxHash}
+			default:
+				resp.Err = &rpc.JSONRPCError{Code: -32603, Message: "internal error"}
+			}
+		}
+		jsonBlob, _ := json.Marshal(resp)
+		w.Write(jsonBlob)
+	}))
+	suite.chain3 = NewChain3(provider.NewHTTPProvider(suite.server.URL, nil))
+}
+
+func (suite *FilterTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func Test_FilterTestSuite(t *testing.T) {
+	suite.Run(t, new(FilterTestSuite))
+}
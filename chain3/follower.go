@@ -0,0 +1,423 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package chain3
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/caivega/chain3go/common"
+)
+
+var (
+	// ErrReorgTooDeep is returned when a chain reorganization replaces more
+	// blocks than the follower window holds. The follower can't go on, and has
+	// to be created again from a checkpoint.
+	ErrReorgTooDeep = errors.New("Reorganization deeper than the follower window")
+	// ErrBlockNotFound is returned when the node doesn't know a block the
+	// follower needs, like the block of a checkpoint
+	ErrBlockNotFound = errors.New("Block not found")
+)
+
+const (
+	followerWindowSize = 128
+	followerInterval   = time.Second
+)
+
+// BlockEventType is the kind of a BlockEvent
+type BlockEventType int
+
+const (
+	// BlockAdded is emitted when a block becomes part of the canonical chain,
+	// once it has enough confirmations
+	BlockAdded BlockEventType = iota
+	// BlockRemoved is emitted when a reorganization removes a block which was
+	// added before
+	BlockRemoved
+)
+
+// Checkpoint identifies the last block processed from a follower
+type Checkpoint struct {
+	Number uint64
+	Hash   common.Hash
+}
+
+// BlockEvent is a change of the canonical chain seen by a BlockFollower
+type BlockEvent struct {
+	Type  BlockEventType
+	Block *common.Block
+	// Checkpoint is the position of the follower once the event is processed,
+	// which is where a follower resumes from
+	Checkpoint Checkpoint
+}
+
+// FollowerOption configures a BlockFollower
+type FollowerOption func(*BlockFollower)
+
+// WithConfirmations sets how many blocks must be built on top of a block
+// before it is added. Reorganizations shallower than that don't emit events.
+func WithConfirmations(confirmations uint64) FollowerOption {
+	return func(f *BlockFollower) {
+		f.confirmations = confirmations
+	}
+}
+
+// WithWindowSize sets how many recent blocks are kept to follow
+// reorganizations, which bounds how deep they may be.
+func WithWindowSize(size int) FollowerOption {
+	return func(f *BlockFollower) {
+		if size > 0 {
+			f.windowSize = size
+		}
+	}
+}
+
+// WithFollowInterval sets how often Follow polls the node for new blocks
+func WithFollowInterval(interval time.Duration) FollowerOption {
+	return func(f *BlockFollower) {
+		if interval > 0 {
+			f.interval = interval
+		}
+	}
+}
+
+// WithCheckpoint resumes following after the block of a checkpoint, which
+// was processed before. Without it the follower starts at the block which
+// is confirmed when it first polls.
+func WithCheckpoint(checkpoint Checkpoint) FollowerOption {
+	return func(f *BlockFollower) {
+		f.checkpoint = &checkpoint
+	}
+}
+
+// BlockFollower follows the canonical chain, emitting every block exactly
+// once, and removing the blocks replaced by reorganizations. It isn't safe for
+// concurrent use.
+type BlockFollower struct {
+	mc            Mc
+	confirmations uint64
+	windowSize    int
+	interval      time.Duration
+	checkpoint    *Checkpoint
+
+	// window holds the recent blocks of the canonical chain in ascending
+	// order, of which the first emitted ones were added
+	window  []*common.Block
+	emitted int
+	events  []BlockEvent
+	// behind tells that the last poll stopped before the head of the chain
+	behind bool
+}
+
+// NewBlockFollower creates a block follower on the given API
+func NewBlockFollower(mc Mc, opts ...FollowerOption) *BlockFollower {
+	f := &BlockFollower{
+		mc:         mc,
+		windowSize: followerWindowSize,
+		interval:   followerInterval,
+	}
+	for _, opt := range opts {
+		opt(f)
+	}
+	return f
+}
+
+// Checkpoint returns the position of the follower after the events returned
+// so far, or nil before anything was added.
+func (f *BlockFollower) Checkpoint() *Checkpoint {
+	if f.emitted == 0 {
+		return f.checkpoint
+	}
+	block := f.window[f.emitted-1]
+	return &Checkpoint{Number: block.Number.Uint64(), Hash: block.Hash}
+}
+
+// Follow polls the node until the context is done, calling handle for every
+// event in order. It returns the first error of handle or of the node.
+func (f *BlockFollower) Follow(ctx context.Context, handle func(BlockEvent) error) error {
+	ticker := time.NewTicker(f.interval)
+	defer ticker.Stop()
+
+	for {
+		events, err := f.Poll(ctx)
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			return err
+		}
+		for _, event := range events {
+			if err := handle(event); err != nil {
+				return err
+			}
+		}
+		if f.behind {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// Poll catches up with the head of the chain once, returning the events since
+// the last poll. When far behind, it only moves a window of blocks closer to
+// the head at a time, so that memory stays bounded. On error the events are
+// kept for the next poll.
+func (f *BlockFollower) Poll(ctx context.Context) ([]BlockEvent, error) {
+	if err := f.sync(ctx); err != nil {
+		return nil, err
+	}
+
+	events := f.events
+	f.events = nil
+	return events, nil
+}
+
+func (f *BlockFollower) sync(ctx context.Context) error {
+	head, err := f.block(ctx, "latest")
+	if err != nil {
+		return err
+	}
+
+	if len(f.window) == 0 {
+		if err := f.start(ctx, head); err != nil {
+			return err
+		}
+	}
+
+	headNumber := head.Number.Uint64()
+	end := headNumber
+	f.behind = end > f.tip().Number.Uint64()+uint64(f.windowSize)
+	if f.behind {
+		end = f.tip().Number.Uint64() + uint64(f.windowSize)
+	}
+
+	for number := f.tip().Number.Uint64() + 1; number < end; number++ {
+		block, err := f.block(ctx, "0x"+strconv.FormatUint(number, 16))
+		if err == ErrBlockNotFound {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		if err := f.link(ctx, block); err != nil {
+			return err
+		}
+	}
+	last := head
+	if f.behind {
+		if last, err = f.block(ctx, "0x"+strconv.FormatUint(end, 16)); err != nil {
+			return err
+		}
+	}
+	if err := f.link(ctx, last); err != nil {
+		return err
+	}
+
+	f.confirm(headNumber)
+	f.trim()
+	return nil
+}
+
+// start fills the window with the block of the checkpoint, or with the block
+// confirmed at head
+func (f *BlockFollower) start(ctx context.Context, head *common.Block) error {
+	if f.checkpoint != nil {
+		block, err := f.blockByHash(ctx, f.checkpoint.Hash)
+		if err != nil {
+			return err
+		}
+		f.window = []*common.Block{block}
+		f.emitted = 1
+		return nil
+	}
+
+	number := head.Number.Uint64()
+	if number < f.confirmations {
+		number = 0
+	} else {
+		number -= f.confirmations
+	}
+	block, err := f.block(ctx, "0x"+strconv.FormatUint(number, 16))
+	if err != nil {
+		return err
+	}
+	f.window = []*common.Block{block}
+	return nil
+}
+
+// link appends block to the window, first replacing the blocks which aren't
+// its ancestors
+func (f *BlockFollower) link(ctx context.Context, block *common.Block) error {
+	number := block.Number.Uint64()
+	for len(f.window) > 0 && f.tip().Number.Uint64() > number {
+		if err := f.unlink(ctx); err != nil {
+			return err
+		}
+	}
+	if tip := f.tip(); tip != nil && tip.Number.Uint64() == number {
+		if tip.Hash == block.Hash {
+			return nil
+		}
+		if err := f.unlink(ctx); err != nil {
+			return err
+		}
+	}
+
+	chain := []*common.Block{block}
+	for depth := 0; f.tip() == nil || f.tip().Hash != chain[0].ParentHash; depth++ {
+		if depth >= f.windowSize || len(f.window) == 0 {
+			return ErrReorgTooDeep
+		}
+		if err := f.unlink(ctx); err != nil {
+			return err
+		}
+		parent, err := f.blockByHash(ctx, chain[0].ParentHash)
+		if err != nil {
+			return err
+		}
+		chain = append([]*common.Block{parent}, chain...)
+	}
+
+	f.window = append(f.window, chain...)
+	return nil
+}
+
+// unlink removes the tip of the window. When it is the last block, its
+// parent is loaded first, so that the window keeps an ancestor to link to.
+func (f *BlockFollower) unlink(ctx context.Context) error {
+	if len(f.window) == 1 {
+		if err := f.extend(ctx); err != nil {
+			return err
+		}
+	}
+	f.pop()
+	return nil
+}
+
+// extend prepends the parent of the first block of the window, which was
+// processed before when the first block was
+func (f *BlockFollower) extend(ctx context.Context) error {
+	first := f.window[0]
+	if first.Number.Sign() == 0 {
+		return nil
+	}
+	parent, err := f.blockByHash(ctx, first.ParentHash)
+	if err != nil {
+		return err
+	}
+
+	f.window = append([]*common.Block{parent}, f.window...)
+	if f.emitted > 0 {
+		f.emitted++
+	}
+	return nil
+}
+
+// pop removes the tip of the window, emitting its removal if it was added
+func (f *BlockFollower) pop() {
+	last := len(f.window) - 1
+	block := f.window[last]
+	f.window = f.window[:last]
+	if f.emitted <= last {
+		return
+	}
+
+	f.emitted--
+	f.events = append(f.events, BlockEvent{
+		Type:       BlockRemoved,
+		Block:      block,
+		Checkpoint: Checkpoint{Number: block.Number.Uint64() - 1, Hash: block.ParentHash},
+	})
+}
+
+// confirm emits the blocks of the window with enough confirmations below
+// the head of the chain
+func (f *BlockFollower) confirm(head uint64) {
+	for f.emitted < len(f.window) {
+		block := f.window[f.emitted]
+		if block.Number.Uint64()+f.confirmations > head {
+			return
+		}
+		f.emitted++
+		f.events = append(f.events, BlockEvent{
+			Type:       BlockAdded,
+			Block:      block,
+			Checkpoint: Checkpoint{Number: block.Number.Uint64(), Hash: block.Hash},
+		})
+	}
+}
+
+// trim drops the oldest emitted blocks which don't fit in the window
+func (f *BlockFollower) trim() {
+	for len(f.window) > f.windowSize && f.emitted > 1 {
+		f.window = f.window[1:]
+		f.emitted--
+	}
+}
+
+func (f *BlockFollower) tip() *common.Block {
+	if len(f.window) == 0 {
+		return nil
+	}
+	return f.window[len(f.window)-1]
+}
+
+// block returns a block by number, or ErrBlockNotFound if the node doesn't
+// have it yet
+func (f *BlockFollower) block(ctx context.Context, quantity string) (*common.Block, error) {
+	block, err := f.mc.GetBlockByNumberContext(ctx, quantity, false)
+	if err != nil {
+		return nil, err
+	}
+	if block.Hash == (common.Hash{}) {
+		return nil, ErrBlockNotFound
+	}
+	return block, nil
+}
+
+// blockByHash returns a block by hash, or ErrBlockNotFound if the node doesn't
+// know it
+func (f *BlockFollower) blockByHash(ctx context.Context, hash common.Hash) (*common.Block, error) {
+	block, err := f.mc.GetBlockByHashContext(ctx, hash, false)
+	if err != nil {
+		return nil, err
+	}
+	if block.Hash == (common.Hash{}) || block.Hash != hash {
+		return nil, ErrBlockNotFound
+	}
+	return block, nil
+}
@@ -0,0 +1,287 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package chain3
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeChain serves blocks of a chain whose canonical part can be rewritten
+type fakeChain struct {
+	mu        sync.Mutex
+	blocks    map[string]map[string]interface{}
+	canonical []string
+	forks     int
+}
+
+func newFakeChain(length int) *fakeChain {
+	c := &fakeChain{blocks: map[string]map[string]interface{}{}}
+	c.extend(length)
+	return c
+}
+
+// extend adds n blocks to the canonical chain
+func (c *fakeChain) extend(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i := 0; i < n; i++ {
+		number := len(c.canonical)
+		hash := fmt.Sprintf("0x%056x%08x", c.forks, number)
+		parent := fmt.Sprintf("0x%064x", 0)
+		if number > 0 {
+			parent = c.canonical[number-1]
+		}
+		c.blocks[hash] = map[string]interface{}{
+			"number":     "0x" + strconv.FormatInt(int64(number), 16),
+			"hash":       hash,
+			"parentHash": parent,
+		}
+		c.canonical = append(c.canonical, hash)
+	}
+}
+
+// reorg replaces the last depth blocks of the canonical chain by length new
+// ones
+func (c *fakeChain) reorg(depth, length int) {
+	c.mu.Lock()
+	c.canonical = c.canonical[:len(c.canonical)-depth]
+	c.forks++
+	c.mu.Unlock()
+	c.extend(length)
+}
+
+func (c *fakeChain) hash(number int) string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.canonical[number]
+}
+
+func (c *fakeChain) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	req := rpc.JSONRPCRequest{}
+	json.NewDecoder(r.Body).Decode(&req)
+	resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier}
+	switch req.Method {
+	case "mc_getBlockByHash":
+		if block, ok := c.blocks[req.Params[0].(string)]; ok {
+			resp.Result = block
+		}
+	case "mc_getBlockByNumber":
+		number := len(c.canonical) - 1
+		if quantity := req.Params[0].(string); quantity != "latest" {
+			n, _ := strconv.ParseInt(quantity[2:], 16, 64)
+			number = int(n)
+		}
+		if number < len(c.canonical) {
+			resp.Result = c.blocks[c.canonical[number]]
+		}
+	}
+	jsonBlob, _ := json.Marshal(resp)
+	w.Write(jsonBlob)
+}
+
+type FollowerTestSuite struct {
+	suite.Suite
+	chain  *fakeChain
+	server *httptest.Server
+	mc     Mc
+}
+
+// events describes events as "+number" or "-number" with the fork of the
+// block, like "+5/1"
+func (suite *FollowerTestSuite) events(events []BlockEvent) []string {
+	result := make([]string, len(events))
+	for i, event := range events {
+		sign := "+"
+		if event.Type == BlockRemoved {
+			sign = "-"
+		}
+		fork, _ := strconv.ParseInt(event.Block.Hash.String()[2:58], 16, 64)
+		result[i] = fmt.Sprintf("%s%d/%d", sign, event.Block.Number.Int64(), fork)
+	}
+	return result
+}
+
+func (suite *FollowerTestSuite) poll(follower *BlockFollower) []string {
+	events, err := follower.Poll(context.Background())
+	suite.Require().NoError(err)
+	return suite.events(events)
+}
+
+func (suite *FollowerTestSuite) Test_Follow() {
+	follower := NewBlockFollower(suite.mc)
+	assert.Nil(suite.T(), follower.Checkpoint(), "should be nil")
+	assert.Equal(suite.T(), []string{"+4/0"}, suite.poll(follower), "should be equal")
+	assert.Empty(suite.T(), suite.poll(follower), "should be empty")
+
+	suite.chain.extend(2)
+	assert.Equal(suite.T(), []string{"+5/0", "+6/0"}, suite.poll(follower), "should be equal")
+	checkpoint := follower.Checkpoint()
+	assert.EqualValues(suite.T(), 6, checkpoint.Number, "should be equal")
+	assert.EqualValues(suite.T(), suite.chain.hash(6), checkpoint.Hash.String(), "should be equal")
+}
+
+func (suite *FollowerTestSuite) Test_Reorg() {
+	follower := NewBlockFollower(suite.mc)
+	suite.poll(follower)
+
+	suite.chain.extend(2)
+	suite.chain.reorg(2, 3)
+	assert.Equal(suite.T(), []string{"+5/1", "+6/1", "+7/1"}, suite.poll(follower), "should be equal")
+
+	suite.chain.reorg(3, 4)
+	events, err := follower.Poll(context.Background())
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), []string{"-7/1", "-6/1", "-5/1", "+5/2", "+6/2", "+7/2", "+8/2"},
+		suite.events(events), "should be equal")
+	assert.EqualValues(suite.T(), 6, events[0].Checkpoint.Number, "should be the parent")
+	assert.EqualValues(suite.T(), 4, events[2].Checkpoint.Number, "should be the parent")
+
+	// a shorter chain replacing the tip
+	suite.chain.reorg(2, 1)
+	assert.Equal(suite.T(), []string{"-8/2", "-7/2", "+7/3"}, suite.poll(follower), "should be equal")
+}
+
+func (suite *FollowerTestSuite) Test_Confirmations() {
+	follower := NewBlockFollower(suite.mc, WithConfirmations(2))
+	assert.Equal(suite.T(), []string{"+2/0"}, suite.poll(follower), "should be equal")
+
+	suite.chain.extend(1)
+	assert.Equal(suite.T(), []string{"+3/0"}, suite.poll(follower), "should be equal")
+
+	// replacing unconfirmed blocks emits nothing
+	suite.chain.reorg(2, 2)
+	assert.Empty(suite.T(), suite.poll(follower), "should be empty")
+	suite.chain.extend(1)
+	assert.Equal(suite.T(), []string{"+4/1"}, suite.poll(follower), "should be equal")
+}
+
+func (suite *FollowerTestSuite) Test_Checkpoint() {
+	checkpoint := Checkpoint{Number: 2, Hash: common.StringToHash(suite.chain.hash(2))}
+	follower := NewBlockFollower(suite.mc, WithCheckpoint(checkpoint))
+	assert.Equal(suite.T(), checkpoint, *follower.Checkpoint(), "should be equal")
+	assert.Equal(suite.T(), []string{"+3/0", "+4/0"}, suite.poll(follower), "should be equal")
+
+	// the checkpoint block was replaced while the follower was stopped
+	checkpoint = *follower.Checkpoint()
+	suite.chain.reorg(2, 3)
+	follower = NewBlockFollower(suite.mc, WithCheckpoint(checkpoint))
+	assert.Equal(suite.T(), []string{"-4/0", "-3/0", "+3/1", "+4/1", "+5/1"}, suite.poll(follower), "should be equal")
+
+	follower = NewBlockFollower(suite.mc, WithCheckpoint(Checkpoint{Number: 1}))
+	_, err := follower.Poll(context.Background())
+	assert.Equal(suite.T(), ErrBlockNotFound, err, "should be equal")
+}
+
+func (suite *FollowerTestSuite) Test_ReorgTooDeep() {
+	follower := NewBlockFollower(suite.mc, WithWindowSize(2))
+	suite.chain.extend(3)
+	suite.poll(follower)
+	suite.chain.extend(3)
+	suite.poll(follower)
+
+	suite.chain.reorg(5, 5)
+	_, err := follower.Poll(context.Background())
+	assert.Equal(suite.T(), ErrReorgTooDeep, err, "should be equal")
+}
+
+func (suite *FollowerTestSuite) Test_CatchUp() {
+	checkpoint := Checkpoint{Number: 2, Hash: common.StringToHash(suite.chain.hash(2))}
+	suite.chain.extend(45)
+	follower := NewBlockFollower(suite.mc, WithCheckpoint(checkpoint), WithWindowSize(10), WithConfirmations(2))
+
+	// far behind, a poll only moves a window closer to the head
+	events, err := follower.Poll(context.Background())
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), events, 10, "should be equal")
+	assert.Equal(suite.T(), "+3/0", suite.events(events)[0], "should be equal")
+	assert.True(suite.T(), len(follower.window) <= 11, "should keep the window bounded")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	added := 10
+	err = follower.Follow(ctx, func(event BlockEvent) error {
+		added++
+		assert.EqualValues(suite.T(), added+2, event.Block.Number.Int64(), "should be in order")
+		if event.Block.Number.Int64() == 47 {
+			cancel()
+		}
+		return nil
+	})
+	assert.Equal(suite.T(), context.Canceled, err, "should be equal")
+	assert.Equal(suite.T(), 45, added, "should be equal")
+}
+
+func (suite *FollowerTestSuite) Test_FollowContext() {
+	follower := NewBlockFollower(suite.mc, WithFollowInterval(time.Millisecond))
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var added []string
+	err := follower.Follow(ctx, func(event BlockEvent) error {
+		added = append(added, suite.events([]BlockEvent{event})...)
+		if len(added) == 1 {
+			suite.chain.extend(1)
+		} else {
+			cancel()
+		}
+		return nil
+	})
+	assert.Equal(suite.T(), context.Canceled, err, "should be equal")
+	assert.Equal(suite.T(), []string{"+4/0", "+5/0"}, added, "should be equal")
+}
+
+func (suite *FollowerTestSuite) SetupTest() {
+	suite.chain = newFakeChain(5)
+	suite.server = httptest.NewServer(suite.chain)
+	suite.mc = NewChain3(provider.NewHTTPProvider(suite.server.URL, nil)).Mc
+}
+
+func (suite *FollowerTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func Test_FollowerTestSuite(t *testing.T) {
+	suite.Run(t, new(FollowerTestSuite))
+}
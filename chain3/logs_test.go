@@ -0,0 +1,169 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package chain3
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// logsRangeLimit is the widest block range the fake node accepts
+const logsRangeLimit = 4
+
+type LogsTestSuite struct {
+	suite.Suite
+	server  *httptest.Server
+	chain3  *Chain3
+	mu      sync.Mutex
+	queries []map[string]interface{}
+}
+
+func (suite *LogsTestSuite) Test_GetLogs() {
+	logs, err := suite.chain3.Mc.GetLogs(&FilterOption{
+		FromBlock: "0x1",
+		ToBlock:   "0x2",
+		Address:   common.StringToAddress("0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d"),
+		Topics:    []common.Data{nil, common.HexToBytes("0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5")},
+	})
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), logs, 2) {
+		assert.EqualValues(suite.T(), big.NewInt(1), logs[0].BlockNumber, "should be equal")
+		assert.EqualValues(suite.T(), "0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d", logs[0].Address.String(), "should be equal")
+	}
+
+	if assert.Len(suite.T(), suite.queries, 1) {
+		query := suite.queries[0]
+		assert.EqualValues(suite.T(), "0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d", query["address"], "should be equal")
+		assert.EqualValues(suite.T(), []interface{}{nil, "0x59ebeb90bc63057b6515673c3ecf9438e5058bca0f92585014eced636878c9a5"}, query["topics"], "should be equal")
+	}
+}
+
+func (suite *LogsTestSuite) Test_BlockHash() {
+	logs, err := suite.chain3.Mc.GetLogs(&FilterOption{BlockHash: "0x0000000000000000000000000000000000000000000000000000000000000007"})
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), logs, 1, "should be equal")
+	assert.EqualValues(suite.T(), "0x0000000000000000000000000000000000000000000000000000000000000007", suite.queries[0]["blockHash"], "should be equal")
+
+	_, err = suite.chain3.Mc.GetLogs(&FilterOption{BlockHash: "0x07", FromBlock: "0x1"})
+	assert.Equal(suite.T(), ErrBlockHashWithRange, err, "should be equal")
+}
+
+func (suite *LogsTestSuite) Test_SplitRange() {
+	logs, err := suite.chain3.Mc.GetLogs(&FilterOption{FromBlock: "earliest"})
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), logs, 21) {
+		for i, log := range logs {
+			assert.EqualValues(suite.T(), i, log.BlockNumber.Int64(), "should be in order")
+		}
+	}
+
+	// A single block over the limit cannot be split.
+	suite.queries = nil
+	_, err = suite.chain3.Mc.GetLogs(&FilterOption{FromBlock: "0x63", ToBlock: "0x63"})
+	assert.True(suite.T(), errors.Is(err, rpc.ErrLimitExceeded), "should be limited")
+	assert.Len(suite.T(), suite.queries, 1, "should be equal")
+}
+
+// blockParam parses a block parameter of the fake node, at block 0x14
+func blockParam(param interface{}) uint64 {
+	switch param {
+	case nil, "latest":
+		return 0x14
+	case "earliest":
+		return 0
+	}
+	number, _ := strconv.ParseUint(param.(string)[2:], 16, 64)
+	return number
+}
+
+func (suite *LogsTestSuite) SetupTest() {
+	suite.queries = nil
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := struct {
+			ID     uint64                   `json:"id"`
+			Method string                   `json:"method"`
+			Params []map[string]interface{} `json:"params"`
+		}{}
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.ID}
+
+		switch req.Method {
+		case "mc_blockNumber":
+			resp.Result = "0x14"
+		case "mc_getLogs":
+			query := req.Params[0]
+			suite.mu.Lock()
+			suite.queries = append(suite.queries, query)
+			suite.mu.Unlock()
+
+			if _, ok := query["blockHash"]; ok {
+				resp.Result = []map[string]interface{}{{"blockNumber": "0x7"}}
+				break
+			}
+			from, to := blockParam(query["fromBlock"]), blockParam(query["toBlock"])
+			if to-from >= logsRangeLimit || from == 0x63 {
+				resp.Err = &rpc.JSONRPCError{Code: -32005, Message: "query returned more than 10000 results"}
+				break
+			}
+			logs := []map[string]interface{}{}
+			for n := from; n <= to; n++ {
+				logs = append(logs, map[string]interface{}{
+					"blockNumber": fmt.Sprintf("0x%x", n),
+					"address":     "0x16c5785ac562ff41e2dcfdf829c5a142f1fccd7d",
+				})
+			}
+			resp.Result = logs
+		}
+		jsonBlob, _ := json.Marshal(resp)
+		w.Write(jsonBlob)
+	}))
+	suite.chain3 = NewChain3(provider.NewHTTPProvider(suite.server.URL, nil))
+}
+
+func (suite *LogsTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func Test_LogsTestSuite(t *testing.T) {
+	suite.Run(t, new(LogsTestSuite))
+}
@@ -30,7 +30,9 @@
 package chain3
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"math/big"
 	"strconv"
@@ -39,51 +41,110 @@ import (
 	"github.com/caivega/chain3go/rpc"
 )
 
+// ErrBlockHashWithRange is returned for a logs query giving both a block hash
+// and a block range
+var ErrBlockHashWithRange = errors.New("Block hash and block range are exclusive")
+
 // Mc ...
 type Mc interface {
 	ProtocolVersion() (string, error)
+	ProtocolVersionContext(ctx context.Context) (string, error)
 	Syncing() (common.SyncStatus, error)
+	SyncingContext(ctx context.Context) (common.SyncStatus, error)
 	Coinbase() (common.Address, error)
+	CoinbaseContext(ctx context.Context) (common.Address, error)
 	Mining() (bool, error)
+	MiningContext(ctx context.Context) (bool, error)
 	HashRate() (uint64, error)
+	HashRateContext(ctx context.Context) (uint64, error)
 	GasPrice() (*big.Int, error)
+	GasPriceContext(ctx context.Context) (*big.Int, error)
 	Accounts() ([]common.Address, error)
+	AccountsContext(ctx context.Context) ([]common.Address, error)
 	BlockNumber() (*big.Int, error)
+	BlockNumberContext(ctx context.Context) (*big.Int, error)
 	GetBalance(address common.Address, quantity string) (*big.Int, error)
+	GetBalanceContext(ctx context.Context, address common.Address, quantity string) (*big.Int, error)
 	GetStorageAt(address common.Address, position uint64, quantity string) (uint64, error)
+	GetStorageAtContext(ctx context.Context, address common.Address, position uint64, quantity string) (uint64, error)
 	GetTransactionCount(address common.Address, quantity string) (*big.Int, error)
+	GetTransactionCountContext(ctx context.Context, address common.Address, quantity string) (*big.Int, error)
 	GetBlockTransactionCountByHash(hash common.Hash) (*big.Int, error)
+	GetBlockTransactionCountByHashContext(ctx context.Context, hash common.Hash) (*big.Int, error)
 	GetBlockTransactionCountByNumber(quantity string) (*big.Int, error)
+	GetBlockTransactionCountByNumberContext(ctx context.Context, quantity string) (*big.Int, error)
 	GetUncleCountByBlockHash(hash common.Hash) (*big.Int, error)
+	GetUncleCountByBlockHashContext(ctx context.Context, hash common.Hash) (*big.Int, error)
 	GetUncleCountByBlockNumber(quantity string) (*big.Int, error)
+	GetUncleCountByBlockNumberContext(ctx context.Context, quantity string) (*big.Int, error)
 	GetCode(address common.Address, quantity string) ([]byte, error)
+	GetCodeContext(ctx context.Context, address common.Address, quantity string) ([]byte, error)
 	Sign(address common.Address, data []byte) ([]byte, error)
+	SignContext(ctx context.Context, address common.Address, data []byte) ([]byte, error)
 	SendTransaction(tx *common.TransactionRequest) (common.Hash, error)
+	SendTransactionContext(ctx context.Context, tx *common.TransactionRequest) (common.Hash, error)
 	SendRawTransaction(tx []byte) (common.Hash, error)
+	SendRawTransactionContext(ctx context.Context, tx []byte) (common.Hash, error)
 	Call(tx *common.TransactionRequest, quantity string) ([]byte, error)
+	CallContext(ctx context.Context, tx *common.TransactionRequest, quantity string) ([]byte, error)
 	EstimateGas(tx *common.TransactionRequest, quantity string) (*big.Int, error)
+	EstimateGasContext(ctx context.Context, tx *common.TransactionRequest, quantity string) (*big.Int, error)
 	GetBlockByHash(hash common.Hash, full bool) (*common.Block, error)
+	GetBlockByHashContext(ctx context.Context, hash common.Hash, full bool) (*common.Block, error)
 	GetBlockByNumber(quantity string, full bool) (*common.Block, error)
+	GetBlockByNumberContext(ctx context.Context, quantity string, full bool) (*common.Block, error)
 	GetTransactionByHash(hash common.Hash) (*common.Transaction, error)
+	GetTransactionByHashContext(ctx context.Context, hash common.Hash) (*common.Transaction, error)
 	GetTransactionByBlockHashAndIndex(hash common.Hash, index uint64) (*common.Transaction, error)
+	GetTransactionByBlockHashAndIndexContext(ctx context.Context, hash common.Hash, index uint64) (*common.Transaction, error)
 	GetTransactionByBlockNumberAndIndex(quantity string, index uint64) (*common.Transaction, error)
+	GetTransactionByBlockNumberAndIndexContext(ctx context.Context, quantity string, index uint64) (*common.Transaction, error)
 	GetTransactionReceipt(hash common.Hash) (*common.TransactionReceipt, error)
+	GetTransactionReceiptContext(ctx context.Context, hash common.Hash) (*common.TransactionReceipt, error)
 	GetUncleByBlockHashAndIndex(hash common.Hash, index uint64) (*common.Block, error)
+	GetUncleByBlockHashAndIndexContext(ctx context.Context, hash common.Hash, index uint64) (*common.Block, error)
 	GetUncleByBlockNumberAndIndex(quantity string, index uint64) (*common.Block, error)
+	GetUncleByBlockNumberAndIndexContext(ctx context.Context, quantity string, index uint64) (*common.Block, error)
 	GetCompilers() ([]string, error)
+	GetCompilersContext(ctx context.Context) ([]string, error)
 	// GompileLLL
 	// CompileSolidity
 	// CompileSerpent
 	NewFilter(option *FilterOption) (Filter, error)
+	NewFilterContext(ctx context.Context, option *FilterOption) (Filter, error)
 	NewBlockFilter() (Filter, error)
+	NewBlockFilterContext(ctx context.Context) (Filter, error)
 	NewPendingTransactionFilter() (Filter, error)
+	NewPendingTransactionFilterContext(ctx context.Context) (Filter, error)
 	UninstallFilter(filter Filter) (bool, error)
-	GetFilterChanges(filter Filter) ([]interface{}, error)
-	GetFilterLogs(filter Filter) ([]interface{}, error)
-	GetLogs(filter Filter) ([]interface{}, error)
+	UninstallFilterContext(ctx context.Context, filter Filter) (bool, error)
+	GetFilterChanges(filter Filter) (*FilterChanges, error)
+	GetFilterChangesContext(ctx context.Context, filter Filter) (*FilterChanges, error)
+	GetFilterLogs(filter Filter) ([]common.Log, error)
+	GetFilterLogsContext(ctx context.Context, filter Filter) ([]common.Log, error)
+	GetLogs(option *FilterOption) ([]common.Log, error)
+	GetLogsContext(ctx context.Context, option *FilterOption) ([]common.Log, error)
 	GetWork() (common.Hash, common.Hash, common.Hash, error)
+	GetWorkContext(ctx context.Context) (common.Hash, common.Hash, common.Hash, error)
 	SubmitWork(nonce uint64, header common.Hash, mixDigest common.Hash) (bool, error)
+	SubmitWorkContext(ctx context.Context, nonce uint64, header common.Hash, mixDigest common.Hash) (bool, error)
 	// SubmitHashrate
+	SubscribeNewHeads() (<-chan *common.Block, Subscription, error)
+	SubscribeNewHeadsContext(ctx context.Context) (<-chan *common.Block, Subscription, error)
+	SubscribeLogs(option *FilterOption) (<-chan common.Log, Subscription, error)
+	SubscribeLogsContext(ctx context.Context, option *FilterOption) (<-chan common.Log, Subscription, error)
+	SubscribePendingTransactions() (<-chan common.Hash, Subscription, error)
+	SubscribePendingTransactionsContext(ctx context.Context) (<-chan common.Hash, Subscription, error)
+	SubscribeSyncing() (<-chan common.SyncStatus, Subscription, error)
+	SubscribeSyncingContext(ctx context.Context) (<-chan common.SyncStatus, Subscription, error)
+	GetBlocksByHash(hashes []common.Hash, full bool) ([]*common.Block, error)
+	GetBlocksByHashContext(ctx context.Context, hashes []common.Hash, full bool) ([]*common.Block, error)
+	GetBlocksByNumber(quantities []string, full bool) ([]*common.Block, error)
+	GetBlocksByNumberContext(ctx context.Context, quantities []string, full bool) ([]*common.Block, error)
+	GetTransactionsByHash(hashes []common.Hash) ([]*common.Transaction, error)
+	GetTransactionsByHashContext(ctx context.Context, hashes []common.Hash) ([]*common.Transaction, error)
+	GetTransactionReceipts(hashes []common.Hash) ([]*common.TransactionReceipt, error)
+	GetTransactionReceiptsContext(ctx context.Context, hashes []common.Hash) ([]*common.TransactionReceipt, error)
 }
 
 // MoacAPI ...
@@ -99,8 +160,13 @@ func newMoacAPI(requestManager *RequestManager) Mc {
 
 // ProtocolVersion returns the current ethereum protocol version.
 func (mc *MoacAPI) ProtocolVersion() (string, error) {
+	return mc.ProtocolVersionContext(context.Background())
+}
+
+// ProtocolVersionContext is like ProtocolVersion but with a context.
+func (mc *MoacAPI) ProtocolVersionContext(ctx context.Context) (string, error) {
 	req := mc.requestManager.NewRequest("mc_protocolVersion")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return "", err
 	}
@@ -115,8 +181,13 @@ func (mc *MoacAPI) ProtocolVersion() (string, error) {
 // Syncing returns true with an object with data about the sync status or false
 // with nil.
 func (mc *MoacAPI) Syncing() (common.SyncStatus, error) {
+	return mc.SyncingContext(context.Background())
+}
+
+// SyncingContext is like Syncing but with a context.
+func (mc *MoacAPI) SyncingContext(ctx context.Context) (common.SyncStatus, error) {
 	req := mc.requestManager.NewRequest("mc_syncing")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return common.SyncStatus{
 			Result: false,
@@ -155,8 +226,13 @@ func (mc *MoacAPI) Syncing() (common.SyncStatus, error) {
 
 // Coinbase returns the client coinbase address.
 func (mc *MoacAPI) Coinbase() (addr common.Address, err error) {
+	return mc.CoinbaseContext(context.Background())
+}
+
+// CoinbaseContext is like Coinbase but with a context.
+func (mc *MoacAPI) CoinbaseContext(ctx context.Context) (addr common.Address, err error) {
 	req := mc.requestManager.NewRequest("mc_coinbase")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return common.NewAddress(nil), err
 	}
@@ -170,8 +246,13 @@ func (mc *MoacAPI) Coinbase() (addr common.Address, err error) {
 
 // Mining returns true if client is actively mining new blocks.
 func (mc *MoacAPI) Mining() (bool, error) {
+	return mc.MiningContext(context.Background())
+}
+
+// MiningContext is like Mining but with a context.
+func (mc *MoacAPI) MiningContext(ctx context.Context) (bool, error) {
 	req := mc.requestManager.NewRequest("mc_mining")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return false, err
 	}
@@ -186,8 +267,13 @@ func (mc *MoacAPI) Mining() (bool, error) {
 // HashRate returns the number of hashes per second that the node is mining
 // with.
 func (mc *MoacAPI) HashRate() (uint64, error) {
+	return mc.HashRateContext(context.Background())
+}
+
+// HashRateContext is like HashRate but with a context.
+func (mc *MoacAPI) HashRateContext(ctx context.Context) (uint64, error) {
 	req := mc.requestManager.NewRequest("mc_hashrate")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return 0, err
 	}
@@ -205,8 +291,13 @@ func (mc *MoacAPI) HashRate() (uint64, error) {
 
 // GasPrice returns the current price per gas in wei.
 func (mc *MoacAPI) GasPrice() (result *big.Int, err error) {
+	return mc.GasPriceContext(context.Background())
+}
+
+// GasPriceContext is like GasPrice but with a context.
+func (mc *MoacAPI) GasPriceContext(ctx context.Context) (result *big.Int, err error) {
 	req := mc.requestManager.NewRequest("mc_gasPrice")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -225,8 +316,13 @@ func (mc *MoacAPI) GasPrice() (result *big.Int, err error) {
 
 // Accounts returns a list of addresses owned by client.
 func (mc *MoacAPI) Accounts() (addrs []common.Address, err error) {
+	return mc.AccountsContext(context.Background())
+}
+
+// AccountsContext is like Accounts but with a context.
+func (mc *MoacAPI) AccountsContext(ctx context.Context) (addrs []common.Address, err error) {
 	req := mc.requestManager.NewRequest("mc_accounts")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -244,8 +340,13 @@ func (mc *MoacAPI) Accounts() (addrs []common.Address, err error) {
 
 // BlockNumber returns the number of most recent block.
 func (mc *MoacAPI) BlockNumber() (result *big.Int, err error) {
+	return mc.BlockNumberContext(context.Background())
+}
+
+// BlockNumberContext is like BlockNumber but with a context.
+func (mc *MoacAPI) BlockNumberContext(ctx context.Context) (result *big.Int, err error) {
 	req := mc.requestManager.NewRequest("mc_blockNumber")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -264,9 +365,14 @@ func (mc *MoacAPI) BlockNumber() (result *big.Int, err error) {
 
 // GetBalance returns the balance of the account of given address.
 func (mc *MoacAPI) GetBalance(address common.Address, quantity string) (result *big.Int, err error) {
+	return mc.GetBalanceContext(context.Background(), address, quantity)
+}
+
+// GetBalanceContext is like GetBalance but with a context.
+func (mc *MoacAPI) GetBalanceContext(ctx context.Context, address common.Address, quantity string) (result *big.Int, err error) {
 	req := mc.requestManager.NewRequest("mc_getBalance")
 	req.Set("params", []string{address.String(), quantity})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -285,9 +391,14 @@ func (mc *MoacAPI) GetBalance(address common.Address, quantity string) (result *
 
 // GetStorageAt returns the value from a storage position at a given address.
 func (mc *MoacAPI) GetStorageAt(address common.Address, position uint64, quantity string) (uint64, error) {
+	return mc.GetStorageAtContext(context.Background(), address, position, quantity)
+}
+
+// GetStorageAtContext is like GetStorageAt but with a context.
+func (mc *MoacAPI) GetStorageAtContext(ctx context.Context, address common.Address, position uint64, quantity string) (uint64, error) {
 	req := mc.requestManager.NewRequest("mc_getStorageAt")
 	req.Set("params", []string{address.String(), fmt.Sprintf("%v", position), quantity})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return 0, err
 	}
@@ -305,9 +416,14 @@ func (mc *MoacAPI) GetStorageAt(address common.Address, position uint64, quantit
 
 // GetTransactionCount returns the number of transactions sent from an address.
 func (mc *MoacAPI) GetTransactionCount(address common.Address, quantity string) (result *big.Int, err error) {
+	return mc.GetTransactionCountContext(context.Background(), address, quantity)
+}
+
+// GetTransactionCountContext is like GetTransactionCount but with a context.
+func (mc *MoacAPI) GetTransactionCountContext(ctx context.Context, address common.Address, quantity string) (result *big.Int, err error) {
 	req := mc.requestManager.NewRequest("mc_getTransactionCount")
 	req.Set("params", []string{address.String(), quantity})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -327,9 +443,14 @@ func (mc *MoacAPI) GetTransactionCount(address common.Address, quantity string)
 // GetBlockTransactionCountByHash returns the number of transactions in a block
 // from a block matching the given block hash.
 func (mc *MoacAPI) GetBlockTransactionCountByHash(hash common.Hash) (result *big.Int, err error) {
+	return mc.GetBlockTransactionCountByHashContext(context.Background(), hash)
+}
+
+// GetBlockTransactionCountByHashContext is like GetBlockTransactionCountByHash but with a context.
+func (mc *MoacAPI) GetBlockTransactionCountByHashContext(ctx context.Context, hash common.Hash) (result *big.Int, err error) {
 	req := mc.requestManager.NewRequest("mc_getBlockTransactionCountByHash")
 	req.Set("params", hash.String())
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -349,9 +470,14 @@ func (mc *MoacAPI) GetBlockTransactionCountByHash(hash common.Hash) (result *big
 // GetBlockTransactionCountByNumber returns the number of transactions in a
 // block from a block matching the given block number.
 func (mc *MoacAPI) GetBlockTransactionCountByNumber(quantity string) (result *big.Int, err error) {
+	return mc.GetBlockTransactionCountByNumberContext(context.Background(), quantity)
+}
+
+// GetBlockTransactionCountByNumberContext is like GetBlockTransactionCountByNumber but with a context.
+func (mc *MoacAPI) GetBlockTransactionCountByNumberContext(ctx context.Context, quantity string) (result *big.Int, err error) {
 	req := mc.requestManager.NewRequest("mc_getBlockTransactionCountByNumber")
 	req.Set("params", quantity)
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -371,9 +497,14 @@ func (mc *MoacAPI) GetBlockTransactionCountByNumber(quantity string) (result *bi
 // GetUncleCountByBlockHash returns the number of uncles in a block from a block
 // matching the given block hash.
 func (mc *MoacAPI) GetUncleCountByBlockHash(hash common.Hash) (result *big.Int, err error) {
+	return mc.GetUncleCountByBlockHashContext(context.Background(), hash)
+}
+
+// GetUncleCountByBlockHashContext is like GetUncleCountByBlockHash but with a context.
+func (mc *MoacAPI) GetUncleCountByBlockHashContext(ctx context.Context, hash common.Hash) (result *big.Int, err error) {
 	req := mc.requestManager.NewRequest("mc_getUncleCountByBlockHash")
 	req.Set("params", hash.String())
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -393,9 +524,14 @@ func (mc *MoacAPI) GetUncleCountByBlockHash(hash common.Hash) (result *big.Int,
 // GetUncleCountByBlockNumber returns the number of uncles in a block from a
 // block matching the given block number.
 func (mc *MoacAPI) GetUncleCountByBlockNumber(quantity string) (result *big.Int, err error) {
+	return mc.GetUncleCountByBlockNumberContext(context.Background(), quantity)
+}
+
+// GetUncleCountByBlockNumberContext is like GetUncleCountByBlockNumber but with a context.
+func (mc *MoacAPI) GetUncleCountByBlockNumberContext(ctx context.Context, quantity string) (result *big.Int, err error) {
 	req := mc.requestManager.NewRequest("mc_getUncleCountByBlockNumber")
 	req.Set("params", quantity)
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -414,9 +550,14 @@ func (mc *MoacAPI) GetUncleCountByBlockNumber(quantity string) (result *big.Int,
 
 // GetCode returns code at a given address.
 func (mc *MoacAPI) GetCode(address common.Address, quantity string) ([]byte, error) {
+	return mc.GetCodeContext(context.Background(), address, quantity)
+}
+
+// GetCodeContext is like GetCode but with a context.
+func (mc *MoacAPI) GetCodeContext(ctx context.Context, address common.Address, quantity string) ([]byte, error) {
 	req := mc.requestManager.NewRequest("mc_getCode")
 	req.Set("params", []string{address.String(), quantity})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -430,9 +571,14 @@ func (mc *MoacAPI) GetCode(address common.Address, quantity string) ([]byte, err
 
 // Sign signs data with a given address.
 func (mc *MoacAPI) Sign(address common.Address, data []byte) ([]byte, error) {
+	return mc.SignContext(context.Background(), address, data)
+}
+
+// SignContext is like Sign but with a context.
+func (mc *MoacAPI) SignContext(ctx context.Context, address common.Address, data []byte) ([]byte, error) {
 	req := mc.requestManager.NewRequest("mc_sign")
 	req.Set("params", []string{address.String(), string(data)})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -447,9 +593,14 @@ func (mc *MoacAPI) Sign(address common.Address, data []byte) ([]byte, error) {
 // SendTransaction creates new message call transaction or a contract creation,
 // if the data field contains code.
 func (mc *MoacAPI) SendTransaction(tx *common.TransactionRequest) (hash common.Hash, err error) {
+	return mc.SendTransactionContext(context.Background(), tx)
+}
+
+// SendTransactionContext is like SendTransaction but with a context.
+func (mc *MoacAPI) SendTransactionContext(ctx context.Context, tx *common.TransactionRequest) (hash common.Hash, err error) {
 	req := mc.requestManager.NewRequest("mc_sendTransaction")
 	req.Set("params", tx.ToMap())
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return common.NewHash(nil), err
 	}
@@ -464,9 +615,14 @@ func (mc *MoacAPI) SendTransaction(tx *common.TransactionRequest) (hash common.H
 // SendRawTransaction creates new message call transaction or a contract
 // creation for signed transactions.
 func (mc *MoacAPI) SendRawTransaction(tx []byte) (hash common.Hash, err error) {
+	return mc.SendRawTransactionContext(context.Background(), tx)
+}
+
+// SendRawTransactionContext is like SendRawTransaction but with a context.
+func (mc *MoacAPI) SendRawTransactionContext(ctx context.Context, tx []byte) (hash common.Hash, err error) {
 	req := mc.requestManager.NewRequest("mc_sendRawTransaction")
 	req.Set("params", []string{common.BytesToHex(tx)})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return common.NewHash(nil), err
 	}
@@ -481,9 +637,14 @@ func (mc *MoacAPI) SendRawTransaction(tx []byte) (hash common.Hash, err error) {
 // Call executes a new message call immediately without creating a transaction
 // on the block chain.
 func (mc *MoacAPI) Call(tx *common.TransactionRequest, quantity string) ([]byte, error) {
+	return mc.CallContext(context.Background(), tx, quantity)
+}
+
+// CallContext is like Call but with a context.
+func (mc *MoacAPI) CallContext(ctx context.Context, tx *common.TransactionRequest, quantity string) ([]byte, error) {
 	req := mc.requestManager.NewRequest("mc_call")
-	req.Set("params", []string{tx.String(), quantity})
-	resp, err := mc.requestManager.Send(req)
+	req.Set("params", []interface{}{tx, quantity})
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -499,9 +660,14 @@ func (mc *MoacAPI) Call(tx *common.TransactionRequest, quantity string) ([]byte,
 // blockchain and returns the used gas, which can be used for estimating the
 // used gas.
 func (mc *MoacAPI) EstimateGas(tx *common.TransactionRequest, quantity string) (result *big.Int, err error) {
+	return mc.EstimateGasContext(context.Background(), tx, quantity)
+}
+
+// EstimateGasContext is like EstimateGas but with a context.
+func (mc *MoacAPI) EstimateGasContext(ctx context.Context, tx *common.TransactionRequest, quantity string) (result *big.Int, err error) {
 	req := mc.requestManager.NewRequest("mc_estimateGas")
-	req.Set("params", []string{tx.String(), quantity})
-	resp, err := mc.requestManager.Send(req)
+	req.Set("params", []interface{}{tx, quantity})
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -520,9 +686,14 @@ func (mc *MoacAPI) EstimateGas(tx *common.TransactionRequest, quantity string) (
 
 // GetBlockByHash returns information about a block by hash.
 func (mc *MoacAPI) GetBlockByHash(hash common.Hash, full bool) (*common.Block, error) {
+	return mc.GetBlockByHashContext(context.Background(), hash, full)
+}
+
+// GetBlockByHashContext is like GetBlockByHash but with a context.
+func (mc *MoacAPI) GetBlockByHashContext(ctx context.Context, hash common.Hash, full bool) (*common.Block, error) {
 	req := mc.requestManager.NewRequest("mc_getBlockByHash")
 	req.Set("params", []interface{}{hash.String(), full})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -543,9 +714,14 @@ func (mc *MoacAPI) GetBlockByHash(hash common.Hash, full bool) (*common.Block, e
 
 // GetBlockByNumber returns information about a block by block number.
 func (mc *MoacAPI) GetBlockByNumber(quantity string, full bool) (*common.Block, error) {
+	return mc.GetBlockByNumberContext(context.Background(), quantity, full)
+}
+
+// GetBlockByNumberContext is like GetBlockByNumber but with a context.
+func (mc *MoacAPI) GetBlockByNumberContext(ctx context.Context, quantity string, full bool) (*common.Block, error) {
 	req := mc.requestManager.NewRequest("mc_getBlockByNumber")
 	req.Set("params", []interface{}{quantity, full})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -567,9 +743,14 @@ func (mc *MoacAPI) GetBlockByNumber(quantity string, full bool) (*common.Block,
 // GetTransactionByHash returns the information about a transaction requested by
 // transaction hash.
 func (mc *MoacAPI) GetTransactionByHash(hash common.Hash) (*common.Transaction, error) {
+	return mc.GetTransactionByHashContext(context.Background(), hash)
+}
+
+// GetTransactionByHashContext is like GetTransactionByHash but with a context.
+func (mc *MoacAPI) GetTransactionByHashContext(ctx context.Context, hash common.Hash) (*common.Transaction, error) {
 	req := mc.requestManager.NewRequest("mc_getTransactionByHash")
 	req.Set("params", hash.String())
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -591,9 +772,14 @@ func (mc *MoacAPI) GetTransactionByHash(hash common.Hash) (*common.Transaction,
 // GetTransactionByBlockHashAndIndex returns information about a transaction by
 // block hash and transaction index position.
 func (mc *MoacAPI) GetTransactionByBlockHashAndIndex(hash common.Hash, index uint64) (*common.Transaction, error) {
+	return mc.GetTransactionByBlockHashAndIndexContext(context.Background(), hash, index)
+}
+
+// GetTransactionByBlockHashAndIndexContext is like GetTransactionByBlockHashAndIndex but with a context.
+func (mc *MoacAPI) GetTransactionByBlockHashAndIndexContext(ctx context.Context, hash common.Hash, index uint64) (*common.Transaction, error) {
 	req := mc.requestManager.NewRequest("mc_getTransactionByBlockHashAndIndex")
 	req.Set("params", []string{hash.String(), fmt.Sprintf("%v", index)})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -615,9 +801,14 @@ func (mc *MoacAPI) GetTransactionByBlockHashAndIndex(hash common.Hash, index uin
 // GetTransactionByBlockNumberAndIndex returns information about a transaction
 // by block number and transaction index position.
 func (mc *MoacAPI) GetTransactionByBlockNumberAndIndex(quantity string, index uint64) (*common.Transaction, error) {
+	return mc.GetTransactionByBlockNumberAndIndexContext(context.Background(), quantity, index)
+}
+
+// GetTransactionByBlockNumberAndIndexContext is like GetTransactionByBlockNumberAndIndex but with a context.
+func (mc *MoacAPI) GetTransactionByBlockNumberAndIndexContext(ctx context.Context, quantity string, index uint64) (*common.Transaction, error) {
 	req := mc.requestManager.NewRequest("mc_getTransactionByBlockNumberAndIndex")
 	req.Set("params", []string{quantity, fmt.Sprintf("%v", index)})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -638,9 +829,14 @@ func (mc *MoacAPI) GetTransactionByBlockNumberAndIndex(quantity string, index ui
 
 // GetTransactionReceipt Returns the receipt of a transaction by transaction hash.
 func (mc *MoacAPI) GetTransactionReceipt(hash common.Hash) (*common.TransactionReceipt, error) {
+	return mc.GetTransactionReceiptContext(context.Background(), hash)
+}
+
+// GetTransactionReceiptContext is like GetTransactionReceipt but with a context.
+func (mc *MoacAPI) GetTransactionReceiptContext(ctx context.Context, hash common.Hash) (*common.TransactionReceipt, error) {
 	req := mc.requestManager.NewRequest("mc_getTransactionReceipt")
 	req.Set("params", hash.String())
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -662,9 +858,14 @@ func (mc *MoacAPI) GetTransactionReceipt(hash common.Hash) (*common.TransactionR
 // GetUncleByBlockHashAndIndex returns information about a uncle of a block by
 // hash and uncle index position.
 func (mc *MoacAPI) GetUncleByBlockHashAndIndex(hash common.Hash, index uint64) (*common.Block, error) {
+	return mc.GetUncleByBlockHashAndIndexContext(context.Background(), hash, index)
+}
+
+// GetUncleByBlockHashAndIndexContext is like GetUncleByBlockHashAndIndex but with a context.
+func (mc *MoacAPI) GetUncleByBlockHashAndIndexContext(ctx context.Context, hash common.Hash, index uint64) (*common.Block, error) {
 	req := mc.requestManager.NewRequest("mc_getUncleByBlockHashAndIndex")
 	req.Set("params", []string{hash.String(), fmt.Sprintf("%d", index)})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -686,9 +887,14 @@ func (mc *MoacAPI) GetUncleByBlockHashAndIndex(hash common.Hash, index uint64) (
 // GetUncleByBlockNumberAndIndex returns information about a uncle of a block by
 // number and uncle index position.
 func (mc *MoacAPI) GetUncleByBlockNumberAndIndex(quantity string, index uint64) (*common.Block, error) {
+	return mc.GetUncleByBlockNumberAndIndexContext(context.Background(), quantity, index)
+}
+
+// GetUncleByBlockNumberAndIndexContext is like GetUncleByBlockNumberAndIndex but with a context.
+func (mc *MoacAPI) GetUncleByBlockNumberAndIndexContext(ctx context.Context, quantity string, index uint64) (*common.Block, error) {
 	req := mc.requestManager.NewRequest("mc_getUncleByBlockNumberAndIndex")
 	req.Set("params", []string{quantity, fmt.Sprintf("%d", index)})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -709,8 +915,13 @@ func (mc *MoacAPI) GetUncleByBlockNumberAndIndex(quantity string, index uint64)
 
 // GetCompilers returns a list of available compilers in the client.
 func (mc *MoacAPI) GetCompilers() (result []string, err error) {
+	return mc.GetCompilersContext(context.Background())
+}
+
+// GetCompilersContext is like GetCompilers but with a context.
+func (mc *MoacAPI) GetCompilersContext(ctx context.Context) (result []string, err error) {
 	req := mc.requestManager.NewRequest("mc_getCompilers")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -729,12 +940,17 @@ func (mc *MoacAPI) GetCompilers() (result []string, err error) {
 // the state changes (logs). To check if the state has changed, call
 // mc_getFilterChanges.
 func (mc *MoacAPI) NewFilter(option *FilterOption) (Filter, error) {
+	return mc.NewFilterContext(context.Background(), option)
+}
+
+// NewFilterContext is like NewFilter but with a context.
+func (mc *MoacAPI) NewFilterContext(ctx context.Context, option *FilterOption) (Filter, error) {
 	req := mc.requestManager.NewRequest("mc_newFilter")
 	if option == nil {
 		option = &FilterOption{}
 	}
 	req.Set("params", option)
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -744,14 +960,19 @@ func (mc *MoacAPI) NewFilter(option *FilterOption) (Filter, error) {
 	}
 
 	id := resp.Get("result").(string)
-	return newFilter(mc, TypeNormal, id), nil
+	return newFilter(mc, TypeNormal, option, id), nil
 }
 
 // NewBlockFilter creates a filter in the node, to notify when a new block
 // arrives. To check if the state has changed, call mc_getFilterChanges.
 func (mc *MoacAPI) NewBlockFilter() (Filter, error) {
+	return mc.NewBlockFilterContext(context.Background())
+}
+
+// NewBlockFilterContext is like NewBlockFilter but with a context.
+func (mc *MoacAPI) NewBlockFilterContext(ctx context.Context) (Filter, error) {
 	req := mc.requestManager.NewRequest("mc_newBlockFilter")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -761,15 +982,20 @@ func (mc *MoacAPI) NewBlockFilter() (Filter, error) {
 	}
 
 	id := resp.Get("result").(string)
-	return newFilter(mc, TypeBlockFilter, id), nil
+	return newFilter(mc, TypeBlockFilter, nil, id), nil
 }
 
 // NewPendingTransactionFilter creates a filter in the node, to notify when new
 // pending transactions arrive. To check if the state has changed, call
 // mc_getFilterChanges.
 func (mc *MoacAPI) NewPendingTransactionFilter() (Filter, error) {
+	return mc.NewPendingTransactionFilterContext(context.Background())
+}
+
+// NewPendingTransactionFilterContext is like NewPendingTransactionFilter but with a context.
+func (mc *MoacAPI) NewPendingTransactionFilterContext(ctx context.Context) (Filter, error) {
 	req := mc.requestManager.NewRequest("mc_newPendingTransactionFilter")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -779,16 +1005,21 @@ func (mc *MoacAPI) NewPendingTransactionFilter() (Filter, error) {
 	}
 
 	id := resp.Get("result").(string)
-	return newFilter(mc, TypeTransactionFilter, id), nil
+	return newFilter(mc, TypeTransactionFilter, nil, id), nil
 }
 
 // UninstallFilter uninstalls a filter with given id. Should always be called
 // when watch is no longer needed. Additonally Filters timeout when they aren't
 // requested with mc_getFilterChanges for a period of time.
 func (mc *MoacAPI) UninstallFilter(filter Filter) (bool, error) {
+	return mc.UninstallFilterContext(context.Background(), filter)
+}
+
+// UninstallFilterContext is like UninstallFilter but with a context.
+func (mc *MoacAPI) UninstallFilterContext(ctx context.Context, filter Filter) (bool, error) {
 	req := mc.requestManager.NewRequest("mc_uninstallFilter")
 	req.Set("params", filter.ID())
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return false, err
 	}
@@ -800,12 +1031,18 @@ func (mc *MoacAPI) UninstallFilter(filter Filter) (bool, error) {
 	return resp.Get("result").(bool), nil
 }
 
-// GetFilterChanges polling mmcod for a filter, which returns an array of logs
-// which occurred since last poll.
-func (mc *MoacAPI) GetFilterChanges(filter Filter) (result []interface{}, err error) {
+// GetFilterChanges polling method for a filter, which returns the hashes of
+// the new blocks or pending transactions, or the new logs, depending on the
+// type of the filter, which occurred since last poll.
+func (mc *MoacAPI) GetFilterChanges(filter Filter) (*FilterChanges, error) {
+	return mc.GetFilterChangesContext(context.Background(), filter)
+}
+
+// GetFilterChangesContext is like GetFilterChanges but with a context.
+func (mc *MoacAPI) GetFilterChangesContext(ctx context.Context, filter Filter) (*FilterChanges, error) {
 	req := mc.requestManager.NewRequest("mc_getFilterChanges")
 	req.Set("params", filter.ID())
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -814,14 +1051,32 @@ func (mc *MoacAPI) GetFilterChanges(filter Filter) (result []interface{}, err er
 		return nil, resp.Error()
 	}
 
-	return resp.Get("result").([]interface{}), nil
+	changes := &FilterChanges{}
+	if filter.Type() != TypeNormal {
+		changes.Hashes, err = decodeHashes(resp.Get("result"))
+	} else {
+		changes.Logs, err = decodeLogs(resp.Get("result"))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return changes, nil
 }
 
 // GetFilterLogs returns an array of all logs matching filter with given id.
-func (mc *MoacAPI) GetFilterLogs(filter Filter) (result []interface{}, err error) {
+func (mc *MoacAPI) GetFilterLogs(filter Filter) ([]common.Log, error) {
+	return mc.GetFilterLogsContext(context.Background(), filter)
+}
+
+// GetFilterLogsContext is like GetFilterLogs but with a context.
+func (mc *MoacAPI) GetFilterLogsContext(ctx context.Context, filter Filter) ([]common.Log, error) {
+	if filter.Type() != TypeNormal {
+		return nil, ErrFilterType
+	}
+
 	req := mc.requestManager.NewRequest("mc_getFilterLogs")
 	req.Set("params", filter.ID())
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -830,14 +1085,68 @@ func (mc *MoacAPI) GetFilterLogs(filter Filter) (result []interface{}, err error
 		return nil, resp.Error()
 	}
 
-	return resp.Get("result").([]interface{}), nil
+	return decodeLogs(resp.Get("result"))
+}
+
+// GetLogs returns all logs matching a given filter object. When the node
+// rejects the query for covering too many blocks or returning too many logs,
+// the block range is split until the node accepts the parts.
+func (mc *MoacAPI) GetLogs(option *FilterOption) ([]common.Log, error) {
+	return mc.GetLogsContext(context.Background(), option)
+}
+
+// GetLogsContext is like GetLogs but with a context.
+func (mc *MoacAPI) GetLogsContext(ctx context.Context, option *FilterOption) ([]common.Log, error) {
+	if option == nil {
+		option = &FilterOption{}
+	}
+	if option.BlockHash != "" && (option.FromBlock != "" || option.ToBlock != "") {
+		return nil, ErrBlockHashWithRange
+	}
+
+	logs, err := mc.getLogs(ctx, option)
+	if err == nil || option.BlockHash != "" || !errors.Is(err, rpc.ErrLimitExceeded) {
+		return logs, err
+	}
+
+	from, fromErr := mc.resolveBlock(ctx, option.FromBlock)
+	to, toErr := mc.resolveBlock(ctx, option.ToBlock)
+	if fromErr != nil || toErr != nil || from >= to {
+		return nil, err
+	}
+	return mc.splitLogs(ctx, *option, from, to)
+}
+
+// getLogsRange queries the logs between blocks from and to, splitting the
+// range further if needed.
+func (mc *MoacAPI) getLogsRange(ctx context.Context, option FilterOption, from, to uint64) ([]common.Log, error) {
+	option.FromBlock = "0x" + strconv.FormatUint(from, 16)
+	option.ToBlock = "0x" + strconv.FormatUint(to, 16)
+	logs, err := mc.getLogs(ctx, &option)
+	if err == nil || from >= to || !errors.Is(err, rpc.ErrLimitExceeded) {
+		return logs, err
+	}
+	return mc.splitLogs(ctx, option, from, to)
+}
+
+// splitLogs queries the two halves of the range between blocks from and to.
+func (mc *MoacAPI) splitLogs(ctx context.Context, option FilterOption, from, to uint64) ([]common.Log, error) {
+	mid := from + (to-from)/2
+	logs, err := mc.getLogsRange(ctx, option, from, mid)
+	if err != nil {
+		return nil, err
+	}
+	more, err := mc.getLogsRange(ctx, option, mid+1, to)
+	if err != nil {
+		return nil, err
+	}
+	return append(logs, more...), nil
 }
 
-// GetLogs returns an array of all logs matching a given filter object.
-func (mc *MoacAPI) GetLogs(filter Filter) (result []interface{}, err error) {
+func (mc *MoacAPI) getLogs(ctx context.Context, option *FilterOption) ([]common.Log, error) {
 	req := mc.requestManager.NewRequest("mc_getLogs")
-	req.Set("params", filter.ID())
-	resp, err := mc.requestManager.Send(req)
+	req.Set("params", option)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return nil, err
 	}
@@ -846,14 +1155,67 @@ func (mc *MoacAPI) GetLogs(filter Filter) (result []interface{}, err error) {
 		return nil, resp.Error()
 	}
 
-	return resp.Get("result").([]interface{}), nil
+	return decodeLogs(resp.Get("result"))
+}
+
+// decodeLogs decodes a result holding a list of logs.
+func decodeLogs(result interface{}) ([]common.Log, error) {
+	list := []JSONLog{}
+	if jsonBytes, err := json.Marshal(result); err == nil {
+		if err := json.Unmarshal(jsonBytes, &list); err == nil {
+			logs := make([]common.Log, len(list))
+			for i, l := range list {
+				logs[i] = l.ToLog()
+			}
+			return logs, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%v", result)
+}
+
+// decodeHashes decodes a result holding a list of hashes.
+func decodeHashes(result interface{}) ([]common.Hash, error) {
+	list := []string{}
+	if jsonBytes, err := json.Marshal(result); err == nil {
+		if err := json.Unmarshal(jsonBytes, &list); err == nil {
+			hashes := make([]common.Hash, len(list))
+			for i, h := range list {
+				hashes[i] = common.StringToHash(h)
+			}
+			return hashes, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%v", result)
+}
+
+// resolveBlock returns the number of the block given by a block parameter,
+// which defaults to latest.
+func (mc *MoacAPI) resolveBlock(ctx context.Context, block string) (uint64, error) {
+	switch block {
+	case "earliest":
+		return 0, nil
+	case "", "latest", "pending":
+		number, err := mc.BlockNumberContext(ctx)
+		if err != nil {
+			return 0, err
+		}
+		return number.Uint64(), nil
+	}
+	return strconv.ParseUint(common.HexToString(block), 16, 64)
 }
 
 // GetWork returns the hash of the current block, the seedHash, and the boundary
 // condition to be met ("target").
 func (mc *MoacAPI) GetWork() (header, seed, boundary common.Hash, err error) {
+	return mc.GetWorkContext(context.Background())
+}
+
+// GetWorkContext is like GetWork but with a context.
+func (mc *MoacAPI) GetWorkContext(ctx context.Context) (header, seed, boundary common.Hash, err error) {
 	req := mc.requestManager.NewRequest("mc_getWork")
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return common.NewHash(nil), common.NewHash(nil), common.NewHash(nil), err
 	}
@@ -871,13 +1233,18 @@ func (mc *MoacAPI) GetWork() (header, seed, boundary common.Hash, err error) {
 
 // SubmitWork is used for submitting a proof-of-work solution.
 func (mc *MoacAPI) SubmitWork(nonce uint64, header, mixDigest common.Hash) (bool, error) {
+	return mc.SubmitWorkContext(context.Background(), nonce, header, mixDigest)
+}
+
+// SubmitWorkContext is like SubmitWork but with a context.
+func (mc *MoacAPI) SubmitWorkContext(ctx context.Context, nonce uint64, header, mixDigest common.Hash) (bool, error) {
 	req := mc.requestManager.NewRequest("mc_submitWork")
 	req.Set("params", []string{
 		fmt.Sprintf("0x%16x", nonce),
 		header.String(),
 		mixDigest.String(),
 	})
-	resp, err := mc.requestManager.Send(req)
+	resp, err := mc.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return false, err
 	}
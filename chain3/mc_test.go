@@ -522,16 +522,9 @@ func (suite *MoacTestSuite) Test_GetFilterChanges() {
 			},
 		},
 	}
-	returnedLogs, err := mc.GetFilterChanges(filter)
+	changes, err := mc.GetFilterChanges(filter)
 	if assert.NoError(suite.T(), err, "Should be no error") {
-		for i, l := range returnedLogs {
-			log := common.Log{}
-			rawBytes, err := json.Marshal(l)
-			assert.NoError(suite.T(), err, "Should be no error")
-			err = json.Unmarshal(rawBytes, &log)
-			assert.NoError(suite.T(), err, "Should be no error")
-			assert.EqualValues(suite.T(), logs[i], log, "Should be equal")
-		}
+		assert.EqualValues(suite.T(), logs, changes.Logs, "Should be equal")
 	}
 }
 
@@ -557,21 +550,13 @@ func (suite *MoacTestSuite) Test_GetFilterLogs() {
 	}
 	returnedLogs, err := mc.GetFilterLogs(filter)
 	if assert.NoError(suite.T(), err, "Should be no error") {
-		for i, l := range returnedLogs {
-			log := common.Log{}
-			rawBytes, err := json.Marshal(l)
-			assert.NoError(suite.T(), err, "Should be no error")
-			err = json.Unmarshal(rawBytes, &log)
-			assert.NoError(suite.T(), err, "Should be no error")
-			assert.EqualValues(suite.T(), logs[i], log, "Should be equal")
-		}
+		assert.EqualValues(suite.T(), logs, returnedLogs, "Should be equal")
 	}
 }
 
 func (suite *MoacTestSuite) Test_GetLogs() {
 	mc := suite.mc
 	option := &FilterOption{}
-	filter, err := mc.NewFilter(option)
 	logs := []common.Log{
 		{
 			LogIndex:         0x1,
@@ -588,7 +573,7 @@ func (suite *MoacTestSuite) Test_GetLogs() {
 			},
 		},
 	}
-	returnedLogs, err := mc.GetLogs(filter)
+	returnedLogs, err := mc.GetLogs(option)
 	if assert.NoError(suite.T(), err, "Should be no error") {
 		for i, l := range returnedLogs {
 			log := common.Log{}
@@ -30,6 +30,7 @@
 package chain3
 
 import (
+	"context"
 	"strconv"
 
 	"github.com/caivega/chain3go/common"
@@ -38,8 +39,11 @@ import (
 // Net ...
 type Net interface {
 	Version() (string, error)
+	VersionContext(ctx context.Context) (string, error)
 	PeerCount() (uint64, error)
+	PeerCountContext(ctx context.Context) (uint64, error)
 	Listening() (bool, error)
+	ListeningContext(ctx context.Context) (bool, error)
 }
 
 // NetAPI ...
@@ -54,8 +58,13 @@ func newNetAPI(requestManager *RequestManager) Net {
 
 // Version returns the current network protocol version.
 func (net *NetAPI) Version() (string, error) {
+	return net.VersionContext(context.Background())
+}
+
+// VersionContext is like Version but with a context.
+func (net *NetAPI) VersionContext(ctx context.Context) (string, error) {
 	req := net.requestManager.NewRequest("net_version")
-	resp, err := net.requestManager.Send(req)
+	resp, err := net.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return "", err
 	}
@@ -64,8 +73,13 @@ func (net *NetAPI) Version() (string, error) {
 
 // PeerCount returns number of peers currenly connected to the client.
 func (net *NetAPI) PeerCount() (uint64, error) {
+	return net.PeerCountContext(context.Background())
+}
+
+// PeerCountContext is like PeerCount but with a context.
+func (net *NetAPI) PeerCountContext(ctx context.Context) (uint64, error) {
 	req := net.requestManager.NewRequest("net_peerCount")
-	resp, err := net.requestManager.Send(req)
+	resp, err := net.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return 0, err
 	}
@@ -78,8 +92,13 @@ func (net *NetAPI) PeerCount() (uint64, error) {
 
 // Listening returns true if client is actively listening for network connections.
 func (net *NetAPI) Listening() (bool, error) {
+	return net.ListeningContext(context.Background())
+}
+
+// ListeningContext is like Listening but with a context.
+func (net *NetAPI) ListeningContext(ctx context.Context) (bool, error) {
 	req := net.requestManager.NewRequest("net_listening")
-	resp, err := net.requestManager.Send(req)
+	resp, err := net.requestManager.SendContext(ctx, req)
 	if err != nil {
 		return false, err
 	}
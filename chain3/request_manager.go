@@ -30,24 +30,122 @@
 package chain3
 
 import (
+	"context"
+	"errors"
+
 	"github.com/caivega/chain3go/provider"
 	"github.com/caivega/chain3go/rpc"
 )
 
+var (
+	// ErrSubscriptionNotSupported is returned when subscribing through a
+	// provider which cannot receive notifications
+	ErrSubscriptionNotSupported = errors.New("Subscriptions are not supported by the provider")
+	// ErrMissingResponse is reported for a request of a batch the node did not
+	// answer
+	ErrMissingResponse = errors.New("Missing response")
+)
+
+// RequestHook is called when a request is sent, and the returned function
+// once it completed, e.g. to collect metrics. For a request of a batch, err
+// also holds the error returned by the node.
+type RequestHook func(request rpc.Request) func(response rpc.Response, err error)
+
 // requestManager is responsible for passing messages to providers
 type RequestManager struct {
 	provider provider.Provider
 	rpc      rpc.RPC
+	hook     RequestHook
 }
 
 func NewRequestManager(provider provider.Provider) *RequestManager {
 	return &RequestManager{provider: provider, rpc: provider.GetRPCMethod()}
 }
 
+// SetHook sets the hook called for every request. It must not be called
+// while requests are sent.
+func (rm *RequestManager) SetHook(hook RequestHook) {
+	rm.hook = hook
+}
+
 func (rm *RequestManager) NewRequest(method string) rpc.Request {
 	return rm.rpc.NewRequest(method)
 }
 
 func (rm *RequestManager) Send(request rpc.Request) (rpc.Response, error) {
-	return rm.provider.Send(request)
+	return rm.SendContext(context.Background(), request)
+}
+
+// SendContext sends a request, giving up when ctx is done.
+func (rm *RequestManager) SendContext(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+	if rm.hook == nil {
+		return provider.SendContext(ctx, rm.provider, request)
+	}
+
+	done := rm.hook(request)
+	response, err := provider.SendContext(ctx, rm.provider, request)
+	done(response, err)
+	return response, err
+}
+
+// SendBatch sends requests in one round trip if the provider supports it, or
+// one after the other otherwise. The results are in the order of the requests.
+// The returned error is only set if the batch could not be sent at all.
+func (rm *RequestManager) SendBatch(requests []rpc.Request) ([]BatchResult, error) {
+	return rm.SendBatchContext(context.Background(), requests)
+}
+
+// SendBatchContext is like SendBatch but with a context.
+func (rm *RequestManager) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]BatchResult, error) {
+	results := make([]BatchResult, len(requests))
+	batchProvider, ok := rm.provider.(provider.BatchProvider)
+	if !ok {
+		for i, request := range requests {
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+			results[i].Response, results[i].Err = rm.SendContext(ctx, request)
+			if results[i].Err == nil {
+				results[i].Err = results[i].Response.Error()
+			}
+		}
+		return results, nil
+	}
+
+	dones := make([]func(rpc.Response, error), len(requests))
+	if rm.hook != nil {
+		for i, request := range requests {
+			dones[i] = rm.hook(request)
+		}
+	}
+
+	responses, err := batchProvider.SendBatchContext(ctx, requests)
+	if err != nil {
+		for _, done := range dones {
+			if done != nil {
+				done(nil, err)
+			}
+		}
+		return nil, err
+	}
+
+	for i, response := range responses {
+		results[i].Response = response
+		if response == nil {
+			results[i].Err = ErrMissingResponse
+		} else {
+			results[i].Err = response.Error()
+		}
+		if dones[i] != nil {
+			dones[i](results[i].Response, results[i].Err)
+		}
+	}
+	return results, nil
+}
+
+func (rm *RequestManager) subscriber() (provider.Subscriber, error) {
+	if subscriber, ok := provider.AsSubscriber(rm.provider); ok {
+		return subscriber, nil
+	}
+	return nil, ErrSubscriptionNotSupported
 }
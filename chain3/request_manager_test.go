@@ -0,0 +1,120 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package chain3
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RequestManagerTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	chain3 *Chain3
+}
+
+func (suite *RequestManagerTestSuite) Test_SendContext() {
+	rm := suite.chain3.CurrentRequestManager()
+	resp, err := rm.SendContext(context.Background(), rm.NewRequest("mc_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "0x4b7", resp.Get("result"), "should be equal")
+}
+
+func (suite *RequestManagerTestSuite) Test_Hook() {
+	rm := suite.chain3.CurrentRequestManager()
+	defer rm.SetHook(nil)
+
+	observed := []string{}
+	rm.SetHook(func(request rpc.Request) func(rpc.Response, error) {
+		observed = append(observed, "send "+request.Get("method").(string))
+		return func(response rpc.Response, err error) {
+			observed = append(observed, "done "+response.Get("result").(string))
+		}
+	})
+
+	_, err := suite.chain3.Mc.BlockNumber()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), []string{"send mc_blockNumber", "done 0x4b7"}, observed, "should be equal")
+}
+
+func (suite *RequestManagerTestSuite) Test_BlockNumberContext() {
+	number, err := suite.chain3.Mc.BlockNumberContext(context.Background())
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), big.NewInt(0x4b7), number, "should be equal")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = suite.chain3.Mc.BlockNumberContext(ctx)
+	assert.Error(suite.T(), err, "Should be canceled")
+}
+
+func (suite *RequestManagerTestSuite) Test_VersionContext() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := suite.chain3.Net.VersionContext(ctx)
+	assert.Error(suite.T(), err, "Should be timed out")
+}
+
+func (suite *RequestManagerTestSuite) SetupTest() {
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		req := rpc.JSONRPCRequest{}
+		json.NewDecoder(r.Body).Decode(&req)
+		resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier}
+		switch req.Method {
+		case "mc_blockNumber":
+			resp.Result = "0x4b7"
+		case "net_version":
+			// Hang until the client gives up.
+			<-r.Context().Done()
+			return
+		}
+		jsonBlob, _ := json.Marshal(resp)
+		w.Write(jsonBlob)
+	}))
+	suite.chain3 = NewChain3(provider.NewHTTPProvider(suite.server.URL, nil))
+}
+
+func (suite *RequestManagerTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func Test_RequestManagerTestSuite(t *testing.T) {
+	suite.Run(t, new(RequestManagerTestSuite))
+}
@@ -0,0 +1,278 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package chain3
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+)
+
+// ErrInvalidSubscriptionID is returned when the node answers a subscription
+// request without a subscription identifier
+var ErrInvalidSubscriptionID = errors.New("Invalid subscription identifier")
+
+// Subscription is the handle of a subscription created by one of the
+// Mc.Subscribe* methods. The data channel returned alongside it is closed when
+// the subscription ends.
+type Subscription interface {
+	// ID returns the subscription identifier assigned by the node.
+	ID() string
+	// Err receives the reason the subscription ended, unless it ended
+	// through Unsubscribe.
+	Err() <-chan error
+	// Unsubscribe cancels the subscription on the node.
+	Unsubscribe() error
+}
+
+type baseSubscription struct {
+	mc         *MoacAPI
+	subscriber provider.Subscriber
+	sub        *rpc.Subscription
+	errCh      chan error
+	quitCh     chan struct{}
+	once       sync.Once
+}
+
+// subscribe creates a subscription on the node and calls deliver for every
+// notification until it ends, then calls done.
+func (mc *MoacAPI) subscribe(ctx context.Context, deliver func(result []byte, quit <-chan struct{}), done func(), args ...interface{}) (Subscription, error) {
+	subscriber, err := mc.requestManager.subscriber()
+	if err != nil {
+		return nil, err
+	}
+
+	req := mc.requestManager.NewRequest("mc_subscribe")
+	req.Set("params", args)
+	resp, err := mc.requestManager.SendContext(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.Error() != nil {
+		return nil, resp.Error()
+	}
+
+	id, ok := resp.Get("result").(string)
+	if !ok {
+		return nil, ErrInvalidSubscriptionID
+	}
+	sub, err := subscriber.Subscribe(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &baseSubscription{
+		mc:         mc,
+		subscriber: subscriber,
+		sub:        sub,
+		errCh:      make(chan error, 1),
+		quitCh:     make(chan struct{}),
+	}
+	go s.run(deliver, done)
+	return s, nil
+}
+
+func (s *baseSubscription) run(deliver func(result []byte, quit <-chan struct{}), done func()) {
+	for n := range s.sub.Notifications() {
+		result, err := json.Marshal(n.Get("result"))
+		if err != nil {
+			continue
+		}
+		deliver(result, s.quitCh)
+	}
+
+	if err := s.sub.Err(); err != nil {
+		s.errCh <- err
+	}
+	s.stop()
+	done()
+}
+
+func (s *baseSubscription) stop() {
+	s.once.Do(func() {
+		close(s.quitCh)
+	})
+}
+
+// ID returns the subscription identifier
+func (s *baseSubscription) ID() string {
+	return s.sub.ID()
+}
+
+// Err ...
+func (s *baseSubscription) Err() <-chan error {
+	return s.errCh
+}
+
+// Unsubscribe cancels the subscription. Notifications which were not yet
+// consumed are discarded.
+func (s *baseSubscription) Unsubscribe() error {
+	s.stop()
+	s.subscriber.Unsubscribe(s.sub.ID())
+
+	req := s.mc.requestManager.NewRequest("mc_unsubscribe")
+	req.Set("params", s.sub.ID())
+	resp, err := s.mc.requestManager.Send(req)
+	if err != nil {
+		return err
+	}
+
+	return resp.Error()
+}
+
+// SubscribeNewHeads subscribes to the headers of the blocks added to the chain,
+// including the ones of a chain reorganization.
+func (mc *MoacAPI) SubscribeNewHeads() (<-chan *common.Block, Subscription, error) {
+	return mc.SubscribeNewHeadsContext(context.Background())
+}
+
+// SubscribeNewHeadsContext is like SubscribeNewHeads but with a context, which only applies to
+// creating the subscription.
+func (mc *MoacAPI) SubscribeNewHeadsContext(ctx context.Context) (<-chan *common.Block, Subscription, error) {
+	ch := make(chan *common.Block)
+	sub, err := mc.subscribe(ctx, func(result []byte, quit <-chan struct{}) {
+		header := &JSONBlock{}
+		if err := json.Unmarshal(result, header); err != nil {
+			return
+		}
+		select {
+		case ch <- header.ToBlock():
+		case <-quit:
+		}
+	}, func() { close(ch) }, "newHeads")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ch, sub, nil
+}
+
+// SubscribeLogs subscribes to the logs matching the given filter option which
+// are included in new blocks.
+func (mc *MoacAPI) SubscribeLogs(option *FilterOption) (<-chan common.Log, Subscription, error) {
+	return mc.SubscribeLogsContext(context.Background(), option)
+}
+
+// SubscribeLogsContext is like SubscribeLogs but with a context, which only applies to
+// creating the subscription.
+func (mc *MoacAPI) SubscribeLogsContext(ctx context.Context, option *FilterOption) (<-chan common.Log, Subscription, error) {
+	if option == nil {
+		option = &FilterOption{}
+	}
+	ch := make(chan common.Log)
+	sub, err := mc.subscribe(ctx, func(result []byte, quit <-chan struct{}) {
+		log := JSONLog{}
+		if err := json.Unmarshal(result, &log); err != nil {
+			return
+		}
+		select {
+		case ch <- log.ToLog():
+		case <-quit:
+		}
+	}, func() { close(ch) }, "logs", option)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ch, sub, nil
+}
+
+// SubscribePendingTransactions subscribes to the hashes of the transactions
+// added to the pending state.
+func (mc *MoacAPI) SubscribePendingTransactions() (<-chan common.Hash, Subscription, error) {
+	return mc.SubscribePendingTransactionsContext(context.Background())
+}
+
+// SubscribePendingTransactionsContext is like SubscribePendingTransactions but with a context, which only applies to
+// creating the subscription.
+func (mc *MoacAPI) SubscribePendingTransactionsContext(ctx context.Context) (<-chan common.Hash, Subscription, error) {
+	ch := make(chan common.Hash)
+	sub, err := mc.subscribe(ctx, func(result []byte, quit <-chan struct{}) {
+		var hash string
+		if err := json.Unmarshal(result, &hash); err != nil {
+			return
+		}
+		select {
+		case ch <- common.StringToHash(hash):
+		case <-quit:
+		}
+	}, func() { close(ch) }, "newPendingTransactions")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ch, sub, nil
+}
+
+// SubscribeSyncing subscribes to the changes of the synchronization status.
+func (mc *MoacAPI) SubscribeSyncing() (<-chan common.SyncStatus, Subscription, error) {
+	return mc.SubscribeSyncingContext(context.Background())
+}
+
+// SubscribeSyncingContext is like SubscribeSyncing but with a context, which only applies to
+// creating the subscription.
+func (mc *MoacAPI) SubscribeSyncingContext(ctx context.Context) (<-chan common.SyncStatus, Subscription, error) {
+	ch := make(chan common.SyncStatus)
+	sub, err := mc.subscribe(ctx, func(result []byte, quit <-chan struct{}) {
+		var status common.SyncStatus
+		if err := json.Unmarshal(result, &status.Result); err != nil {
+			progress := struct {
+				Syncing bool `json:"syncing"`
+				Status  struct {
+					StartingBlock JSONQuantity `json:"startingBlock"`
+					CurrentBlock  JSONQuantity `json:"currentBlock"`
+					HighestBlock  JSONQuantity `json:"highestBlock"`
+				} `json:"status"`
+			}{}
+			if err := json.Unmarshal(result, &progress); err != nil {
+				return
+			}
+			status.Result = progress.Syncing
+			status.StartingBlock = toBigInt(progress.Status.StartingBlock)
+			status.CurrentBlock = toBigInt(progress.Status.CurrentBlock)
+			status.HighestBlock = toBigInt(progress.Status.HighestBlock)
+		}
+		select {
+		case ch <- status:
+		case <-quit:
+		}
+	}, func() { close(ch) }, "syncing")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return ch, sub, nil
+}
@@ -0,0 +1,217 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package chain3
+
+import (
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type SubscriptionTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	provider provider.Provider
+	mc       Mc
+
+	mu           sync.Mutex
+	unsubscribed []string
+	nullResult   bool
+}
+
+func (suite *SubscriptionTestSuite) Test_SubscribeNewHeads() {
+	heads, sub, err := suite.mc.SubscribeNewHeads()
+	if assert.NoError(suite.T(), err, "Should be no error") {
+		assert.EqualValues(suite.T(), "0xnewHeads", sub.ID(), "should be equal")
+		for i := int64(1); i <= 3; i++ {
+			head := <-heads
+			assert.EqualValues(suite.T(), big.NewInt(i), head.Number, "should be equal")
+		}
+		assert.NoError(suite.T(), sub.Unsubscribe(), "Should be no error")
+		for range heads {
+		}
+		assert.EqualValues(suite.T(), []string{"0xnewHeads"}, suite.unsubscribedIDs(), "should be equal")
+	}
+}
+
+func (suite *SubscriptionTestSuite) Test_SubscribeLogs() {
+	logs, sub, err := suite.mc.SubscribeLogs(&FilterOption{Address: "0x8b14c0f1de8159204f841850606bf6ac36ed89b3"})
+	if assert.NoError(suite.T(), err, "Should be no error") {
+		log := <-logs
+		assert.EqualValues(suite.T(), "0x8b14c0f1de8159204f841850606bf6ac36ed89b3", log.Address.String(), "should be equal")
+		assert.EqualValues(suite.T(), big.NewInt(1), log.BlockNumber, "should be equal")
+		assert.NoError(suite.T(), sub.Unsubscribe(), "Should be no error")
+	}
+}
+
+func (suite *SubscriptionTestSuite) Test_SubscribePendingTransactions() {
+	hashes, sub, err := suite.mc.SubscribePendingTransactions()
+	if assert.NoError(suite.T(), err, "Should be no error") {
+		hash := <-hashes
+		assert.EqualValues(suite.T(), common.StringToHash("0xe670ec64341771606e55d6b4ca35a1a6b75ee3d5145a99d05921026d1527331"), hash, "should be equal")
+		assert.NoError(suite.T(), sub.Unsubscribe(), "Should be no error")
+	}
+}
+
+func (suite *SubscriptionTestSuite) Test_SubscribeSyncing() {
+	statuses, sub, err := suite.mc.SubscribeSyncing()
+	if assert.NoError(suite.T(), err, "Should be no error") {
+		status := <-statuses
+		assert.True(suite.T(), status.Result, "should be syncing")
+		assert.EqualValues(suite.T(), big.NewInt(0x10), status.HighestBlock, "should be equal")
+		status = <-statuses
+		assert.False(suite.T(), status.Result, "should not be syncing")
+		assert.NoError(suite.T(), sub.Unsubscribe(), "Should be no error")
+	}
+}
+
+func (suite *SubscriptionTestSuite) Test_ConnectionLost() {
+	heads, sub, err := suite.mc.SubscribeNewHeads()
+	if assert.NoError(suite.T(), err, "Should be no error") {
+		suite.provider.(*provider.WebSocketProvider).Close()
+		for range heads {
+		}
+		assert.Equal(suite.T(), provider.ErrProviderClosed, <-sub.Err(), "should be equal")
+	}
+}
+
+func (suite *SubscriptionTestSuite) Test_NotSupported() {
+	mc := NewChain3(provider.NewHTTPProvider("localhost:0", nil)).Mc
+	_, _, err := mc.SubscribeNewHeads()
+	assert.Equal(suite.T(), ErrSubscriptionNotSupported, err, "should be equal")
+}
+
+func (suite *SubscriptionTestSuite) Test_InvalidID() {
+	suite.mu.Lock()
+	suite.nullResult = true
+	suite.mu.Unlock()
+	_, _, err := suite.mc.SubscribeNewHeads()
+	assert.Equal(suite.T(), ErrInvalidSubscriptionID, err, "should be equal")
+}
+
+func (suite *SubscriptionTestSuite) unsubscribedIDs() []string {
+	suite.mu.Lock()
+	defer suite.mu.Unlock()
+	return suite.unsubscribed
+}
+
+// notifications returns what the fake node pushes for each subscription
+func (suite *SubscriptionTestSuite) notifications(kind string) []interface{} {
+	switch kind {
+	case "newHeads":
+		return []interface{}{
+			map[string]interface{}{"number": "0x1", "hash": "0x01"},
+			map[string]interface{}{"number": "0x2", "hash": "0x02"},
+			map[string]interface{}{"number": "0x3", "hash": "0x03"},
+		}
+	case "logs":
+		return []interface{}{
+			map[string]interface{}{"address": "0x8b14c0f1de8159204f841850606bf6ac36ed89b3", "blockNumber": "0x1"},
+		}
+	case "newPendingTransactions":
+		return []interface{}{"0xe670ec64341771606e55d6b4ca35a1a6b75ee3d5145a99d05921026d1527331"}
+	case "syncing":
+		return []interface{}{
+			map[string]interface{}{"syncing": true, "status": map[string]interface{}{
+				"startingBlock": "0x0", "currentBlock": "0x1", "highestBlock": "0x10"}},
+			false,
+		}
+	}
+	return nil
+}
+
+func (suite *SubscriptionTestSuite) SetupTest() {
+	suite.unsubscribed = nil
+	suite.nullResult = false
+	upgrader := websocket.Upgrader{}
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		for {
+			req := rpc.JSONRPCRequest{}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier}
+			var pushed []interface{}
+			switch req.Method {
+			case "mc_subscribe":
+				kind := req.Params[0].(string)
+				suite.mu.Lock()
+				nullResult := suite.nullResult
+				suite.mu.Unlock()
+				if nullResult {
+					break
+				}
+				resp.Result = "0x" + kind
+				pushed = suite.notifications(kind)
+			case "mc_unsubscribe":
+				suite.mu.Lock()
+				suite.unsubscribed = append(suite.unsubscribed, req.Params[0].(string))
+				suite.mu.Unlock()
+				resp.Result = true
+			}
+			conn.WriteJSON(resp)
+			for _, result := range pushed {
+				n := rpc.JSONRPCNotification{Version: "2.0", Method: "mc_subscription"}
+				n.Params.Subscription = resp.Result.(string)
+				n.Params.Result = result
+				jsonBlob, _ := json.Marshal(n)
+				conn.WriteMessage(websocket.TextMessage, jsonBlob)
+			}
+		}
+	}))
+	suite.provider = provider.NewWebSocketProvider(strings.Replace(suite.server.URL, "http://", "ws://", 1), nil)
+	suite.mc = NewChain3(suite.provider).Mc
+}
+
+func (suite *SubscriptionTestSuite) TearDownTest() {
+	suite.provider.(*provider.WebSocketProvider).Close()
+	suite.server.Close()
+}
+
+func Test_SubscriptionTestSuite(t *testing.T) {
+	suite.Run(t, new(SubscriptionTestSuite))
+}
@@ -37,26 +37,26 @@ import (
 )
 
 type JSONBlock struct {
-	Difficulty       json.Number `json:"difficulty"`
-	ExtraData        string      `json:"extraData"`
-	GasLimit         json.Number `json:"gasLimit"`
-	GasUsed          json.Number `json:"gasUsed"`
-	Hash             string      `json:"hash"`
-	LogsBloom        string      `json:"logsBloom"`
-	Miner            string      `json:"miner"`
-	MixHash          string      `json:"mixHash"`
-	Nonce            string      `json:"nonce"`
-	Number           json.Number `json:"number"`
-	ParentHash       string      `json:"parentHash"`
-	ReceiptsRoot     string      `json:"receiptsRoot"`
-	Sha3Uncles       string      `json:"sha3Uncles"`
-	Size             json.Number `json:"size"`
-	StateRoot        string      `json:"stateRoot"`
-	Timestamp        json.Number `json:"timestamp"`
-	TotalDifficulty  json.Number `json:"totalDifficulty"`
-	Transactions     []string    `json:"transactions"`
-	TransactionsRoot string      `json:"transactionsRoot"`
-	Uncles           []string    `json:"uncles"`
+	Difficulty       JSONQuantity `json:"difficulty"`
+	ExtraData        string       `json:"extraData"`
+	GasLimit         JSONQuantity `json:"gasLimit"`
+	GasUsed          JSONQuantity `json:"gasUsed"`
+	Hash             string       `json:"hash"`
+	LogsBloom        string       `json:"logsBloom"`
+	Miner            string       `json:"miner"`
+	MixHash          string       `json:"mixHash"`
+	Nonce            string       `json:"nonce"`
+	Number           JSONQuantity `json:"number"`
+	ParentHash       string       `json:"parentHash"`
+	ReceiptsRoot     string       `json:"receiptsRoot"`
+	Sha3Uncles       string       `json:"sha3Uncles"`
+	Size             JSONQuantity `json:"size"`
+	StateRoot        string       `json:"stateRoot"`
+	Timestamp        JSONQuantity `json:"timestamp"`
+	TotalDifficulty  JSONQuantity `json:"totalDifficulty"`
+	Transactions     []string     `json:"transactions"`
+	TransactionsRoot string       `json:"transactionsRoot"`
+	Uncles           []string     `json:"uncles"`
 }
 
 func (b *JSONBlock) ToBlock() (block *common.Block) {
@@ -85,22 +85,22 @@ func (b *JSONBlock) ToBlock() (block *common.Block) {
 }
 
 type JSONTransaction struct {
-	BlockHash        string      `json:"blockHash"`
-	BlockNumber      json.Number `json:"blockNumber"`
-	From             string      `json:"from"`
-	Gas              json.Number `json:"gas"`
-	GasPrice         json.Number `json:"gasprice"`
-	Hash             string      `json:"hash"`
-	Input            string      `json:"input"`
-	Nonce            json.Number `json:"nonce"`
-	R                string      `json:"r"`
-	S                string      `json:"s"`
-	ShardingFlag     string      `json:"shardingFlag"`
-	SysCnt           string      `json:"syscnt"`
-	To               string      `json:"to"`
-	TransactionIndex json.Number `json:"transactionIndex"`
-	V                string      `json:"v"`
-	Value            json.Number `json:"value"`
+	BlockHash        string       `json:"blockHash"`
+	BlockNumber      JSONQuantity `json:"blockNumber"`
+	From             string       `json:"from"`
+	Gas              JSONQuantity `json:"gas"`
+	GasPrice         JSONQuantity `json:"gasprice"`
+	Hash             string       `json:"hash"`
+	Input            string       `json:"input"`
+	Nonce            JSONQuantity `json:"nonce"`
+	R                string       `json:"r"`
+	S                string       `json:"s"`
+	ShardingFlag     string       `json:"shardingFlag"`
+	SysCnt           string       `json:"syscnt"`
+	To               string       `json:"to"`
+	TransactionIndex JSONQuantity `json:"transactionIndex"`
+	V                string       `json:"v"`
+	Value            JSONQuantity `json:"value"`
 }
 
 func (t *JSONTransaction) ToTransaction() (tx *common.Transaction) {
@@ -125,18 +125,18 @@ func (t *JSONTransaction) ToTransaction() (tx *common.Transaction) {
 }
 
 type JSONTransactionReceipt struct {
-	BlockHash         string      `json:"blockHash"`
-	BlockNumber       json.Number `json:"blockNumber"`
-	ContractAddress   string      `json:"contractAddress"`
-	CumulativeGasUsed json.Number `json:"cumulativeGasUsed"`
-	From              string      `json:"from"`
-	GasUsed           json.Number `json:"gasUsed"`
-	Logs              []JSONLog   `json:"logs"`
-	LogsBloom         string      `json:"logsBloom"`
-	Root              string      `json:"root"`
-	To                string      `json:"to"`
-	TransactionHash   string      `json:"transactionHash"`
-	TransactionIndex  json.Number `json:"transactionIndex"`
+	BlockHash         string       `json:"blockHash"`
+	BlockNumber       JSONQuantity `json:"blockNumber"`
+	ContractAddress   string       `json:"contractAddress"`
+	CumulativeGasUsed JSONQuantity `json:"cumulativeGasUsed"`
+	From              string       `json:"from"`
+	GasUsed           JSONQuantity `json:"gasUsed"`
+	Logs              []JSONLog    `json:"logs"`
+	LogsBloom         string       `json:"logsBloom"`
+	Root              string       `json:"root"`
+	To                string       `json:"to"`
+	TransactionHash   string       `json:"transactionHash"`
+	TransactionIndex  JSONQuantity `json:"transactionIndex"`
 }
 
 func (r *JSONTransactionReceipt) ToTransactionReceipt() (receipt *common.TransactionReceipt) {
@@ -160,15 +160,15 @@ func (r *JSONTransactionReceipt) ToTransactionReceipt() (receipt *common.Transac
 }
 
 type JSONLog struct {
-	TxData           string      `json:"TxData"`
-	Address          string      `json:"address"`
-	BlockHash        string      `json:"blockHash"`
-	BlockNumber      json.Number `json:"blockNumber"`
-	LogIndex         json.Number `json:"logIndex"`
-	Removed          bool        `json:"removed"`
-	Topics           []string    `json:"topics"`
-	TransactionHash  string      `json:"transactionHash"`
-	TransactionIndex json.Number `json:"transactionIndex"`
+	TxData           string       `json:"TxData"`
+	Address          string       `json:"address"`
+	BlockHash        string       `json:"blockHash"`
+	BlockNumber      JSONQuantity `json:"blockNumber"`
+	LogIndex         JSONQuantity `json:"logIndex"`
+	Removed          bool         `json:"removed"`
+	Topics           []string     `json:"topics"`
+	TransactionHash  string       `json:"transactionHash"`
+	TransactionIndex JSONQuantity `json:"transactionIndex"`
 }
 
 func (l JSONLog) ToLog() (log common.Log) {
@@ -185,7 +185,32 @@ func (l JSONLog) ToLog() (log common.Log) {
 	return log
 }
 
-func toBigInt(data json.Number) *big.Int {
+// JSONQuantity is a numeric value, which nodes encode either as a JSON number
+// or as a hex string.
+type JSONQuantity string
+
+// UnmarshalJSON accepts both numbers and strings.
+func (q *JSONQuantity) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	if len(data) > 1 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		*q = JSONQuantity(s)
+		return nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(data, &n); err != nil {
+		return err
+	}
+	*q = JSONQuantity(n)
+	return nil
+}
+
+func toBigInt(data JSONQuantity) *big.Int {
 	f := new(big.Int)
 	f.SetString(string(data), 0)
 	return f
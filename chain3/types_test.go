@@ -0,0 +1,172 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package chain3
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// TypesTestSuite checks that the getters decode quantities encoded either as
+// hex strings or as JSON numbers
+type TypesTestSuite struct {
+	suite.Suite
+	server  *httptest.Server
+	numeric bool
+	mc      Mc
+}
+
+func (suite *TypesTestSuite) quantity(n int64) interface{} {
+	if suite.numeric {
+		return n
+	}
+	return "0x" + big.NewInt(n).Text(16)
+}
+
+func (suite *TypesTestSuite) log() map[string]interface{} {
+	return map[string]interface{}{
+		"blockNumber":      suite.quantity(16),
+		"logIndex":         suite.quantity(2),
+		"transactionIndex": suite.quantity(1),
+	}
+}
+
+func (suite *TypesTestSuite) answer(req rpc.JSONRPCRequest) *rpc.JSONRPCResponse {
+	resp := &rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier}
+	switch req.Method {
+	case "mc_getBlockByNumber":
+		resp.Result = map[string]interface{}{
+			"difficulty":      suite.quantity(131072),
+			"gasLimit":        suite.quantity(9000000),
+			"gasUsed":         suite.quantity(21000),
+			"number":          suite.quantity(16),
+			"size":            suite.quantity(540),
+			"timestamp":       suite.quantity(1500000000),
+			"totalDifficulty": suite.quantity(2097152),
+		}
+	case "mc_getTransactionByHash":
+		resp.Result = map[string]interface{}{
+			"blockNumber":      suite.quantity(16),
+			"gas":              suite.quantity(21000),
+			"gasprice":         suite.quantity(20000000000),
+			"nonce":            suite.quantity(7),
+			"transactionIndex": suite.quantity(1),
+			"value":            suite.quantity(1000),
+		}
+	case "mc_getTransactionReceipt":
+		resp.Result = map[string]interface{}{
+			"blockNumber":       suite.quantity(16),
+			"cumulativeGasUsed": suite.quantity(42000),
+			"gasUsed":           suite.quantity(21000),
+			"transactionIndex":  suite.quantity(1),
+			"logs":              []interface{}{suite.log()},
+		}
+	case "mc_getLogs":
+		resp.Result = []interface{}{suite.log()}
+	}
+	return resp
+}
+
+func (suite *TypesTestSuite) check() {
+	block, err := suite.mc.GetBlockByNumber("0x10", false)
+	suite.Require().NoError(err)
+	assert.EqualValues(suite.T(), big.NewInt(131072), block.Difficulty, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(9000000), block.GasLimit, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(21000), block.GasUsed, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(16), block.Number, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(540), block.Size, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(1500000000), block.Timestamp, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(2097152), block.TotalDifficulty, "should be equal")
+
+	tx, err := suite.mc.GetTransactionByHash(common.StringToHash("0x01"))
+	suite.Require().NoError(err)
+	assert.EqualValues(suite.T(), big.NewInt(16), tx.BlockNumber, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(21000), tx.Gas, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(20000000000), tx.GasPrice, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(7), tx.Nonce, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(1), tx.TransactionIndex, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(1000), tx.Value, "should be equal")
+
+	receipt, err := suite.mc.GetTransactionReceipt(common.StringToHash("0x01"))
+	suite.Require().NoError(err)
+	assert.EqualValues(suite.T(), big.NewInt(16), receipt.BlockNumber, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(42000), receipt.CumulativeGasUsed, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(21000), receipt.GasUsed, "should be equal")
+	assert.EqualValues(suite.T(), big.NewInt(1), receipt.TransactionIndex, "should be equal")
+
+	logs, err := suite.mc.GetLogs(&FilterOption{})
+	suite.Require().NoError(err)
+	for _, logs := range [][]common.Log{logs, receipt.Logs} {
+		if assert.Len(suite.T(), logs, 1) {
+			assert.EqualValues(suite.T(), big.NewInt(16), logs[0].BlockNumber, "should be equal")
+			assert.EqualValues(suite.T(), big.NewInt(2), logs[0].LogIndex, "should be equal")
+			assert.EqualValues(suite.T(), big.NewInt(1), logs[0].TransactionIndex, "should be equal")
+		}
+	}
+}
+
+func (suite *TypesTestSuite) Test_HexQuantities() {
+	suite.numeric = false
+	suite.check()
+}
+
+func (suite *TypesTestSuite) Test_NumericQuantities() {
+	suite.numeric = true
+	suite.check()
+}
+
+func (suite *TypesTestSuite) SetupTest() {
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		req := rpc.JSONRPCRequest{}
+		json.Unmarshal(body, &req)
+		jsonBlob, _ := json.Marshal(suite.answer(req))
+		w.Write(jsonBlob)
+	}))
+	suite.mc = NewChain3(provider.NewHTTPProvider(suite.server.URL, nil)).Mc
+}
+
+func (suite *TypesTestSuite) TearDownTest() {
+	suite.server.Close()
+}
+
+func Test_TypesTestSuite(t *testing.T) {
+	suite.Run(t, new(TypesTestSuite))
+}
@@ -0,0 +1,631 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package rlp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+)
+
+var (
+	// EOL is returned when the end of the current list is reached
+	EOL = errors.New("rlp: end of list")
+
+	ErrExpectedString   = errors.New("rlp: expected String or Byte")
+	ErrExpectedList     = errors.New("rlp: expected List")
+	ErrCanonInt         = errors.New("rlp: non-canonical integer format")
+	ErrCanonSize        = errors.New("rlp: non-canonical size information")
+	ErrElemTooLarge     = errors.New("rlp: element is larger than containing list")
+	ErrValueTooLarge    = errors.New("rlp: value size exceeds available input length")
+	ErrMoreThanOneValue = errors.New("rlp: input contains more than one value")
+	ErrUintOverflow     = errors.New("rlp: uint overflow")
+	ErrWrongSize        = errors.New("rlp: input string has the wrong size")
+	ErrTooFewElements   = errors.New("rlp: too few elements")
+	ErrTooManyElements  = errors.New("rlp: too many elements")
+	ErrNotAtEOL         = errors.New("rlp: call of ListEnd not positioned at EOL")
+	ErrDecodeIntoNil    = errors.New("rlp: decode target must be a non-nil pointer")
+)
+
+// Decoder is implemented by types with a custom decoding. DecodeRLP must read
+// exactly one value from the stream.
+type Decoder interface {
+	DecodeRLP(s *Stream) error
+}
+
+var decoderType = reflect.TypeOf((*Decoder)(nil)).Elem()
+
+// Kind is the kind of an encoded value
+type Kind int
+
+const (
+	// Byte is a single byte below 0x80, which is its own encoding
+	Byte Kind = iota
+	// String is a byte string
+	String
+	// List is a list of values
+	List
+)
+
+func (k Kind) String() string {
+	switch k {
+	case Byte:
+		return "Byte"
+	case String:
+		return "String"
+	case List:
+		return "List"
+	}
+	return fmt.Sprintf("Unknown(%d)", int(k))
+}
+
+// Decode reads one value from r into val, which must be a non-nil pointer.
+// Values are read as encoded by Encode, with a non-canonical encoding being an
+// error.
+func Decode(r io.Reader, val interface{}) error {
+	return NewStream(r, 0).Decode(val)
+}
+
+// DecodeBytes decodes b into val, which must hold exactly one value
+func DecodeBytes(b []byte, val interface{}) error {
+	s := NewStream(bytes.NewReader(b), uint64(len(b)))
+	if err := s.Decode(val); err != nil {
+		return err
+	}
+	if s.remaining > 0 {
+		return ErrMoreThanOneValue
+	}
+	return nil
+}
+
+// readChunkSize is the most allocated at once to read a value of an input of
+// unknown size
+const readChunkSize = 64 << 10
+
+type byteReader interface {
+	io.Reader
+	io.ByteReader
+}
+
+// Stream reads values one piece at a time, which allows decoding a value
+// without knowing its type upfront, or values larger than memory.
+type Stream struct {
+	r byteReader
+	// remaining is the size of the input left when it is limited
+	remaining uint64
+	limited   bool
+	// stack holds the size left in each list being read
+	stack []uint64
+
+	kind    Kind
+	size    uint64
+	byteval byte
+	kinderr error
+	kindok  bool
+}
+
+// NewStream creates a stream reading from r. When inputLimit isn't 0, values
+// larger than it are rejected before being read. The limit is the size of r
+// when it is a *bytes.Reader, *bytes.Buffer or *strings.Reader.
+func NewStream(r io.Reader, inputLimit uint64) *Stream {
+	s := &Stream{}
+	if br, ok := r.(byteReader); ok {
+		s.r = br
+	} else {
+		s.r = bufio.NewReader(r)
+	}
+	if sized, ok := r.(interface{ Len() int }); ok && inputLimit == 0 {
+		inputLimit = uint64(sized.Len())
+		s.limited = true
+	}
+	if inputLimit > 0 {
+		s.remaining = inputLimit
+		s.limited = true
+	}
+	return s
+}
+
+// Kind returns the kind and size of the next value. The size of a Byte is 0.
+// It returns EOL at the end of the current list.
+func (s *Stream) Kind() (Kind, uint64, error) {
+	if s.kindok {
+		return s.kind, s.size, s.kinderr
+	}
+
+	if len(s.stack) > 0 && s.stack[len(s.stack)-1] == 0 {
+		return 0, 0, EOL
+	}
+	s.kind, s.size, s.kinderr = s.readKind()
+	if s.kinderr == nil {
+		if len(s.stack) > 0 && s.size > s.stack[len(s.stack)-1] {
+			s.kinderr = ErrElemTooLarge
+		} else if s.limited && s.size > s.remaining {
+			s.kinderr = ErrValueTooLarge
+		}
+	}
+	s.kindok = true
+	return s.kind, s.size, s.kinderr
+}
+
+func (s *Stream) readKind() (Kind, uint64, error) {
+	b, err := s.readByte()
+	if err != nil {
+		if len(s.stack) == 0 && err == io.ErrUnexpectedEOF {
+			// the end of the input between values
+			err = io.EOF
+		}
+		return 0, 0, err
+	}
+
+	s.byteval = 0
+	switch {
+	case b < 0x80:
+		s.byteval = b
+		return Byte, 0, nil
+	case b < 0xB8:
+		return String, uint64(b - 0x80), nil
+	case b < 0xC0:
+		size, err := s.readSize(b - 0xB7)
+		return String, size, err
+	case b < 0xF8:
+		return List, uint64(b - 0xC0), nil
+	default:
+		size, err := s.readSize(b - 0xF7)
+		return List, size, err
+	}
+}
+
+// readSize reads the size of a long string or list
+func (s *Stream) readSize(length byte) (uint64, error) {
+	b, err := s.readFull(uint64(length))
+	if err != nil {
+		return 0, err
+	}
+	if b[0] == 0 {
+		return 0, ErrCanonSize
+	}
+	var size uint64
+	for _, c := range b {
+		size = size<<8 | uint64(c)
+	}
+	if size < 56 {
+		return 0, ErrCanonSize
+	}
+	return size, nil
+}
+
+// Bytes reads a String or Byte
+func (s *Stream) Bytes() ([]byte, error) {
+	kind, size, err := s.Kind()
+	if err != nil {
+		return nil, err
+	}
+	switch kind {
+	case Byte:
+		s.kindok = false
+		return []byte{s.byteval}, nil
+	case String:
+		s.kindok = false
+		b, err := s.readFull(size)
+		if err != nil {
+			return nil, err
+		}
+		if size == 1 && b[0] < 0x80 {
+			return nil, ErrCanonSize
+		}
+		return b, nil
+	}
+	return nil, ErrExpectedString
+}
+
+// Raw reads the encoding of the next value, including its header
+func (s *Stream) Raw() ([]byte, error) {
+	kind, size, err := s.Kind()
+	if err != nil {
+		return nil, err
+	}
+	s.kindok = false
+	if kind == Byte {
+		return []byte{s.byteval}, nil
+	}
+
+	offset := byte(0x80)
+	if kind == List {
+		offset = 0xC0
+	}
+	content, err := s.readFull(size)
+	if err != nil {
+		return nil, err
+	}
+	if kind == String && size == 1 && content[0] < 0x80 {
+		return nil, ErrCanonSize
+	}
+	return append(appendHeader(nil, offset, size), content...), nil
+}
+
+// Uint64 reads an unsigned integer of at most 64 bits
+func (s *Stream) Uint64() (uint64, error) {
+	return s.uint(64)
+}
+
+func (s *Stream) uint(bits int) (uint64, error) {
+	b, err := s.intBytes()
+	if err != nil {
+		return 0, err
+	}
+	if len(b) > bits/8 {
+		return 0, ErrUintOverflow
+	}
+	var i uint64
+	for _, c := range b {
+		i = i<<8 | uint64(c)
+	}
+	if bits < 64 && i >= 1<<uint(bits) {
+		return 0, ErrUintOverflow
+	}
+	return i, nil
+}
+
+// BigInt reads an unsigned integer of any size
+func (s *Stream) BigInt() (*big.Int, error) {
+	b, err := s.intBytes()
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
+
+// intBytes reads the big endian bytes of an integer, which must not have
+// leading zeros
+func (s *Stream) intBytes() ([]byte, error) {
+	kind, _, err := s.Kind()
+	if err != nil {
+		return nil, err
+	}
+	if kind == Byte && s.byteval == 0 {
+		s.kindok = false
+		return nil, ErrCanonInt
+	}
+	b, err := s.Bytes()
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > 0 && b[0] == 0 {
+		return nil, ErrCanonInt
+	}
+	return b, nil
+}
+
+// List starts reading a list, returning the size of its content. Its values
+// are read until EOL, followed by a call to ListEnd.
+func (s *Stream) List() (uint64, error) {
+	kind, size, err := s.Kind()
+	if err != nil {
+		return 0, err
+	}
+	if kind != List {
+		return 0, ErrExpectedList
+	}
+	s.kindok = false
+	if len(s.stack) > 0 {
+		s.stack[len(s.stack)-1] -= size
+	}
+	s.stack = append(s.stack, size)
+	return size, nil
+}
+
+// ListEnd finishes reading the current list
+func (s *Stream) ListEnd() error {
+	if len(s.stack) == 0 || s.stack[len(s.stack)-1] != 0 {
+		return ErrNotAtEOL
+	}
+	s.stack = s.stack[:len(s.stack)-1]
+	s.kindok = false
+	return nil
+}
+
+// Decode reads the next value into val, which must be a non-nil pointer
+func (s *Stream) Decode(val interface{}) error {
+	v := reflect.ValueOf(val)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return ErrDecodeIntoNil
+	}
+	return s.decodeValue(v.Elem())
+}
+
+func (s *Stream) readByte() (byte, error) {
+	if len(s.stack) > 0 && s.stack[len(s.stack)-1] == 0 {
+		return 0, ErrElemTooLarge
+	}
+	if s.limited && s.remaining == 0 {
+		return 0, io.ErrUnexpectedEOF
+	}
+	b, err := s.r.ReadByte()
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	if err != nil {
+		return 0, err
+	}
+	s.consumed(1)
+	return b, nil
+}
+
+func (s *Stream) readFull(n uint64) ([]byte, error) {
+	if len(s.stack) > 0 && n > s.stack[len(s.stack)-1] {
+		return nil, ErrElemTooLarge
+	}
+	if s.limited && n > s.remaining {
+		return nil, ErrValueTooLarge
+	}
+	if !s.limited && n > readChunkSize {
+		return s.readChunks(n)
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(s.r, b); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	s.consumed(n)
+	return b, nil
+}
+
+// readChunks reads a large value of an input of unknown size, whose declared
+// size can't be trusted to allocate it upfront
+func (s *Stream) readChunks(n uint64) ([]byte, error) {
+	var b []byte
+	for left := n; left > 0; {
+		chunk := left
+		if chunk > readChunkSize {
+			chunk = readChunkSize
+		}
+		start := len(b)
+		b = append(b, make([]byte, chunk)...)
+		if _, err := io.ReadFull(s.r, b[start:]); err != nil {
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				err = ErrValueTooLarge
+			}
+			return nil, err
+		}
+		left -= chunk
+	}
+	s.consumed(n)
+	return b, nil
+}
+
+func (s *Stream) consumed(n uint64) {
+	if s.limited {
+		s.remaining -= n
+	}
+	if len(s.stack) > 0 {
+		s.stack[len(s.stack)-1] -= n
+	}
+}
+
+func (s *Stream) decodeValue(v reflect.Value) error {
+	t := v.Type()
+	switch {
+	case t == rawValueType:
+		b, err := s.Raw()
+		if err != nil {
+			return err
+		}
+		v.SetBytes(b)
+		return nil
+	case v.CanAddr() && reflect.PtrTo(t).Implements(decoderType):
+		return v.Addr().Interface().(Decoder).DecodeRLP(s)
+	case t == bigIntType:
+		i, err := s.BigInt()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(*i))
+		return nil
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		i, err := s.uint(8)
+		if err != nil {
+			return err
+		}
+		if i > 1 {
+			return fmt.Errorf("rlp: invalid boolean value %d", i)
+		}
+		v.SetBool(i == 1)
+		return nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		i, err := s.uint(t.Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(i)
+		return nil
+	case reflect.String:
+		b, err := s.Bytes()
+		if err != nil {
+			return err
+		}
+		v.SetString(string(b))
+		return nil
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			b, err := s.Bytes()
+			if err != nil {
+				return err
+			}
+			v.SetBytes(b)
+			return nil
+		}
+		return s.decodeList(v)
+	case reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			b, err := s.Bytes()
+			if err != nil {
+				return err
+			}
+			if len(b) != v.Len() {
+				return fmt.Errorf("%w: %d bytes for %v", ErrWrongSize, len(b), t)
+			}
+			reflect.Copy(v, reflect.ValueOf(b))
+			return nil
+		}
+		return s.decodeList(v)
+	case reflect.Struct:
+		return s.decodeStruct(v)
+	case reflect.Ptr:
+		if v.IsNil() {
+			v.Set(reflect.New(t.Elem()))
+		}
+		return s.decodeValue(v.Elem())
+	case reflect.Interface:
+		if t.NumMethod() != 0 {
+			break
+		}
+		val, err := s.decodeInterface()
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(val))
+		return nil
+	}
+	return fmt.Errorf("rlp: type %v is not RLP-serializable", t)
+}
+
+// decodeList decodes a list into a slice or an array, which must have as
+// many elements as the list
+func (s *Stream) decodeList(v reflect.Value) error {
+	if _, err := s.List(); err != nil {
+		return err
+	}
+
+	if v.Kind() == reflect.Slice {
+		v.Set(reflect.MakeSlice(v.Type(), 0, 0))
+	}
+	for i := 0; ; i++ {
+		if _, _, err := s.Kind(); err == EOL {
+			if v.Kind() == reflect.Array && i < v.Len() {
+				return fmt.Errorf("%w for %v", ErrTooFewElements, v.Type())
+			}
+			break
+		}
+		if v.Kind() == reflect.Slice {
+			v.Set(reflect.Append(v, reflect.Zero(v.Type().Elem())))
+		} else if i >= v.Len() {
+			return fmt.Errorf("%w for %v", ErrTooManyElements, v.Type())
+		}
+		if err := s.decodeValue(v.Index(i)); err != nil {
+			return err
+		}
+	}
+	return s.ListEnd()
+}
+
+func (s *Stream) decodeStruct(v reflect.Value) error {
+	if _, err := s.List(); err != nil {
+		return err
+	}
+
+	for _, i := range structFields(v.Type()) {
+		if v.Type().Field(i).Tag.Get("rlp") == "nil" {
+			ok, err := s.decodeNil(v.Field(i))
+			if err != nil {
+				return err
+			}
+			if ok {
+				continue
+			}
+		}
+		err := s.decodeValue(v.Field(i))
+		if err == EOL {
+			return fmt.Errorf("%w for %v", ErrTooFewElements, v.Type())
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if _, _, err := s.Kind(); err != EOL {
+		return fmt.Errorf("%w for %v", ErrTooManyElements, v.Type())
+	}
+	return s.ListEnd()
+}
+
+// decodeNil sets the pointer v to nil when the next value is empty, reporting
+// whether it did
+func (s *Stream) decodeNil(v reflect.Value) (bool, error) {
+	kind, size, err := s.Kind()
+	if err != nil || kind == Byte || size > 0 || v.Kind() != reflect.Ptr {
+		return false, nil
+	}
+	if kind == List {
+		if _, err := s.List(); err != nil {
+			return false, err
+		}
+		err = s.ListEnd()
+	} else {
+		_, err = s.Bytes()
+	}
+	v.Set(reflect.Zero(v.Type()))
+	return true, err
+}
+
+// decodeInterface decodes a value of unknown type, lists as []interface{} and
+// strings as []byte
+func (s *Stream) decodeInterface() (interface{}, error) {
+	kind, _, err := s.Kind()
+	if err != nil {
+		return nil, err
+	}
+	if kind != List {
+		return s.Bytes()
+	}
+
+	if _, err := s.List(); err != nil {
+		return nil, err
+	}
+	list := []interface{}{}
+	for {
+		if _, _, err := s.Kind(); err == EOL {
+			break
+		}
+		val, err := s.decodeInterface()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, val)
+	}
+	return list, s.ListEnd()
+}
@@ -0,0 +1,274 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package rlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+func unhex(s string) []byte {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		panic(err)
+	}
+	return b
+}
+
+type DecodeTestSuite struct {
+	suite.Suite
+}
+
+func (suite *DecodeTestSuite) Test_Decode() {
+	var s string
+	assert.NoError(suite.T(), DecodeBytes(unhex("83646f67"), &s), "Should be no error")
+	assert.Equal(suite.T(), "dog", s, "should be equal")
+
+	var list []string
+	assert.NoError(suite.T(), DecodeBytes(unhex("c88363617483646f67"), &list), "Should be no error")
+	assert.Equal(suite.T(), []string{"cat", "dog"}, list, "should be equal")
+
+	var i uint16
+	assert.NoError(suite.T(), DecodeBytes(unhex("820400"), &i), "Should be no error")
+	assert.EqualValues(suite.T(), 1024, i, "should be equal")
+
+	var b bool
+	assert.NoError(suite.T(), DecodeBytes(unhex("01"), &b), "Should be no error")
+	assert.True(suite.T(), b, "should be true")
+
+	var n *big.Int
+	assert.NoError(suite.T(), DecodeBytes(unhex("90100102030405060708090a0b0c0d0e0f"), &n), "Should be no error")
+	assert.Equal(suite.T(), "100102030405060708090a0b0c0d0e0f", n.Text(16), "should be equal")
+
+	var address common.Address
+	assert.NoError(suite.T(), DecodeBytes(unhex("94"+"11"+strings.Repeat("00", 19)), &address), "Should be no error")
+	assert.Equal(suite.T(), common.Address{0x11}, address, "should be equal")
+
+	var raw []RawValue
+	assert.NoError(suite.T(), DecodeBytes(unhex("c4c1018180"), &raw), "Should be no error")
+	assert.Equal(suite.T(), []RawValue{{0xC1, 0x01}, {0x81, 0x80}}, raw, "should be equal")
+
+	var val interface{}
+	assert.NoError(suite.T(), DecodeBytes(unhex("c7c0c1c0c3c0c1c0"), &val), "Should be no error")
+	empty := []interface{}{}
+	assert.Equal(suite.T(), []interface{}{empty, []interface{}{empty}, []interface{}{empty, []interface{}{empty}}}, val, "should be equal")
+}
+
+func (suite *DecodeTestSuite) Test_Struct() {
+	to := common.Address{0x22}
+	val := &testStruct{
+		Nonce:   1,
+		Value:   big.NewInt(1000),
+		To:      &to,
+		Hash:    common.Hash{0x33},
+		Payload: common.Data{0xab},
+		Names:   []string{"a", "b"},
+	}
+	b, err := EncodeToBytes(val)
+	suite.Require().NoError(err)
+
+	decoded := &testStruct{}
+	assert.NoError(suite.T(), DecodeBytes(b, decoded), "Should be no error")
+	assert.Equal(suite.T(), val, decoded, "should be equal")
+
+	var optional struct {
+		To  *common.Address `rlp:"nil"`
+		Via *common.Address `rlp:"nil"`
+	}
+	optional.Via = &to
+	b, err = EncodeToBytes(&optional)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), "d680"+"94"+"22"+strings.Repeat("00", 19), hex.EncodeToString(b), "should be equal")
+	optional.To = &common.Address{}
+	optional.Via = nil
+	assert.NoError(suite.T(), DecodeBytes(b, &optional), "Should be no error")
+	assert.Nil(suite.T(), optional.To, "should be nil")
+	assert.Equal(suite.T(), &to, optional.Via, "should be equal")
+
+	var short struct{ A, B, C uint64 }
+	assert.True(suite.T(), errors.Is(DecodeBytes(unhex("c20102"), &short), ErrTooFewElements), "should be too few")
+	var long struct{ A uint64 }
+	assert.True(suite.T(), errors.Is(DecodeBytes(unhex("c20102"), &long), ErrTooManyElements), "should be too many")
+	var hash common.Hash
+	assert.True(suite.T(), errors.Is(DecodeBytes(unhex("820102"), &hash), ErrWrongSize), "should be the wrong size")
+}
+
+func (suite *DecodeTestSuite) Test_Canonical() {
+	cases := []struct {
+		hex string
+		val interface{}
+		err error
+	}{
+		{"8105", new([]byte), ErrCanonSize},
+		{"b80100", new([]byte), ErrCanonSize},
+		{"b90005" + "0102030405", new([]byte), ErrCanonSize},
+		{"f800", new([]interface{}), ErrCanonSize},
+		{"00", new(uint64), ErrCanonInt},
+		{"820001", new(uint64), ErrCanonInt},
+		{"820001", new(*big.Int), ErrCanonInt},
+		{"8501020304", new(uint64), ErrValueTooLarge},
+		{"890102030405060708090a", new(uint64), ErrUintOverflow},
+		{"820100", new(uint8), ErrUintOverflow},
+		{"c20102", new(uint64), ErrExpectedString},
+		{"01", new([]uint64), ErrExpectedList},
+		{"c2820102", new([]interface{}), ErrElemTooLarge},
+		{"83010203", new([]byte), nil},
+		{"830102", new([]byte), ErrValueTooLarge},
+		{"0102", new(uint64), ErrMoreThanOneValue},
+		{"", new(uint64), io.EOF},
+	}
+
+	for _, c := range cases {
+		err := DecodeBytes(unhex(c.hex), c.val)
+		assert.Equal(suite.T(), c.err, err, c.hex)
+	}
+
+	assert.Equal(suite.T(), ErrDecodeIntoNil, DecodeBytes(unhex("01"), nil), "should be equal")
+	var i uint64
+	assert.Equal(suite.T(), ErrDecodeIntoNil, DecodeBytes(unhex("01"), i), "should be equal")
+}
+
+func (suite *DecodeTestSuite) Test_Stream() {
+	// a reader without a known size
+	r := io.MultiReader(bytes.NewReader(unhex("c5830102037f")), bytes.NewReader(unhex("83646f67")))
+	s := NewStream(r, 0)
+
+	kind, size, err := s.Kind()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), List, kind, "should be equal")
+	assert.EqualValues(suite.T(), 5, size, "should be equal")
+
+	_, err = s.List()
+	assert.NoError(suite.T(), err, "Should be no error")
+	b, err := s.Bytes()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), []byte{1, 2, 3}, b, "should be equal")
+	assert.Equal(suite.T(), ErrNotAtEOL, s.ListEnd(), "should be equal")
+	i, err := s.Uint64()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), 0x7f, i, "should be equal")
+	_, _, err = s.Kind()
+	assert.Equal(suite.T(), EOL, err, "should be equal")
+	assert.NoError(suite.T(), s.ListEnd(), "Should be no error")
+
+	raw, err := s.Raw()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), unhex("83646f67"), raw, "should be equal")
+
+	_, _, err = s.Kind()
+	assert.Equal(suite.T(), io.EOF, err, "should be equal")
+
+	// a truncated value
+	var str string
+	err = Decode(bytes.NewBufferString("\x83do"), &str)
+	assert.Equal(suite.T(), ErrValueTooLarge, err, "should be equal")
+	err = Decode(io.MultiReader(strings.NewReader("\x83do")), &str)
+	assert.Equal(suite.T(), io.ErrUnexpectedEOF, err, "should be equal")
+	// the declared size of a value is not allocated upfront
+	err = Decode(io.MultiReader(strings.NewReader("\xbf\x7f\xff\xff\xff\xff\xff\xff\xff")), &str)
+	assert.Equal(suite.T(), ErrValueTooLarge, err, "should be equal")
+	long := strings.Repeat("a", readChunkSize+10)
+	b, _ = EncodeToBytes(long)
+	err = Decode(io.MultiReader(bytes.NewReader(b)), &str)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), long, str, "should be equal")
+}
+
+func Test_DecodeTestSuite(t *testing.T) {
+	suite.Run(t, new(DecodeTestSuite))
+}
+
+// FuzzDecode checks that every input which decodes is the canonical encoding
+// of its value
+func FuzzDecode(f *testing.F) {
+	for _, seed := range []string{"80", "7f", "8180", "c0", "c7c0c1c0c3c0c1c0", "c88363617483646f67", "b838" + strings.Repeat("61", 56), "bf7fffffffffffffff"} {
+		f.Add(unhex(seed))
+	}
+
+	f.Fuzz(func(t *testing.T, input []byte) {
+		// an input of unknown size must not make the decoder allocate the
+		// sizes it declares
+		var unsized interface{}
+		Decode(io.MultiReader(bytes.NewReader(input)), &unsized)
+
+		var val interface{}
+		if err := DecodeBytes(input, &val); err != nil {
+			return
+		}
+		b, err := EncodeToBytes(val)
+		if err != nil {
+			t.Fatalf("can't encode %v: %v", val, err)
+		}
+		if !bytes.Equal(b, input) {
+			t.Fatalf("%x decodes to %v, which encodes to %x", input, val, b)
+		}
+	})
+}
+
+// FuzzRoundTrip checks that structs decode to the values they were encoded
+// from
+func FuzzRoundTrip(f *testing.F) {
+	f.Add(uint64(0), []byte{}, "", []byte{})
+	f.Add(uint64(1024), []byte{0x7f}, "dog", []byte{0xff, 0x00})
+
+	f.Fuzz(func(t *testing.T, nonce uint64, payload []byte, name string, value []byte) {
+		val := &testStruct{
+			Nonce:   nonce,
+			Value:   new(big.Int).SetBytes(value),
+			To:      &common.Address{},
+			Payload: common.Data(payload),
+			Names:   []string{name},
+		}
+		copy(val.To[:], payload)
+		copy(val.Hash[:], value)
+
+		b, err := EncodeToBytes(val)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded := &testStruct{}
+		if err := DecodeBytes(b, decoded); err != nil {
+			t.Fatalf("can't decode %x: %v", b, err)
+		}
+		if decoded.Nonce != val.Nonce || decoded.Value.Cmp(val.Value) != 0 || *decoded.To != *val.To ||
+			decoded.Hash != val.Hash || !bytes.Equal(decoded.Payload, val.Payload) || decoded.Names[0] != name {
+			t.Fatalf("%+v decodes to %+v", val, decoded)
+		}
+	})
+}
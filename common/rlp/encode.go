@@ -0,0 +1,244 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package rlp implements the Recursive Length Prefix encoding, which MOAC uses
+// to serialize transactions and blocks.
+//
+// Unsigned integers, *big.Int, strings, byte slices and byte arrays, like
+// common.Address, common.Hash and common.Data, are encoded as strings. Other
+// slices and arrays, and structs, are encoded as lists of their elements or
+// exported fields. A field tagged `rlp:"-"` is skipped. A nil pointer is
+// encoded as an empty value, which a pointer field tagged `rlp:"nil"` decodes
+// back to nil.
+package rlp
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"reflect"
+)
+
+// ErrNegativeBigInt is returned when encoding a negative *big.Int
+var ErrNegativeBigInt = errors.New("rlp: cannot encode negative big.Int")
+
+// Encoder is implemented by types with a custom encoding. EncodeRLP must write
+// exactly one value, which may be a list.
+type Encoder interface {
+	EncodeRLP(w io.Writer) error
+}
+
+// RawValue is an encoded value, which is written and read as it is
+type RawValue []byte
+
+var (
+	encoderType  = reflect.TypeOf((*Encoder)(nil)).Elem()
+	rawValueType = reflect.TypeOf(RawValue{})
+	bigIntType   = reflect.TypeOf(big.Int{})
+)
+
+// Encode writes the encoding of val to w
+func Encode(w io.Writer, val interface{}) error {
+	b, err := EncodeToBytes(val)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// EncodeToBytes returns the encoding of val
+func EncodeToBytes(val interface{}) ([]byte, error) {
+	return appendValue(nil, reflect.ValueOf(val))
+}
+
+func appendValue(b []byte, v reflect.Value) ([]byte, error) {
+	if !v.IsValid() {
+		return append(b, 0xC0), nil
+	}
+
+	t := v.Type()
+	switch {
+	case t == rawValueType:
+		return append(b, v.Bytes()...), nil
+	case t.Implements(encoderType) && (t.Kind() != reflect.Ptr || !v.IsNil()):
+		return appendEncoder(b, v.Interface().(Encoder))
+	case t.Kind() != reflect.Ptr && reflect.PtrTo(t).Implements(encoderType) && v.CanAddr():
+		return appendEncoder(b, v.Addr().Interface().(Encoder))
+	case t == bigIntType:
+		i := v.Interface().(big.Int)
+		return appendBigInt(b, &i)
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		if v.Bool() {
+			return append(b, 0x01), nil
+		}
+		return append(b, 0x80), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return appendUint(b, v.Uint()), nil
+	case reflect.String:
+		return appendString(b, []byte(v.String())), nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return appendString(b, byteSlice(v)), nil
+		}
+		return appendList(b, func(content []byte) ([]byte, error) {
+			var err error
+			for i := 0; i < v.Len() && err == nil; i++ {
+				content, err = appendValue(content, v.Index(i))
+			}
+			return content, err
+		})
+	case reflect.Struct:
+		return appendList(b, func(content []byte) ([]byte, error) {
+			var err error
+			for _, i := range structFields(t) {
+				if content, err = appendValue(content, v.Field(i)); err != nil {
+					return nil, err
+				}
+			}
+			return content, nil
+		})
+	case reflect.Ptr:
+		if v.IsNil() {
+			return appendEmpty(b, t.Elem()), nil
+		}
+		return appendValue(b, v.Elem())
+	case reflect.Interface:
+		return appendValue(b, v.Elem())
+	}
+	return nil, fmt.Errorf("rlp: type %v is not RLP-serializable", t)
+}
+
+func appendEncoder(b []byte, enc Encoder) ([]byte, error) {
+	buf := bytes.NewBuffer(b)
+	if err := enc.EncodeRLP(buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// appendEmpty appends the encoding of a nil pointer to t, which is the empty
+// list for lists and the empty string otherwise
+func appendEmpty(b []byte, t reflect.Type) []byte {
+	switch t.Kind() {
+	case reflect.Struct:
+		if t != bigIntType {
+			return append(b, 0xC0)
+		}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() != reflect.Uint8 {
+			return append(b, 0xC0)
+		}
+	}
+	return append(b, 0x80)
+}
+
+func appendBigInt(b []byte, i *big.Int) ([]byte, error) {
+	if i.Sign() < 0 {
+		return nil, ErrNegativeBigInt
+	}
+	return appendString(b, i.Bytes()), nil
+}
+
+func appendUint(b []byte, i uint64) []byte {
+	if i == 0 {
+		return append(b, 0x80)
+	}
+	if i < 0x80 {
+		return append(b, byte(i))
+	}
+	return appendString(b, uintBytes(i))
+}
+
+func appendString(b []byte, s []byte) []byte {
+	if len(s) == 1 && s[0] < 0x80 {
+		return append(b, s[0])
+	}
+	b = appendHeader(b, 0x80, uint64(len(s)))
+	return append(b, s...)
+}
+
+// appendList appends the list with the content written by fill
+func appendList(b []byte, fill func([]byte) ([]byte, error)) ([]byte, error) {
+	content, err := fill(nil)
+	if err != nil {
+		return nil, err
+	}
+	b = appendHeader(b, 0xC0, uint64(len(content)))
+	return append(b, content...), nil
+}
+
+// appendHeader appends the header of a string, for offset 0x80, or of a list,
+// for offset 0xC0
+func appendHeader(b []byte, offset byte, size uint64) []byte {
+	if size < 56 {
+		return append(b, offset+byte(size))
+	}
+	sizeBytes := uintBytes(size)
+	b = append(b, offset+55+byte(len(sizeBytes)))
+	return append(b, sizeBytes...)
+}
+
+// uintBytes returns i in big endian, without leading zero bytes
+func uintBytes(i uint64) []byte {
+	var b [8]byte
+	n := 8
+	for ; i > 0; i >>= 8 {
+		n--
+		b[n] = byte(i)
+	}
+	return b[n:]
+}
+
+func byteSlice(v reflect.Value) []byte {
+	if v.Kind() == reflect.Slice {
+		return v.Bytes()
+	}
+	b := make([]byte, v.Len())
+	reflect.Copy(reflect.ValueOf(b), v)
+	return b
+}
+
+// structFields returns the indexes of the encoded fields of t
+func structFields(t reflect.Type) []int {
+	fields := make([]int, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("rlp") == "-" {
+			continue
+		}
+		fields = append(fields, i)
+	}
+	return fields
+}
@@ -0,0 +1,146 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package rlp
+
+import (
+	"bytes"
+	"encoding/hex"
+	"io"
+	"math/big"
+	"strings"
+	"testing"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type testStruct struct {
+	Nonce   uint64
+	Value   *big.Int
+	To      *common.Address
+	Hash    common.Hash
+	Payload common.Data
+	Names   []string
+	Skipped string `rlp:"-"`
+	private string
+}
+
+type testEncoder struct{}
+
+func (testEncoder) EncodeRLP(w io.Writer) error {
+	_, err := w.Write([]byte{0xC1, 0x01})
+	return err
+}
+
+type EncodeTestSuite struct {
+	suite.Suite
+}
+
+func (suite *EncodeTestSuite) encode(val interface{}) string {
+	b, err := EncodeToBytes(val)
+	suite.Require().NoError(err)
+	return hex.EncodeToString(b)
+}
+
+func (suite *EncodeTestSuite) Test_Encode() {
+	lorem := "Lorem ipsum dolor sit amet, consectetur adipisicing elit"
+	big1024, _ := new(big.Int).SetString("100102030405060708090a0b0c0d0e0f", 16)
+
+	cases := []struct {
+		val interface{}
+		hex string
+	}{
+		{"dog", "83646f67"},
+		{[]string{"cat", "dog"}, "c88363617483646f67"},
+		{"", "80"},
+		{[]string{}, "c0"},
+		{uint64(0), "80"},
+		{uint8(15), "0f"},
+		{uint16(1024), "820400"},
+		{[]interface{}{[]interface{}{}, []interface{}{[]interface{}{}}, []interface{}{[]interface{}{}, []interface{}{[]interface{}{}}}}, "c7c0c1c0c3c0c1c0"},
+		{lorem, "b838" + hex.EncodeToString([]byte(lorem))},
+		{[]byte{0x7f}, "7f"},
+		{[]byte{0x80}, "8180"},
+		{true, "01"},
+		{false, "80"},
+		{big.NewInt(0), "80"},
+		{big.NewInt(127), "7f"},
+		{big1024, "90100102030405060708090a0b0c0d0e0f"},
+		{(*big.Int)(nil), "80"},
+		{(*testStruct)(nil), "c0"},
+		{common.Data{1, 2, 3}, "83010203"},
+		{common.Address{0x11}, "94" + "11" + strings.Repeat("00", 19)},
+		{RawValue{0xC1, 0x01}, "c101"},
+		{testEncoder{}, "c101"},
+		{strings.Repeat("a", 1024), "b90400" + strings.Repeat("61", 1024)},
+	}
+
+	for _, c := range cases {
+		assert.Equal(suite.T(), c.hex, suite.encode(c.val), "should be equal")
+	}
+}
+
+func (suite *EncodeTestSuite) Test_Struct() {
+	to := common.Address{0x22}
+	val := &testStruct{
+		Nonce:   1,
+		Value:   big.NewInt(1000),
+		To:      &to,
+		Payload: common.Data{0xab},
+		Names:   []string{"a"},
+		Skipped: "skipped",
+		private: "private",
+	}
+	expected := "f8" + "3e" + "01" + "8203e8" +
+		"94" + "22" + strings.Repeat("00", 19) +
+		"a0" + strings.Repeat("00", 32) +
+		"81ab" + "c161"
+	assert.Equal(suite.T(), expected, suite.encode(val), "should be equal")
+
+	buf := &bytes.Buffer{}
+	assert.NoError(suite.T(), Encode(buf, val), "Should be no error")
+	assert.Equal(suite.T(), expected, hex.EncodeToString(buf.Bytes()), "should be equal")
+}
+
+func (suite *EncodeTestSuite) Test_Errors() {
+	_, err := EncodeToBytes(big.NewInt(-1))
+	assert.Equal(suite.T(), ErrNegativeBigInt, err, "should be equal")
+
+	_, err = EncodeToBytes(int64(1))
+	assert.Error(suite.T(), err, "signed integers aren't supported")
+
+	_, err = EncodeToBytes(map[string]string{})
+	assert.Error(suite.T(), err, "maps aren't supported")
+}
+
+func Test_EncodeTestSuite(t *testing.T) {
+	suite.Run(t, new(EncodeTestSuite))
+}
@@ -91,6 +91,8 @@ type TransactionRequest struct {
 	GasPrice string  `json:"gasprice"`
 	Value    string  `json:"value"`
 	Data     Data    `json:"data"`
+	// Nonce is filled by the node, or by a transactor, when empty
+	Nonce string `json:"nonce,omitempty"`
 }
 
 func (tx *TransactionRequest) String() string {
@@ -98,6 +100,29 @@ func (tx *TransactionRequest) String() string {
 	return string(jsonBytes)
 }
 
+// MarshalJSON encodes the request as the node expects it, with hex data, and
+// without the empty fields. A zero To is left out, for contract creations.
+func (tx TransactionRequest) MarshalJSON() ([]byte, error) {
+	m := map[string]string{"from": tx.From.String()}
+	if tx.To != (Address{}) {
+		m["to"] = tx.To.String()
+	}
+	if len(tx.Data) > 0 {
+		m["data"] = tx.Data.String()
+	}
+	for key, value := range map[string]string{
+		"gas":      tx.Gas,
+		"gasPrice": tx.GasPrice,
+		"value":    tx.Value,
+		"nonce":    tx.Nonce,
+	} {
+		if value != "" {
+			m[key] = value
+		}
+	}
+	return json.Marshal(m)
+}
+
 func (tx *TransactionRequest) ToMap() *map[string]string {
 	m := make(map[string]string)
 	m["from"] = tx.From.String()
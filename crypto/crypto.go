@@ -0,0 +1,148 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package crypto provides the secp256k1 keys and signatures, and the Keccak
+// hashing, used by MOAC accounts.
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"encoding/hex"
+	"errors"
+	"math/big"
+	"strings"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	"github.com/tonnerre/golang-go.crypto/sha3"
+)
+
+var (
+	// ErrInvalidPrivateKey is returned for private keys which are not a
+	// scalar of the secp256k1 curve
+	ErrInvalidPrivateKey = errors.New("Invalid private key")
+	// ErrInvalidPublicKey is returned for bytes which are not an uncompressed
+	// secp256k1 public key
+	ErrInvalidPublicKey = errors.New("Invalid public key")
+)
+
+var secp256k1N = secp256k1.S256().N
+
+// S256 returns the secp256k1 curve
+func S256() *secp256k1.KoblitzCurve {
+	return secp256k1.S256()
+}
+
+// Keccak256 returns the Keccak-256 hash of the concatenated data
+func Keccak256(data ...[]byte) []byte {
+	d := sha3.NewKeccak256()
+	for _, b := range data {
+		d.Write(b)
+	}
+	return d.Sum(nil)
+}
+
+// Keccak256Hash is like Keccak256 but returns a common.Hash
+func Keccak256Hash(data ...[]byte) common.Hash {
+	return common.NewHash(Keccak256(data...))
+}
+
+// GenerateKey creates a random private key
+func GenerateKey() (*ecdsa.PrivateKey, error) {
+	key, err := secp256k1.GeneratePrivateKey()
+	if err != nil {
+		return nil, err
+	}
+	return key.ToECDSA(), nil
+}
+
+// ToECDSA returns the private key of the 32 bytes d
+func ToECDSA(d []byte) (*ecdsa.PrivateKey, error) {
+	if len(d) != 32 {
+		return nil, ErrInvalidPrivateKey
+	}
+	k := new(big.Int).SetBytes(d)
+	if k.Sign() == 0 || k.Cmp(secp256k1N) >= 0 {
+		return nil, ErrInvalidPrivateKey
+	}
+	return secp256k1.PrivKeyFromBytes(d).ToECDSA(), nil
+}
+
+// HexToECDSA returns the private key of a hex string, with or without 0x
+func HexToECDSA(s string) (*ecdsa.PrivateKey, error) {
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	d, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidPrivateKey
+	}
+	return ToECDSA(d)
+}
+
+// FromECDSA returns the 32 bytes of a private key
+func FromECDSA(key *ecdsa.PrivateKey) []byte {
+	if key == nil {
+		return nil
+	}
+	return key.D.FillBytes(make([]byte, 32))
+}
+
+// FromECDSAPub returns the 65 bytes of an uncompressed public key
+func FromECDSAPub(pub *ecdsa.PublicKey) []byte {
+	if pub == nil || pub.X == nil || pub.Y == nil {
+		return nil
+	}
+	b := make([]byte, 65)
+	b[0] = 4
+	pub.X.FillBytes(b[1:33])
+	pub.Y.FillBytes(b[33:])
+	return b
+}
+
+// UnmarshalPubkey parses the 65 bytes of an uncompressed public key
+func UnmarshalPubkey(pub []byte) (*ecdsa.PublicKey, error) {
+	if len(pub) != 65 || pub[0] != 4 {
+		return nil, ErrInvalidPublicKey
+	}
+	key, err := secp256k1.ParsePubKey(pub)
+	if err != nil {
+		return nil, ErrInvalidPublicKey
+	}
+	return key.ToECDSA(), nil
+}
+
+// PubkeyToAddress returns the address of a public key, which is the last 20
+// bytes of the Keccak-256 hash of its coordinates
+func PubkeyToAddress(pub ecdsa.PublicKey) common.Address {
+	b := FromECDSAPub(&pub)
+	var address common.Address
+	copy(address[:], Keccak256(b[1:])[12:])
+	return address
+}
@@ -0,0 +1,113 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package crypto
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// testKey is the private key of the EIP-155 example
+const (
+	testKey     = "4646464646464646464646464646464646464646464646464646464646464646"
+	testAddress = "0x9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f"
+)
+
+type CryptoTestSuite struct {
+	suite.Suite
+}
+
+func (suite *CryptoTestSuite) Test_Keccak256() {
+	assert.Equal(suite.T(), "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		hex.EncodeToString(Keccak256()), "should be equal")
+	hash := Keccak256Hash([]byte("ab"), []byte("c"))
+	assert.Equal(suite.T(), "0x4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+		hash.String(), "should be equal")
+}
+
+func (suite *CryptoTestSuite) Test_Keys() {
+	key, err := HexToECDSA("0x" + testKey)
+	suite.Require().NoError(err)
+	address := PubkeyToAddress(key.PublicKey)
+	assert.Equal(suite.T(), testAddress, address.String(), "should be equal")
+	assert.Equal(suite.T(), testKey, hex.EncodeToString(FromECDSA(key)), "should be equal")
+
+	pub, err := UnmarshalPubkey(FromECDSAPub(&key.PublicKey))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), address, PubkeyToAddress(*pub), "should be equal")
+
+	_, err = HexToECDSA("0x00")
+	assert.Equal(suite.T(), ErrInvalidPrivateKey, err, "should be equal")
+	_, err = ToECDSA(make([]byte, 32))
+	assert.Equal(suite.T(), ErrInvalidPrivateKey, err, "should be equal")
+	_, err = ToECDSA(common.HexToBytes("0xfffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141"))
+	assert.Equal(suite.T(), ErrInvalidPrivateKey, err, "should be equal")
+	_, err = HexToECDSA("zz")
+	assert.Equal(suite.T(), ErrInvalidPrivateKey, err, "should be equal")
+	_, err = UnmarshalPubkey([]byte{4, 1})
+	assert.Equal(suite.T(), ErrInvalidPublicKey, err, "should be equal")
+}
+
+func (suite *CryptoTestSuite) Test_Sign() {
+	key, err := GenerateKey()
+	suite.Require().NoError(err)
+	hash := Keccak256([]byte("message"))
+
+	sig, err := Sign(hash, key)
+	suite.Require().NoError(err)
+	assert.Len(suite.T(), sig, SignatureLength, "should be equal")
+	again, _ := Sign(hash, key)
+	assert.Equal(suite.T(), sig, again, "should be deterministic")
+
+	pub, err := Ecrecover(hash, sig)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), FromECDSAPub(&key.PublicKey), pub, "should be equal")
+
+	other := Keccak256([]byte("other"))
+	recovered, err := SigToPub(other, sig)
+	if err == nil {
+		assert.NotEqual(suite.T(), key.PublicKey, *recovered, "should be another key")
+	}
+
+	sig[64] = 2
+	_, err = SigToPub(hash, sig)
+	assert.Equal(suite.T(), ErrInvalidSignature, err, "should be equal")
+
+	_, err = Sign(hash[:31], key)
+	assert.Error(suite.T(), err, "should require 32 bytes")
+}
+
+func Test_CryptoTestSuite(t *testing.T) {
+	suite.Run(t, new(CryptoTestSuite))
+}
@@ -0,0 +1,100 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package crypto
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/decred/dcrd/dcrec/secp256k1/v4"
+	secp256k1ecdsa "github.com/decred/dcrd/dcrec/secp256k1/v4/ecdsa"
+)
+
+// SignatureLength is the length of a signature: R, S and the recovery id V
+const SignatureLength = 65
+
+// ErrInvalidSignature is returned for signatures which don't recover a key
+var ErrInvalidSignature = errors.New("Invalid signature")
+
+var secp256k1HalfN = new(big.Int).Rsh(secp256k1N, 1)
+
+// Sign signs a 32 bytes hash with a private key, returning the signature as
+// R || S || V, with V being the recovery id, 0 or 1. Signatures are
+// deterministic (RFC 6979), and S is in the lower half of the curve order.
+func Sign(hash []byte, key *ecdsa.PrivateKey) ([]byte, error) {
+	if len(hash) != 32 {
+		return nil, errors.New("Hash is required to be exactly 32 bytes")
+	}
+	d := FromECDSA(key)
+	if _, err := ToECDSA(d); err != nil {
+		return nil, err
+	}
+
+	compact := secp256k1ecdsa.SignCompact(secp256k1.PrivKeyFromBytes(d), hash, false)
+	sig := make([]byte, SignatureLength)
+	copy(sig, compact[1:])
+	sig[64] = compact[0] - 27
+	return sig, nil
+}
+
+// Ecrecover returns the uncompressed public key which created a signature
+func Ecrecover(hash, sig []byte) ([]byte, error) {
+	pub, err := SigToPub(hash, sig)
+	if err != nil {
+		return nil, err
+	}
+	return FromECDSAPub(pub), nil
+}
+
+// SigToPub returns the public key which created a signature
+func SigToPub(hash, sig []byte) (*ecdsa.PublicKey, error) {
+	if len(sig) != SignatureLength || sig[64] > 1 {
+		return nil, ErrInvalidSignature
+	}
+	compact := make([]byte, SignatureLength)
+	compact[0] = sig[64] + 27
+	copy(compact[1:], sig[:64])
+
+	pub, _, err := secp256k1ecdsa.RecoverCompact(compact, hash)
+	if err != nil {
+		return nil, ErrInvalidSignature
+	}
+	return pub.ToECDSA(), nil
+}
+
+// ValidateSignatureValues reports whether r and s are valid, with s in the
+// lower half of the curve order, and v is a recovery id
+func ValidateSignatureValues(v byte, r, s *big.Int) bool {
+	if r == nil || s == nil || r.Sign() <= 0 || s.Sign() <= 0 || v > 1 {
+		return false
+	}
+	return r.Cmp(secp256k1N) < 0 && s.Cmp(secp256k1HalfN) <= 0
+}
@@ -34,7 +34,6 @@ import (
 	"fmt"
 
 	Chain3 "github.com/caivega/chain3go/chain3"
-	"github.com/caivega/chain3go/common"
 	"github.com/caivega/chain3go/provider"
 	"github.com/caivega/chain3go/rpc"
 )
@@ -71,10 +70,9 @@ func main() {
 
 	if filterCh := filter.Watch(); filterCh != nil {
 		for {
-			log, err := filterCh.Next()
+			blockHash, err := filterCh.NextHash()
 			if err == nil {
-				blockHash := log.(string)
-				block, err := mc.GetBlockByHash(common.StringToHash(blockHash), false)
+				block, err := mc.GetBlockByHash(blockHash, false)
 				if err != nil {
 					fmt.Println("error", err)
 				}
@@ -69,9 +69,9 @@ func main() {
 
 	if filterCh := filter.Watch(); filterCh != nil {
 		for {
-			log, err := filterCh.Next()
+			hash, err := filterCh.NextHash()
 			if err == nil {
-				fmt.Printf("Block: %v\n", log)
+				fmt.Printf("Transaction: %s\n", hash.String())
 				filterCh.Close()
 				chain3Api.Mc.UninstallFilter(filter)
 			} else {
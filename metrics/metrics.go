@@ -0,0 +1,254 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package metrics collects statistics about the JSON-RPC calls of a client
+// and exposes them in the Prometheus text format.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+)
+
+// DefaultBuckets are the upper bounds, in seconds, of the latency histograms
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// Histogram counts observations in buckets
+type Histogram struct {
+	// Buckets are the upper bounds of the buckets, in increasing order
+	Buckets []float64
+	// Counts holds the number of observations lower or equal to each bound
+	Counts []uint64
+	Count  uint64
+	Sum    float64
+}
+
+func (histogram *Histogram) observe(value float64) {
+	for i, bound := range histogram.Buckets {
+		if value <= bound {
+			histogram.Counts[i]++
+		}
+	}
+	histogram.Count++
+	histogram.Sum += value
+}
+
+// MethodStats are the statistics of a method
+type MethodStats struct {
+	Requests uint64
+	// Failures counts the requests which got no response
+	Failures uint64
+	// ErrorCodes counts the JSON-RPC errors returned by the node, by code
+	ErrorCodes map[int64]uint64
+	InFlight   int64
+	// Latency is in seconds
+	Latency Histogram
+}
+
+// Collector collects statistics per method. Its Observe method can be set as
+// the hook of a chain3.RequestManager, or it can be used as a provider
+// middleware.
+type Collector struct {
+	buckets []float64
+
+	mu      sync.Mutex
+	methods map[string]*MethodStats
+}
+
+// NewCollector creates a collector with the given latency buckets, in
+// seconds. Defaults to DefaultBuckets.
+func NewCollector(buckets ...float64) *Collector {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	return &Collector{buckets: sorted, methods: make(map[string]*MethodStats)}
+}
+
+// Observe records that request is sent and returns the function recording its
+// completion.
+func (collector *Collector) Observe(request rpc.Request) func(rpc.Response, error) {
+	method, _ := request.Get("method").(string)
+	start := time.Now()
+
+	collector.mu.Lock()
+	stats := collector.stats(method)
+	stats.Requests++
+	stats.InFlight++
+	collector.mu.Unlock()
+
+	return func(response rpc.Response, err error) {
+		elapsed := time.Since(start).Seconds()
+		if err == nil && response != nil {
+			err = response.Error()
+		}
+
+		collector.mu.Lock()
+		defer collector.mu.Unlock()
+		stats.InFlight--
+		stats.Latency.observe(elapsed)
+
+		var rpcErr *rpc.JSONRPCError
+		if errors.As(err, &rpcErr) {
+			stats.ErrorCodes[rpcErr.Code]++
+		} else if err != nil {
+			stats.Failures++
+		}
+	}
+}
+
+// Middleware returns a provider middleware observing the requests
+func (collector *Collector) Middleware() provider.Middleware {
+	return func(next provider.Handler) provider.Handler {
+		return func(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+			done := collector.Observe(request)
+			response, err := next(ctx, request)
+			done(response, err)
+			return response, err
+		}
+	}
+}
+
+// Snapshot returns a copy of the statistics, by method
+func (collector *Collector) Snapshot() map[string]MethodStats {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+
+	snapshot := make(map[string]MethodStats, len(collector.methods))
+	for method, stats := range collector.methods {
+		copied := *stats
+		copied.ErrorCodes = make(map[int64]uint64, len(stats.ErrorCodes))
+		for code, count := range stats.ErrorCodes {
+			copied.ErrorCodes[code] = count
+		}
+		copied.Latency.Counts = append([]uint64(nil), stats.Latency.Counts...)
+		snapshot[method] = copied
+	}
+	return snapshot
+}
+
+// ServeHTTP writes the statistics in the Prometheus text format
+func (collector *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	collector.WritePrometheus(w)
+}
+
+// WritePrometheus writes the statistics to w in the Prometheus text format
+func (collector *Collector) WritePrometheus(w io.Writer) error {
+	snapshot := collector.Snapshot()
+	methods := make([]string, 0, len(snapshot))
+	for method := range snapshot {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+
+	p := &printer{w: w}
+	p.header("chain3_rpc_requests_total", "counter", "Number of JSON-RPC requests sent.")
+	for _, method := range methods {
+		p.printf("chain3_rpc_requests_total{method=%q} %d\n", method, snapshot[method].Requests)
+	}
+	p.header("chain3_rpc_failures_total", "counter", "Number of JSON-RPC requests which got no response.")
+	for _, method := range methods {
+		p.printf("chain3_rpc_failures_total{method=%q} %d\n", method, snapshot[method].Failures)
+	}
+	p.header("chain3_rpc_errors_total", "counter", "Number of JSON-RPC errors returned by the node.")
+	for _, method := range methods {
+		codes := make([]int64, 0, len(snapshot[method].ErrorCodes))
+		for code := range snapshot[method].ErrorCodes {
+			codes = append(codes, code)
+		}
+		sort.Slice(codes, func(i, j int) bool { return codes[i] < codes[j] })
+		for _, code := range codes {
+			p.printf("chain3_rpc_errors_total{method=%q,code=\"%d\"} %d\n", method, code, snapshot[method].ErrorCodes[code])
+		}
+	}
+	p.header("chain3_rpc_in_flight", "gauge", "Number of JSON-RPC requests waiting for a response.")
+	for _, method := range methods {
+		p.printf("chain3_rpc_in_flight{method=%q} %d\n", method, snapshot[method].InFlight)
+	}
+	p.header("chain3_rpc_duration_seconds", "histogram", "Duration of JSON-RPC requests.")
+	for _, method := range methods {
+		latency := snapshot[method].Latency
+		for i, bound := range latency.Buckets {
+			p.printf("chain3_rpc_duration_seconds_bucket{method=%q,le=%q} %d\n", method, formatFloat(bound), latency.Counts[i])
+		}
+		p.printf("chain3_rpc_duration_seconds_bucket{method=%q,le=\"+Inf\"} %d\n", method, latency.Count)
+		p.printf("chain3_rpc_duration_seconds_sum{method=%q} %s\n", method, formatFloat(latency.Sum))
+		p.printf("chain3_rpc_duration_seconds_count{method=%q} %d\n", method, latency.Count)
+	}
+	return p.err
+}
+
+// stats returns the statistics of method, creating them if needed. The caller
+// must hold collector.mu.
+func (collector *Collector) stats(method string) *MethodStats {
+	stats, ok := collector.methods[method]
+	if !ok {
+		stats = &MethodStats{
+			ErrorCodes: make(map[int64]uint64),
+			Latency: Histogram{
+				Buckets: collector.buckets,
+				Counts:  make([]uint64, len(collector.buckets)),
+			},
+		}
+		collector.methods[method] = stats
+	}
+	return stats
+}
+
+// printer keeps the first write error
+type printer struct {
+	w   io.Writer
+	err error
+}
+
+func (p *printer) printf(format string, args ...interface{}) {
+	if p.err == nil {
+		_, p.err = fmt.Fprintf(p.w, format, args...)
+	}
+}
+
+func (p *printer) header(name, kind, help string) {
+	p.printf("# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package metrics
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caivega/chain3go/provider"
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MetricsTestSuite struct {
+	suite.Suite
+	collector *Collector
+	rpc       rpc.RPC
+}
+
+func (suite *MetricsTestSuite) Test_Observe() {
+	done := suite.collector.Observe(suite.rpc.NewRequest("mc_call"))
+	assert.EqualValues(suite.T(), 1, suite.collector.Snapshot()["mc_call"].InFlight, "should be equal")
+	done(&rpc.JSONRPCResponse{Version: "2.0", Result: "0x"}, nil)
+
+	done = suite.collector.Observe(suite.rpc.NewRequest("mc_call"))
+	done(&rpc.JSONRPCResponse{Version: "2.0", Err: &rpc.JSONRPCError{Code: -32000, Message: "execution reverted"}}, nil)
+
+	done = suite.collector.Observe(suite.rpc.NewRequest("mc_call"))
+	done(nil, errors.New("connection refused"))
+
+	stats := suite.collector.Snapshot()["mc_call"]
+	assert.EqualValues(suite.T(), 3, stats.Requests, "should be equal")
+	assert.EqualValues(suite.T(), 0, stats.InFlight, "should be equal")
+	assert.EqualValues(suite.T(), 1, stats.Failures, "should be equal")
+	assert.EqualValues(suite.T(), map[int64]uint64{-32000: 1}, stats.ErrorCodes, "should be equal")
+	assert.EqualValues(suite.T(), 3, stats.Latency.Count, "should be equal")
+	assert.EqualValues(suite.T(), 3, stats.Latency.Counts[len(stats.Latency.Counts)-1], "should be equal")
+}
+
+func (suite *MetricsTestSuite) Test_Middleware() {
+	base := provider.Chain(nil, provider.Intercept("mc_blockNumber", func(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+		return &rpc.JSONRPCResponse{Version: "2.0", Identifier: request.ID(), Result: "0x1"}, nil
+	}))
+	client := provider.Chain(base, suite.collector.Middleware())
+
+	_, err := client.Send(suite.rpc.NewRequest("mc_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), 1, suite.collector.Snapshot()["mc_blockNumber"].Requests, "should be equal")
+}
+
+func (suite *MetricsTestSuite) Test_Prometheus() {
+	collector := NewCollector(0.5, 0.1)
+	collector.Observe(suite.rpc.NewRequest("mc_call"))(nil, errors.New("timeout"))
+	collector.Observe(suite.rpc.NewRequest("mc_getLogs"))(&rpc.JSONRPCResponse{Err: &rpc.JSONRPCError{Code: -32005, Message: "too many results"}}, nil)
+	collector.Observe(suite.rpc.NewRequest("mc_getLogs"))
+
+	recorder := httptest.NewRecorder()
+	collector.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := ioutil.ReadAll(recorder.Body)
+	text := string(body)
+
+	assert.Contains(suite.T(), recorder.Header().Get("Content-Type"), "text/plain", "should be equal")
+	assert.Contains(suite.T(), text, "# TYPE chain3_rpc_requests_total counter\n", "should be exposed")
+	assert.Contains(suite.T(), text, `chain3_rpc_requests_total{method="mc_getLogs"} 2`+"\n", "should be exposed")
+	assert.Contains(suite.T(), text, `chain3_rpc_failures_total{method="mc_call"} 1`+"\n", "should be exposed")
+	assert.Contains(suite.T(), text, `chain3_rpc_errors_total{method="mc_getLogs",code="-32005"} 1`+"\n", "should be exposed")
+	assert.Contains(suite.T(), text, `chain3_rpc_in_flight{method="mc_getLogs"} 1`+"\n", "should be exposed")
+	assert.Contains(suite.T(), text, `chain3_rpc_duration_seconds_bucket{method="mc_call",le="0.1"} 1`+"\n", "should be exposed")
+	assert.Contains(suite.T(), text, `chain3_rpc_duration_seconds_bucket{method="mc_call",le="+Inf"} 1`+"\n", "should be exposed")
+	assert.Contains(suite.T(), text, `chain3_rpc_duration_seconds_count{method="mc_call"} 1`+"\n", "should be exposed")
+}
+
+func (suite *MetricsTestSuite) SetupTest() {
+	suite.collector = NewCollector()
+	suite.rpc = rpc.GetDefaultMethod()
+}
+
+func Test_MetricsTestSuite(t *testing.T) {
+	suite.Run(t, new(MetricsTestSuite))
+}
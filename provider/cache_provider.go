@@ -0,0 +1,438 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+// immutableMethods return data identified by a hash, which never changes once
+// the node knows it
+var immutableMethods = map[string]bool{
+	"mc_getBlockByHash":                    true,
+	"mc_getBlockTransactionCountByHash":    true,
+	"mc_getUncleCountByBlockHash":          true,
+	"mc_getTransactionByBlockHashAndIndex": true,
+	"mc_getUncleByBlockHashAndIndex":       true,
+	"mc_getTransactionByHash":              true,
+	"mc_getTransactionReceipt":             true,
+}
+
+// blockMethods map methods returning data at a given block to the index of
+// their block parameter. Their results are only cached with
+// WithConfirmationDepth, as a reorganization changes the block at a number.
+var blockMethods = map[string]int{
+	"mc_getBlockByNumber":                    0,
+	"mc_getBlockTransactionCountByNumber":    0,
+	"mc_getUncleCountByBlockNumber":          0,
+	"mc_getTransactionByBlockNumberAndIndex": 0,
+	"mc_getUncleByBlockNumberAndIndex":       0,
+	"mc_getBalance":                          1,
+	"mc_getCode":                             1,
+	"mc_getTransactionCount":                 1,
+	"mc_call":                                1,
+	"mc_getStorageAt":                        2,
+}
+
+// CacheOption configures a CacheProvider
+type CacheOption func(*CacheProvider)
+
+// WithCacheSize limits the number of cached results. Defaults to 10000.
+func WithCacheSize(maxEntries int) CacheOption {
+	return func(provider *CacheProvider) {
+		provider.cache.maxEntries = maxEntries
+	}
+}
+
+// WithCacheBytes limits the total size of the cached results. Defaults to
+// 64MB.
+func WithCacheBytes(maxBytes int) CacheOption {
+	return func(provider *CacheProvider) {
+		provider.cache.maxBytes = maxBytes
+	}
+}
+
+// WithConfirmationDepth caches the results of queries at an explicit block
+// number once the block is depth blocks below the head of the chain, and
+// considered final. They are not cached by default.
+func WithConfirmationDepth(depth uint64) CacheOption {
+	return func(provider *CacheProvider) {
+		provider.confirmations = depth
+	}
+}
+
+// WithCacheFile loads the cache from path when created, and saves it there on
+// Save and Close.
+func WithCacheFile(path string) CacheOption {
+	return func(provider *CacheProvider) {
+		provider.path = path
+	}
+}
+
+// CacheProvider caches the results of requests for chain data which cannot
+// change: blocks and transactions by hash and receipts of mined transactions.
+// Blocks and state queried at an explicit block number are only cached with
+// WithConfirmationDepth. Queries at latest, pending or earliest, errors and
+// empty results are never cached.
+type CacheProvider struct {
+	provider      Provider
+	path          string
+	confirmations uint64
+
+	mu     sync.Mutex
+	cache  *lru
+	hits   uint64
+	misses uint64
+	head   uint64
+}
+
+// NewCacheProvider wraps provider with a cache. It fails if the cache file
+// cannot be loaded. Without WithConfirmationDepth, only requests by hash are
+// cached: queries at a block number, like mc_getCode or mc_getBlockByNumber,
+// always reach provider.
+func NewCacheProvider(provider Provider, options ...CacheOption) (*CacheProvider, error) {
+	cacheProvider := &CacheProvider{
+		provider: provider,
+		cache:    newLRU(10000, 64<<20),
+	}
+	for _, option := range options {
+		option(cacheProvider)
+	}
+	if cacheProvider.path != "" {
+		if err := cacheProvider.load(); err != nil {
+			return nil, err
+		}
+	}
+	return cacheProvider, nil
+}
+
+// Unwrap returns the wrapped provider
+func (provider *CacheProvider) Unwrap() Provider {
+	return provider.provider
+}
+
+func (provider *CacheProvider) IsConnected() bool {
+	return provider.provider.IsConnected()
+}
+
+// Send answers request from the cache, or sends it and caches the result
+func (provider *CacheProvider) Send(request rpc.Request) (rpc.Response, error) {
+	return provider.SendContext(context.Background(), request)
+}
+
+// SendContext is like Send but with a context.
+func (provider *CacheProvider) SendContext(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+	key, number, cacheable := provider.cacheKey(request)
+	if cacheable {
+		if response, ok := provider.lookup(key, request); ok {
+			return response, nil
+		}
+	}
+
+	response, err := SendContext(ctx, provider.provider, request)
+	if err == nil && cacheable && provider.confirmed(ctx, number) {
+		provider.store(key, response)
+	}
+	return response, err
+}
+
+// SendBatch answers what it can of requests from the cache and sends the rest
+// in one round trip if the wrapped provider supports it, or one after the
+// other otherwise.
+func (provider *CacheProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	return provider.SendBatchContext(context.Background(), requests)
+}
+
+// SendBatchContext is like SendBatch but with a context.
+func (provider *CacheProvider) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	responses := make([]rpc.Response, len(requests))
+	keys := make([]string, len(requests))
+	numbers := make([]int64, len(requests))
+	missing := []int{}
+	for i, request := range requests {
+		key, number, cacheable := provider.cacheKey(request)
+		if cacheable {
+			keys[i] = key
+			numbers[i] = number
+			if response, ok := provider.lookup(key, request); ok {
+				responses[i] = response
+				continue
+			}
+		}
+		missing = append(missing, i)
+	}
+	if len(missing) == 0 {
+		return responses, nil
+	}
+
+	batchProvider, ok := provider.provider.(BatchProvider)
+	if !ok {
+		for _, i := range missing {
+			response, err := SendContext(ctx, provider.provider, requests[i])
+			if err != nil {
+				return nil, err
+			}
+			responses[i] = response
+		}
+	} else {
+		batch := make([]rpc.Request, len(missing))
+		for j, i := range missing {
+			batch[j] = requests[i]
+		}
+		fetched, err := batchProvider.SendBatchContext(ctx, batch)
+		if err != nil {
+			return nil, err
+		}
+		for j, i := range missing {
+			responses[i] = fetched[j]
+		}
+	}
+
+	for _, i := range missing {
+		if keys[i] != "" && responses[i] != nil && provider.confirmed(ctx, numbers[i]) {
+			provider.store(keys[i], responses[i])
+		}
+	}
+	return responses, nil
+}
+
+func (provider *CacheProvider) GetRPCMethod() rpc.RPC {
+	return provider.provider.GetRPCMethod()
+}
+
+// Stats returns the number of requests answered from the cache and the number
+// of cacheable requests which were not
+func (provider *CacheProvider) Stats() (hits, misses uint64) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	return provider.hits, provider.misses
+}
+
+// Len returns the number of cached results
+func (provider *CacheProvider) Len() int {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	return provider.cache.len()
+}
+
+// Save writes the cache to its file, if any
+func (provider *CacheProvider) Save() error {
+	if provider.path == "" {
+		return nil
+	}
+
+	file, err := ioutil.TempFile(filepath.Dir(provider.path), filepath.Base(provider.path)+".tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	provider.mu.Lock()
+	provider.cache.each(func(key string, value []byte) {
+		if err == nil {
+			err = encoder.Encode(&cacheRecord{Key: key, Value: value})
+		}
+	})
+	provider.mu.Unlock()
+	if err == nil {
+		err = writer.Flush()
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), provider.path)
+}
+
+// Close saves the cache to its file, if any
+func (provider *CacheProvider) Close() error {
+	return provider.Save()
+}
+
+// cacheRecord is a line of a cache file
+type cacheRecord struct {
+	Key   string          `json:"key"`
+	Value json.RawMessage `json:"value"`
+}
+
+func (provider *CacheProvider) load() error {
+	file, err := os.Open(provider.path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(bufio.NewReader(file))
+	for decoder.More() {
+		record := cacheRecord{}
+		if err := decoder.Decode(&record); err != nil {
+			return err
+		}
+		provider.cache.add(record.Key, record.Value)
+	}
+	return nil
+}
+
+func (provider *CacheProvider) lookup(key string, request rpc.Request) (rpc.Response, bool) {
+	provider.mu.Lock()
+	result, ok := provider.cache.get(key)
+	if ok {
+		provider.hits++
+	} else {
+		provider.misses++
+	}
+	provider.mu.Unlock()
+	if !ok {
+		return nil, false
+	}
+
+	data, err := json.Marshal(&cachedResponse{Version: "2.0", ID: request.ID(), Result: result})
+	if err != nil {
+		return nil, false
+	}
+	response := provider.provider.GetRPCMethod().NewResponse(data)
+	return response, response != nil
+}
+
+func (provider *CacheProvider) store(key string, response rpc.Response) {
+	if response.Error() != nil {
+		return
+	}
+	result := response.Get("result")
+	if !isFinal(result) {
+		return
+	}
+	data, err := json.Marshal(result)
+	if err != nil {
+		return
+	}
+
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	provider.cache.add(key, data)
+}
+
+type cachedResponse struct {
+	Version string          `json:"jsonrpc"`
+	ID      uint64          `json:"id"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// cacheKey returns the key of request in the cache, if its result cannot
+// change, and the block number it was queried at, or -1 for a query by hash.
+func (provider *CacheProvider) cacheKey(request rpc.Request) (string, int64, bool) {
+	method, _ := request.Get("method").(string)
+	params, _ := request.Get("params").([]interface{})
+
+	number := int64(-1)
+	if !immutableMethods[method] {
+		index, ok := blockMethods[method]
+		if !ok || index >= len(params) || provider.confirmations == 0 {
+			return "", 0, false
+		}
+		// Only explicit block numbers are fixed.
+		block, ok := params[index].(string)
+		if !ok || !strings.HasPrefix(block, "0x") {
+			return "", 0, false
+		}
+		n, err := strconv.ParseInt(block[2:], 16, 64)
+		if err != nil {
+			return "", 0, false
+		}
+		number = n
+	}
+
+	encoded, err := json.Marshal(params)
+	if err != nil {
+		return "", 0, false
+	}
+	return method + string(encoded), number, true
+}
+
+// confirmed reports whether the result of a query at block number may be
+// stored, asking the head of the chain when the block may not be deep enough.
+func (provider *CacheProvider) confirmed(ctx context.Context, number int64) bool {
+	if number < 0 {
+		return true
+	}
+	provider.mu.Lock()
+	head := provider.head
+	provider.mu.Unlock()
+	if uint64(number)+provider.confirmations <= head {
+		return true
+	}
+
+	response, err := SendContext(ctx, provider.provider, provider.GetRPCMethod().NewRequest("mc_blockNumber"))
+	if err != nil || response.Error() != nil {
+		return false
+	}
+	result, _ := response.Get("result").(string)
+	head, err = strconv.ParseUint(strings.TrimPrefix(result, "0x"), 16, 64)
+	if err != nil {
+		return false
+	}
+
+	provider.mu.Lock()
+	if head > provider.head {
+		provider.head = head
+	}
+	provider.mu.Unlock()
+	return uint64(number)+provider.confirmations <= head
+}
+
+// isFinal reports whether result may be cached: unknown objects are null and
+// pending transactions have no block hash yet.
+func isFinal(result interface{}) bool {
+	if result == nil {
+		return false
+	}
+	if object, ok := result.(map[string]interface{}); ok {
+		if blockHash, ok := object["blockHash"]; ok && blockHash == nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,248 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type CacheProviderTestSuite struct {
+	suite.Suite
+	server *httptest.Server
+	dir    string
+	mu     sync.Mutex
+	calls  int
+}
+
+func (suite *CacheProviderTestSuite) newProvider(options ...CacheOption) *CacheProvider {
+	provider, err := NewCacheProvider(NewHTTPProvider(suite.server.URL, nil), options...)
+	suite.Require().NoError(err)
+	return provider
+}
+
+func (suite *CacheProviderTestSuite) send(provider Provider, method string, params ...interface{}) rpc.Response {
+	request := provider.GetRPCMethod().NewRequest(method)
+	request.Set("params", params)
+	resp, err := provider.Send(request)
+	suite.Require().NoError(err)
+	assert.EqualValues(suite.T(), request.ID(), resp.ID(), "should be equal")
+	return resp
+}
+
+func (suite *CacheProviderTestSuite) callCount() int {
+	suite.mu.Lock()
+	defer suite.mu.Unlock()
+	return suite.calls
+}
+
+func (suite *CacheProviderTestSuite) Test_Immutable() {
+	provider := suite.newProvider()
+	for i := 0; i < 3; i++ {
+		resp := suite.send(provider, "mc_getBlockByHash", "0xabc", false)
+		assert.EqualValues(suite.T(), "0xabc", resp.Get("result").(map[string]interface{})["hash"], "should be equal")
+	}
+	assert.EqualValues(suite.T(), 1, suite.callCount(), "should be equal")
+
+	suite.send(provider, "mc_getBlockByHash", "0xabc", true)
+	assert.EqualValues(suite.T(), 2, suite.callCount(), "should be keyed by params")
+
+	hits, misses := provider.Stats()
+	assert.EqualValues(suite.T(), 2, hits, "should be equal")
+	assert.EqualValues(suite.T(), 2, misses, "should be equal")
+}
+
+func (suite *CacheProviderTestSuite) Test_BlockParameter() {
+	provider := suite.newProvider()
+	for _, block := range []string{"latest", "pending", "earliest", "latest"} {
+		suite.send(provider, "mc_getCode", "0x01", block)
+	}
+	suite.send(provider, "mc_getBalance", "0x01")
+	assert.EqualValues(suite.T(), 5, suite.callCount(), "should be equal")
+
+	// a reorganization may change the block at a number
+	suite.send(provider, "mc_getCode", "0x01", "0x1")
+	suite.send(provider, "mc_getCode", "0x01", "0x1")
+	suite.send(provider, "mc_getBlockByNumber", "0x1", false)
+	suite.send(provider, "mc_getBlockByNumber", "0x1", false)
+	assert.EqualValues(suite.T(), 9, suite.callCount(), "should be equal")
+	assert.EqualValues(suite.T(), 0, provider.Len(), "should be equal")
+}
+
+func (suite *CacheProviderTestSuite) Test_ConfirmationDepth() {
+	// the head is at 0x10
+	provider := suite.newProvider(WithConfirmationDepth(6))
+	suite.send(provider, "mc_getBlockByNumber", "0x10", false)
+	suite.send(provider, "mc_getBlockByNumber", "0x10", false)
+	assert.EqualValues(suite.T(), 4, suite.callCount(), "should not cache recent blocks")
+
+	suite.send(provider, "mc_getBlockByNumber", "0xa", false)
+	suite.send(provider, "mc_getBlockByNumber", "0xa", false)
+	suite.send(provider, "mc_getCode", "0x01", "0x2")
+	suite.send(provider, "mc_getCode", "0x01", "0x2")
+	assert.EqualValues(suite.T(), 6, suite.callCount(), "should cache confirmed blocks")
+	assert.EqualValues(suite.T(), 2, provider.Len(), "should be equal")
+}
+
+func (suite *CacheProviderTestSuite) Test_NotFinal() {
+	provider := suite.newProvider()
+	for i := 0; i < 2; i++ {
+		suite.send(provider, "mc_getTransactionByHash", "0xpending")
+		suite.send(provider, "mc_getTransactionReceipt", "0xunknown")
+		suite.send(provider, "mc_blockNumber")
+	}
+	assert.EqualValues(suite.T(), 6, suite.callCount(), "should be equal")
+	assert.EqualValues(suite.T(), 0, provider.Len(), "should be equal")
+}
+
+func (suite *CacheProviderTestSuite) Test_Eviction() {
+	provider := suite.newProvider(WithCacheSize(2))
+	suite.send(provider, "mc_getTransactionByHash", "0x1")
+	suite.send(provider, "mc_getTransactionByHash", "0x2")
+	suite.send(provider, "mc_getTransactionByHash", "0x1")
+	suite.send(provider, "mc_getTransactionByHash", "0x3")
+	assert.EqualValues(suite.T(), 2, provider.Len(), "should be equal")
+	assert.EqualValues(suite.T(), 3, suite.callCount(), "should be equal")
+
+	suite.send(provider, "mc_getTransactionByHash", "0x1")
+	assert.EqualValues(suite.T(), 3, suite.callCount(), "should keep the recently used")
+	suite.send(provider, "mc_getTransactionByHash", "0x2")
+	assert.EqualValues(suite.T(), 4, suite.callCount(), "should evict the least recently used")
+
+	provider = suite.newProvider(WithCacheBytes(100))
+	suite.send(provider, "mc_getTransactionByHash", "0x1")
+	suite.send(provider, "mc_getTransactionByHash", "0x2")
+	assert.EqualValues(suite.T(), 1, provider.Len(), "should be equal")
+}
+
+func (suite *CacheProviderTestSuite) Test_Persistence() {
+	path := filepath.Join(suite.dir, "cache.jsonl")
+	provider := suite.newProvider(WithCacheFile(path))
+	suite.send(provider, "mc_getTransactionReceipt", "0x1")
+	assert.NoError(suite.T(), provider.Close(), "Should be no error")
+
+	provider = suite.newProvider(WithCacheFile(path))
+	resp := suite.send(provider, "mc_getTransactionReceipt", "0x1")
+	assert.EqualValues(suite.T(), "0x1", resp.Get("result").(map[string]interface{})["hash"], "should be equal")
+	assert.EqualValues(suite.T(), 1, suite.callCount(), "should be equal")
+
+	assert.NoError(suite.T(), ioutil.WriteFile(path, []byte("garbage"), 0600))
+	_, err := NewCacheProvider(NewHTTPProvider(suite.server.URL, nil), WithCacheFile(path))
+	assert.Error(suite.T(), err, "should reject a corrupted file")
+}
+
+func (suite *CacheProviderTestSuite) Test_SendBatch() {
+	provider := suite.newProvider()
+	suite.send(provider, "mc_getTransactionByHash", "0x1")
+
+	method := provider.GetRPCMethod()
+	requests := []rpc.Request{method.NewRequest("mc_getTransactionByHash"), method.NewRequest("mc_getTransactionByHash")}
+	requests[0].Set("params", []string{"0x1"})
+	requests[1].Set("params", []string{"0x2"})
+
+	resps, err := provider.SendBatch(requests)
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), resps, 2) {
+		assert.EqualValues(suite.T(), requests[0].ID(), resps[0].ID(), "should be equal")
+		assert.EqualValues(suite.T(), "0x2", resps[1].Get("result").(map[string]interface{})["hash"], "should be equal")
+	}
+	assert.EqualValues(suite.T(), 2, suite.callCount(), "should be equal")
+
+	_, err = provider.SendBatch(requests)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), 2, suite.callCount(), "should be equal")
+}
+
+func (suite *CacheProviderTestSuite) answer(req rpc.JSONRPCRequest) rpc.JSONRPCResponse {
+	suite.mu.Lock()
+	suite.calls++
+	suite.mu.Unlock()
+
+	resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier}
+	hash, _ := req.Params[0].(string)
+	switch {
+	case hash == "0xpending":
+		resp.Result = map[string]interface{}{"hash": hash, "blockHash": nil}
+	case hash == "0xunknown":
+		resp.Result = nil
+	case req.Method == "mc_blockNumber" || req.Method == "mc_getCode" || req.Method == "mc_getBalance":
+		resp.Result = "0x10"
+	default:
+		resp.Result = map[string]interface{}{"hash": hash, "blockHash": "0xbeef"}
+	}
+	return resp
+}
+
+func (suite *CacheProviderTestSuite) SetupTest() {
+	suite.calls = 0
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		reqs := []rpc.JSONRPCRequest{}
+		if err := json.Unmarshal(body, &reqs); err != nil {
+			req := rpc.JSONRPCRequest{}
+			json.Unmarshal(body, &req)
+			req.Params = append(req.Params, nil)
+			jsonBlob, _ := json.Marshal(suite.answer(req))
+			w.Write(jsonBlob)
+			return
+		}
+
+		resps := []rpc.JSONRPCResponse{}
+		for _, req := range reqs {
+			req.Params = append(req.Params, nil)
+			resps = append(resps, suite.answer(req))
+		}
+		jsonBlob, _ := json.Marshal(resps)
+		w.Write(jsonBlob)
+	}))
+
+	dir, err := ioutil.TempDir("", "cache")
+	suite.Require().NoError(err)
+	suite.dir = dir
+}
+
+func (suite *CacheProviderTestSuite) TearDownTest() {
+	suite.server.Close()
+	os.RemoveAll(suite.dir)
+}
+
+func Test_CacheProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(CacheProviderTestSuite))
+}
@@ -30,29 +30,173 @@
 package provider
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"strings"
+	"time"
 
 	"github.com/caivega/chain3go/rpc"
 )
 
 // HTTPProvider provides basic web3 interface
 type HTTPProvider struct {
-	host string
-	rpc  rpc.RPC
+	host    string
+	rpc     rpc.RPC
+	client  *http.Client
+	header  http.Header
+	timeout time.Duration
+	logger  Logger
+}
+
+// HTTPError is returned when the node answers with an error status and no
+// JSON-RPC response, e.g. from a proxy in front of it.
+type HTTPError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (err *HTTPError) Error() string {
+	return fmt.Sprintf("HTTP error %s", err.Status)
+}
+
+// HTTPOption configures an HTTPProvider
+type HTTPOption func(*httpOptions)
+
+type httpOptions struct {
+	method    rpc.RPC
+	client    *http.Client
+	tlsConfig *tls.Config
+	header    http.Header
+	timeout   time.Duration
+	logger    Logger
+}
+
+// WithRPCMethod sets the RPC used to build requests. Defaults to
+// rpc.GetDefaultMethod().
+func WithRPCMethod(method rpc.RPC) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.method = method
+	}
+}
+
+// WithHTTPClient sets the http client used to send requests. Defaults to
+// http.DefaultClient.
+func WithHTTPClient(client *http.Client) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.client = client
+	}
+}
+
+// WithTLSConfig sets the TLS configuration used for https hosts, e.g. to trust
+// a private CA or to present a client certificate.
+func WithTLSConfig(config *tls.Config) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.tlsConfig = config
+	}
+}
+
+// WithHeader adds a header to every request.
+func WithHeader(key, value string) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.header.Add(key, value)
+	}
+}
+
+// WithBearerToken authenticates every request with the given bearer token.
+func WithBearerToken(token string) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.header.Set("Authorization", "Bearer "+token)
+	}
+}
+
+// WithBasicAuth authenticates every request with the given credentials.
+func WithBasicAuth(username, password string) HTTPOption {
+	return func(opts *httpOptions) {
+		credentials := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+		opts.header.Set("Authorization", "Basic "+credentials)
+	}
+}
+
+// WithTimeout bounds the duration of every request, including reading the
+// response body. Zero means no timeout.
+func WithTimeout(timeout time.Duration) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.timeout = timeout
+	}
+}
+
+// WithLogger sets the logger receiving request and response payloads at
+// LogLevelDebug and failures at LogLevelWarn. Payloads of sensitive methods
+// (see IsSensitiveMethod) are redacted. Providers are silent by default.
+func WithLogger(logger Logger) HTTPOption {
+	return func(opts *httpOptions) {
+		opts.logger = logger
+	}
 }
 
 // NewHTTPProvider creates a HTTP provider
 func NewHTTPProvider(host string, method rpc.RPC) Provider {
-	if !strings.HasPrefix(host, "http://") {
+	return NewHTTPProviderWithOptions(host, WithRPCMethod(method))
+}
+
+// NewHTTPProviderWithOptions creates a HTTP provider configured by options.
+// Hosts without a scheme are reached over plain http.
+func NewHTTPProviderWithOptions(host string, options ...HTTPOption) Provider {
+	if !strings.HasPrefix(host, "http://") && !strings.HasPrefix(host, "https://") {
 		host = "http://" + host
 	}
-	if method == nil {
-		method = rpc.GetDefaultMethod()
+
+	opts := &httpOptions{header: make(http.Header)}
+	for _, option := range options {
+		option(opts)
+	}
+	if opts.method == nil {
+		opts.method = rpc.GetDefaultMethod()
+	}
+	if opts.client == nil {
+		opts.client = http.DefaultClient
+	}
+	if opts.logger == nil {
+		opts.logger = nopLogger{}
+	}
+	if opts.tlsConfig != nil {
+		opts.client = withTLSConfig(opts.client, opts.tlsConfig)
+	}
+
+	return &HTTPProvider{
+		host:    host,
+		rpc:     opts.method,
+		client:  opts.client,
+		header:  opts.header,
+		timeout: opts.timeout,
+		logger:  opts.logger,
 	}
-	return &HTTPProvider{host: host, rpc: method}
+}
+
+// withTLSConfig returns a copy of client whose transport uses config.
+func withTLSConfig(client *http.Client, config *tls.Config) *http.Client {
+	var transport *http.Transport
+	switch t := client.Transport.(type) {
+	case nil:
+		transport = http.DefaultTransport.(*http.Transport).Clone()
+	case *http.Transport:
+		transport = t.Clone()
+	default:
+		// A custom RoundTripper is in charge of its own TLS setup.
+		return client
+	}
+	transport.TLSClientConfig = config
+
+	c := *client
+	c.Transport = transport
+	return &c
 }
 
 // IsConnected ...
@@ -67,9 +211,84 @@ func (provider *HTTPProvider) IsConnected() bool {
 
 // Send JSON RPC request through http client
 func (provider *HTTPProvider) Send(request rpc.Request) (response rpc.Response, err error) {
-	contentType := provider.determineContentType()
-	fmt.Println("[send]", request.String())
-	resp, err := http.Post(provider.host, contentType, strings.NewReader(request.String()))
+	return provider.SendContext(context.Background(), request)
+}
+
+// SendContext is like Send but cancels the http request when ctx is done.
+func (provider *HTTPProvider) SendContext(ctx context.Context, request rpc.Request) (response rpc.Response, err error) {
+	method, id := request.Get("method"), request.ID()
+	sensitive := isSensitive(request)
+	payload := []byte(request.String())
+
+	provider.logger.Log(LogLevelDebug, "send", "method", method, "id", id, "payload", logPayload(sensitive, payload))
+	body, err := provider.post(ctx, payload)
+	if err != nil {
+		provider.logger.Log(LogLevelWarn, "request failed", "method", method, "id", id, "error", err)
+		return nil, err
+	}
+
+	provider.logger.Log(LogLevelDebug, "receive", "method", method, "id", id, "payload", logPayload(sensitive, body))
+	response = provider.rpc.NewResponse(body)
+	if response == nil {
+		provider.logger.Log(LogLevelWarn, "malformed response", "method", method, "id", id)
+		err = fmt.Errorf("Malformed response body, %s", string(body))
+	}
+	return response, err
+}
+
+// SendBatch sends JSON RPC requests in a single http request
+func (provider *HTTPProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	return provider.SendBatchContext(context.Background(), requests)
+}
+
+// SendBatchContext is like SendBatch but cancels the http request when ctx is
+// done.
+func (provider *HTTPProvider) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	batch := provider.rpc.EncodeBatch(requests)
+	// A batch containing any sensitive call is redacted as a whole.
+	sensitive := isSensitive(requests...)
+
+	provider.logger.Log(LogLevelDebug, "send batch", "size", len(requests), "payload", logPayload(sensitive, batch))
+	body, err := provider.post(ctx, batch)
+	if err != nil {
+		provider.logger.Log(LogLevelWarn, "batch request failed", "size", len(requests), "error", err)
+		return nil, err
+	}
+
+	provider.logger.Log(LogLevelDebug, "receive batch", "size", len(requests), "payload", logPayload(sensitive, body))
+	responses := provider.rpc.DecodeBatch(body)
+	if responses == nil {
+		provider.logger.Log(LogLevelWarn, "malformed batch response", "size", len(requests))
+		// The node answers a batch it rejects as a whole with a single error.
+		if response := provider.rpc.NewResponse(body); response != nil && response.Error() != nil {
+			return nil, response.Error()
+		}
+		return nil, fmt.Errorf("Malformed response body, %s", string(body))
+	}
+	return orderResponses(requests, responses), nil
+}
+
+func (provider *HTTPProvider) post(ctx context.Context, data []byte) ([]byte, error) {
+	if provider.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, provider.timeout)
+		defer cancel()
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", provider.host, bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	for key, values := range provider.header {
+		req.Header[key] = values
+	}
+	req.Header.Set("Content-Type", provider.determineContentType())
+
+	resp, err := provider.client.Do(req)
 	if err != nil {
 		return nil, err
 	}
@@ -80,12 +299,40 @@ func (provider *HTTPProvider) Send(request rpc.Request) (response rpc.Response,
 		return nil, err
 	}
 
-	response = provider.rpc.NewResponse(body)
-	fmt.Println("[get]", string(body))
-	if response == nil {
-		err = fmt.Errorf("Malformed response body, %s", string(body))
+	// Some nodes report JSON-RPC errors with an error status, which are still
+	// proper responses.
+	if resp.StatusCode/100 != 2 && !isJSONRPCResponse(body) {
+		return nil, &HTTPError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
 	}
-	return response, err
+	return body, nil
+}
+
+// isJSONRPCResponse reports whether body holds JSON-RPC responses rather than
+// any JSON, such as the error object of a proxy: each must have a jsonrpc or
+// id member, and a result or error member.
+func isJSONRPCResponse(body []byte) bool {
+	var responses []map[string]json.RawMessage
+	if err := json.Unmarshal(body, &responses); err != nil {
+		var response map[string]json.RawMessage
+		if err := json.Unmarshal(body, &response); err != nil {
+			return false
+		}
+		responses = append(responses, response)
+	}
+	if len(responses) == 0 {
+		return false
+	}
+
+	for _, response := range responses {
+		_, version := response["jsonrpc"]
+		_, id := response["id"]
+		_, result := response["result"]
+		_, failure := response["error"]
+		if !(version || id) || !(result || failure) {
+			return false
+		}
+	}
+	return true
 }
 
 func (provider *HTTPProvider) GetRPCMethod() rpc.RPC {
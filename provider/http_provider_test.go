@@ -30,10 +30,19 @@
 package provider
 
 import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/caivega/chain3go/rpc"
 	"github.com/stretchr/testify/assert"
@@ -66,6 +75,149 @@ func (suite *HTTPProviderTestSuite) Test_Send() {
 	assert.EqualValues(suite.T(), "ok", resp.Get("result").(string), "should be equal")
 }
 
+func (suite *HTTPProviderTestSuite) Test_SendBatch() {
+	provider := suite.provider.(BatchProvider)
+	method := suite.provider.GetRPCMethod()
+	requests := []rpc.Request{
+		method.NewRequest("test_method1"),
+		method.NewRequest("test_skipped"),
+		method.NewRequest("test_method2"),
+	}
+	resps, err := provider.SendBatch(requests)
+
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), resps, 3) {
+		assert.EqualValues(suite.T(), requests[0].ID(), resps[0].ID(), "should be equal")
+		assert.EqualValues(suite.T(), "ok", resps[0].Get("result").(string), "should be equal")
+		assert.Nil(suite.T(), resps[1], "should be missing")
+		assert.EqualValues(suite.T(), requests[2].ID(), resps[2].ID(), "should be equal")
+	}
+}
+
+func (suite *HTTPProviderTestSuite) Test_SendContext() {
+	provider := suite.provider.(ContextProvider)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := provider.SendContext(ctx, suite.provider.GetRPCMethod().NewRequest("test_slow"))
+	assert.True(suite.T(), errors.Is(err, context.DeadlineExceeded), "should be timed out")
+}
+
+func (suite *HTTPProviderTestSuite) Test_Headers() {
+	provider := NewHTTPProviderWithOptions(suite.server.URL,
+		WithHeader("X-Test", "value"),
+		WithBearerToken("token"))
+	resp, err := provider.Send(provider.GetRPCMethod().NewRequest("test_header"))
+
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "value|Bearer token", resp.Get("result").(string), "should be equal")
+
+	provider = NewHTTPProviderWithOptions(suite.server.URL, WithBasicAuth("user", "pass"))
+	resp, err = provider.Send(provider.GetRPCMethod().NewRequest("test_header"))
+
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "|Basic dXNlcjpwYXNz", resp.Get("result").(string), "should be equal")
+}
+
+func (suite *HTTPProviderTestSuite) Test_Timeout() {
+	provider := NewHTTPProviderWithOptions(suite.server.URL, WithTimeout(10*time.Millisecond))
+
+	_, err := provider.Send(provider.GetRPCMethod().NewRequest("test_slow"))
+	assert.True(suite.T(), errors.Is(err, context.DeadlineExceeded), "should be timed out")
+
+	_, err = provider.Send(provider.GetRPCMethod().NewRequest("test_method"))
+	assert.NoError(suite.T(), err, "Should be no error")
+}
+
+func (suite *HTTPProviderTestSuite) Test_ErrorStatus() {
+	body := ""
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	provider := NewHTTPProvider(server.URL, nil)
+
+	// a JSON-RPC error is a response whatever the status
+	body = `{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}`
+	resp, err := provider.Send(provider.GetRPCMethod().NewRequest("test_method"))
+	suite.Require().NoError(err)
+	assert.True(suite.T(), errors.Is(resp.Error(), rpc.ErrMethodNotFound), "should be the error of the node")
+
+	for _, body = range []string{`{"message":"Forbidden"}`, `[{"message":"Forbidden"}]`, `{"id":1}`, `[]`} {
+		_, err = provider.Send(provider.GetRPCMethod().NewRequest("test_method"))
+		var httpErr *HTTPError
+		if assert.True(suite.T(), errors.As(err, &httpErr), "should be an HTTP error for "+body) {
+			assert.EqualValues(suite.T(), http.StatusForbidden, httpErr.StatusCode, "should be equal")
+		}
+	}
+}
+
+func (suite *HTTPProviderTestSuite) Test_TLS() {
+	server := httptest.NewTLSServer(suite.server.Config.Handler)
+	defer server.Close()
+
+	provider := NewHTTPProviderWithOptions(server.URL)
+	_, err := provider.Send(provider.GetRPCMethod().NewRequest("test_method"))
+	assert.Error(suite.T(), err, "should reject the unknown certificate")
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	provider = NewHTTPProviderWithOptions(server.URL, WithTLSConfig(&tls.Config{RootCAs: pool}))
+	resp, err := provider.Send(provider.GetRPCMethod().NewRequest("test_method"))
+
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "ok", resp.Get("result").(string), "should be equal")
+
+	provider = NewHTTPProviderWithOptions(server.URL, WithHTTPClient(server.Client()))
+	_, err = provider.Send(provider.GetRPCMethod().NewRequest("test_method"))
+	assert.NoError(suite.T(), err, "Should be no error")
+}
+
+func (suite *HTTPProviderTestSuite) Test_Logger() {
+	var mu sync.Mutex
+	entries := []string{}
+	logger := LoggerFunc(func(level LogLevel, msg string, keyvals ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, fmt.Sprint(append([]interface{}{level, msg}, keyvals...)...))
+	})
+	provider := NewHTTPProviderWithOptions(suite.server.URL, WithLogger(logger))
+	method := provider.GetRPCMethod()
+
+	request := method.NewRequest("test_method")
+	request.Set("params", []string{"visible"})
+	_, err := provider.Send(request)
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	request = method.NewRequest("personal_unlockAccount")
+	request.Set("params", []string{"0x01", "secret"})
+	_, err = provider.Send(request)
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	_, err = provider.(BatchProvider).SendBatch([]rpc.Request{method.NewRequest("test_method"), request})
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(suite.T(), entries, 6) {
+		assert.Contains(suite.T(), entries[0], "visible", "should log the payload")
+		for _, entry := range entries[2:] {
+			assert.NotContains(suite.T(), entry, "secret", "should redact the payload")
+			assert.Contains(suite.T(), entry, "[REDACTED]", "should redact the payload")
+		}
+	}
+}
+
+func (suite *HTTPProviderTestSuite) Test_WriterLogger() {
+	var buf bytes.Buffer
+	logger := NewWriterLogger(&buf, LogLevelWarn)
+	logger.Log(LogLevelDebug, "hidden")
+	logger.Log(LogLevelWarn, "request failed", "method", "mc_call", "error", errors.New("boom"))
+
+	assert.EqualValues(suite.T(), "level=WARN msg=\"request failed\" method=\"mc_call\" error=\"boom\"\n", buf.String(), "should be equal")
+}
+
 func (suite *HTTPProviderTestSuite) Test_GetRPCMethod() {
 	provider := suite.provider
 	assert.NotNil(suite.T(), provider.GetRPCMethod(), "should be equal")
@@ -73,10 +225,24 @@ func (suite *HTTPProviderTestSuite) Test_GetRPCMethod() {
 
 func (suite *HTTPProviderTestSuite) SetupTest() {
 	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		decoder := json.NewDecoder(r.Body)
+		body, _ := ioutil.ReadAll(r.Body)
+		reqs := []rpc.JSONRPCRequest{}
+		if err := json.Unmarshal(body, &reqs); err == nil {
+			// Answer batches in reverse order, skipping test_skipped.
+			resps := []rpc.JSONRPCResponse{}
+			for i := len(reqs) - 1; i >= 0; i-- {
+				if reqs[i].Method != "test_skipped" {
+					resps = append(resps, rpc.JSONRPCResponse{Version: "2.0", Identifier: reqs[i].Identifier, Result: "ok"})
+				}
+			}
+			jsonBlob, _ := json.Marshal(resps)
+			w.Write(jsonBlob)
+			return
+		}
+
 		req := rpc.JSONRPCRequest{}
 		resp := rpc.JSONRPCResponse{Version: "2.0"}
-		err := decoder.Decode(&req)
+		err := json.Unmarshal(body, &req)
 		if err != nil {
 			resp.Identifier = 0
 			resp.Result = "error"
@@ -85,6 +251,14 @@ func (suite *HTTPProviderTestSuite) SetupTest() {
 			switch req.Method {
 			case "net_listening":
 				resp.Result = true
+			case "test_header":
+				resp.Result = r.Header.Get("X-Test") + "|" + r.Header.Get("Authorization")
+			case "test_slow":
+				select {
+				case <-r.Context().Done():
+				case <-time.After(time.Second):
+				}
+				resp.Result = "ok"
 			default:
 				resp.Result = "ok"
 			}
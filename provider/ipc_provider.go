@@ -0,0 +1,87 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+// IPCProvider provides web3 interface over the unix domain socket of a node
+// running on the same host, e.g. moac.ipc.
+type IPCProvider struct {
+	*streamProvider
+	path string
+}
+
+// NewIPCProvider creates an IPC provider for the given socket path. The
+// connection is established on the first request and re-established after it
+// is lost.
+func NewIPCProvider(path string, method rpc.RPC, options ...StreamOption) Provider {
+	provider := &IPCProvider{path: path}
+	provider.streamProvider = newStreamProvider(method, provider.dial, options...)
+	return provider
+}
+
+func (provider *IPCProvider) dial(ctx context.Context) (messageConn, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "unix", provider.path)
+	if err != nil {
+		return nil, err
+	}
+	return &ipcConn{conn: conn, decoder: json.NewDecoder(conn)}, nil
+}
+
+// ipcConn carries a stream of JSON RPC messages. The node does not delimit
+// its messages, so they are split by decoding the stream.
+type ipcConn struct {
+	conn    net.Conn
+	decoder *json.Decoder
+}
+
+func (c *ipcConn) ReadMessage() ([]byte, error) {
+	var message json.RawMessage
+	if err := c.decoder.Decode(&message); err != nil {
+		return nil, err
+	}
+	return message, nil
+}
+
+func (c *ipcConn) WriteMessage(data []byte) error {
+	_, err := c.conn.Write(append(data, '\n'))
+	return err
+}
+
+func (c *ipcConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math/rand"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type IPCProviderTestSuite struct {
+	suite.Suite
+	dir      string
+	listener net.Listener
+	provider Provider
+}
+
+func (suite *IPCProviderTestSuite) Test_IsConnected() {
+	provider := suite.provider
+	assert.EqualValues(suite.T(), true, provider.IsConnected(), "should be equal")
+}
+
+func (suite *IPCProviderTestSuite) Test_Send() {
+	provider := suite.provider
+	req := &rpc.JSONRPCRequest{
+		Version:    "2.0",
+		Method:     "test_method",
+		Params:     nil,
+		Identifier: 10}
+	resp, err := provider.Send(req)
+
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), req.Identifier, resp.ID(), "should be equal")
+	assert.EqualValues(suite.T(), "test_method", resp.Get("result").(string), "should be equal")
+}
+
+func (suite *IPCProviderTestSuite) Test_SendConcurrently() {
+	provider := suite.provider
+	method := provider.GetRPCMethod()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := "method_" + strings.Repeat("x", i)
+			req := method.NewRequest(name)
+			resp, err := provider.Send(req)
+			if assert.NoError(suite.T(), err, "Should be no error") {
+				assert.EqualValues(suite.T(), req.ID(), resp.ID(), "should be equal")
+				assert.EqualValues(suite.T(), name, resp.Get("result").(string), "should be equal")
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (suite *IPCProviderTestSuite) Test_DialError() {
+	provider := NewIPCProvider(filepath.Join(suite.dir, "missing.ipc"), nil)
+	_, err := provider.Send(provider.GetRPCMethod().NewRequest("net_listening"))
+	assert.Error(suite.T(), err, "Should be error")
+	assert.False(suite.T(), provider.IsConnected(), "should not be connected")
+}
+
+func (suite *IPCProviderTestSuite) SetupTest() {
+	var err error
+	suite.dir, err = ioutil.TempDir("", "chain3go")
+	suite.Require().NoError(err)
+	path := filepath.Join(suite.dir, "moac.ipc")
+	listener, err := net.Listen("unix", path)
+	suite.Require().NoError(err)
+	suite.listener = listener
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveIPC(conn)
+		}
+	}()
+	suite.provider = NewIPCProvider(path, rpc.GetDefaultMethod())
+}
+
+// serveIPC answers every request from its own goroutine, after a random
+// delay, and splits every response across two writes to exercise the stream
+// decoding.
+func serveIPC(conn net.Conn) {
+	defer conn.Close()
+	decoder := json.NewDecoder(conn)
+	var writeMu sync.Mutex
+	for {
+		req := rpc.JSONRPCRequest{}
+		if err := decoder.Decode(&req); err != nil {
+			return
+		}
+		go func(req rpc.JSONRPCRequest) {
+			resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier}
+			switch req.Method {
+			case "net_listening":
+				resp.Result = true
+			default:
+				resp.Result = req.Method
+			}
+			time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+			jsonBlob, _ := json.Marshal(resp)
+			writeMu.Lock()
+			conn.Write(jsonBlob[:len(jsonBlob)/2])
+			time.Sleep(time.Millisecond)
+			conn.Write(jsonBlob[len(jsonBlob)/2:])
+			writeMu.Unlock()
+		}(req)
+	}
+}
+
+func (suite *IPCProviderTestSuite) TearDownTest() {
+	suite.provider.(*IPCProvider).Close()
+	suite.listener.Close()
+	os.RemoveAll(suite.dir)
+}
+
+func Test_IPCProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(IPCProviderTestSuite))
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+// LogLevel is the severity of a log entry
+type LogLevel int
+
+// Log levels, from the most verbose to the least
+const (
+	LogLevelDebug LogLevel = iota
+	LogLevelInfo
+	LogLevelWarn
+	LogLevelError
+)
+
+func (level LogLevel) String() string {
+	switch level {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	}
+	return fmt.Sprintf("LEVEL(%d)", int(level))
+}
+
+// Logger receives structured log entries from providers. keyvals holds
+// alternating keys and values, in the style of log/slog.
+type Logger interface {
+	Log(level LogLevel, msg string, keyvals ...interface{})
+}
+
+// LoggerFunc adapts a function to the Logger interface, e.g. to forward
+// entries to a slog.Logger.
+type LoggerFunc func(level LogLevel, msg string, keyvals ...interface{})
+
+// Log calls f(level, msg, keyvals...)
+func (f LoggerFunc) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	f(level, msg, keyvals...)
+}
+
+type nopLogger struct{}
+
+func (nopLogger) Log(LogLevel, string, ...interface{}) {}
+
+// NewWriterLogger returns a Logger writing one line per entry at or above
+// level to w.
+func NewWriterLogger(w io.Writer, level LogLevel) Logger {
+	return &writerLogger{w: w, level: level}
+}
+
+type writerLogger struct {
+	mu    sync.Mutex
+	w     io.Writer
+	level LogLevel
+}
+
+func (logger *writerLogger) Log(level LogLevel, msg string, keyvals ...interface{}) {
+	if level < logger.level {
+		return
+	}
+
+	var line strings.Builder
+	fmt.Fprintf(&line, "level=%s msg=%q", level, msg)
+	for i := 0; i < len(keyvals); i += 2 {
+		if i+1 < len(keyvals) {
+			fmt.Fprintf(&line, " %v=%q", keyvals[i], fmt.Sprint(keyvals[i+1]))
+		} else {
+			fmt.Fprintf(&line, " %v=%q", keyvals[i], "")
+		}
+	}
+	line.WriteByte('\n')
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	io.WriteString(logger.w, line.String())
+}
+
+// redacted replaces the payload of sensitive calls in logs.
+const redacted = "[REDACTED]"
+
+// sensitiveMethods lists the methods whose payloads carry secrets, like
+// passwords or signed transactions, and are never logged. It is only read, so
+// that requests may check it concurrently.
+var sensitiveMethods = map[string]bool{
+	"personal_unlockAccount":   true,
+	"personal_newAccount":      true,
+	"personal_importRawKey":    true,
+	"personal_sendTransaction": true,
+	"personal_signTransaction": true,
+	"personal_sign":            true,
+	"mc_sendRawTransaction":    true,
+	"mc_sign":                  true,
+}
+
+// IsSensitiveMethod reports whether the payloads of method carry secrets, which
+// providers redact from logs and cassettes
+func IsSensitiveMethod(method string) bool {
+	return sensitiveMethods[method]
+}
+
+func isSensitive(requests ...rpc.Request) bool {
+	for _, request := range requests {
+		if method, _ := request.Get("method").(string); sensitiveMethods[method] {
+			return true
+		}
+	}
+	return false
+}
+
+// logPayload returns what may be logged of a payload.
+func logPayload(sensitive bool, payload []byte) string {
+	if sensitive {
+		return redacted
+	}
+	return string(payload)
+}
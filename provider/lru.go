@@ -0,0 +1,104 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import "container/list"
+
+// lru is a least recently used cache of encoded results, bounded by a number
+// of entries and a total size in bytes. It is not safe for concurrent use.
+type lru struct {
+	maxEntries int
+	maxBytes   int
+	size       int
+	order      *list.List
+	entries    map[string]*list.Element
+}
+
+type lruEntry struct {
+	key   string
+	value []byte
+}
+
+// newLRU creates a cache. A limit of zero leaves that dimension unbounded.
+func newLRU(maxEntries, maxBytes int) *lru {
+	return &lru{
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		order:      list.New(),
+		entries:    make(map[string]*list.Element),
+	}
+}
+
+func (cache *lru) get(key string) ([]byte, bool) {
+	element, ok := cache.entries[key]
+	if !ok {
+		return nil, false
+	}
+	cache.order.MoveToFront(element)
+	return element.Value.(*lruEntry).value, true
+}
+
+func (cache *lru) add(key string, value []byte) {
+	if element, ok := cache.entries[key]; ok {
+		entry := element.Value.(*lruEntry)
+		cache.size += len(value) - len(entry.value)
+		entry.value = value
+		cache.order.MoveToFront(element)
+	} else {
+		cache.entries[key] = cache.order.PushFront(&lruEntry{key: key, value: value})
+		cache.size += len(key) + len(value)
+	}
+
+	for cache.order.Len() > 0 &&
+		((cache.maxEntries > 0 && cache.order.Len() > cache.maxEntries) ||
+			(cache.maxBytes > 0 && cache.size > cache.maxBytes)) {
+		cache.removeOldest()
+	}
+}
+
+func (cache *lru) removeOldest() {
+	element := cache.order.Back()
+	entry := element.Value.(*lruEntry)
+	cache.order.Remove(element)
+	delete(cache.entries, entry.key)
+	cache.size -= len(entry.key) + len(entry.value)
+}
+
+func (cache *lru) len() int {
+	return cache.order.Len()
+}
+
+// each calls f on the entries from the least to the most recently used.
+func (cache *lru) each(f func(key string, value []byte)) {
+	for element := cache.order.Back(); element != nil; element = element.Prev() {
+		entry := element.Value.(*lruEntry)
+		f(entry.key, entry.value)
+	}
+}
@@ -0,0 +1,293 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+// Handler sends a request and returns its response
+type Handler func(context.Context, rpc.Request) (rpc.Response, error)
+
+// Middleware decorates a Handler, e.g. to observe, alter or answer requests
+type Middleware func(Handler) Handler
+
+// errNoResponse is returned through the middlewares for a batched request the
+// node did not answer
+var errNoResponse = errors.New("No response")
+
+// ChainProvider sends requests through middlewares to a base provider
+type ChainProvider struct {
+	base        Provider
+	middlewares []Middleware
+	handler     Handler
+}
+
+// Chain returns a provider sending requests through middlewares, the first
+// one being the outermost, to base. Every request of a batch goes through the
+// middlewares on its own, and those reaching base are sent together as one
+// batch. Subscriptions are handled by base.
+func Chain(base Provider, middlewares ...Middleware) *ChainProvider {
+	provider := &ChainProvider{base: base, middlewares: middlewares}
+	provider.handler = provider.chain(func(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+		return SendContext(ctx, base, request)
+	})
+	return provider
+}
+
+// chain wraps handler in the middlewares
+func (provider *ChainProvider) chain(handler Handler) Handler {
+	for i := len(provider.middlewares) - 1; i >= 0; i-- {
+		handler = provider.middlewares[i](handler)
+	}
+	return handler
+}
+
+// Unwrap returns the base provider
+func (provider *ChainProvider) Unwrap() Provider {
+	return provider.base
+}
+
+func (provider *ChainProvider) IsConnected() bool {
+	return provider.base.IsConnected()
+}
+
+// Send sends request through the middlewares
+func (provider *ChainProvider) Send(request rpc.Request) (rpc.Response, error) {
+	return provider.SendContext(context.Background(), request)
+}
+
+// SendContext is like Send but with a context.
+func (provider *ChainProvider) SendContext(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+	return provider.handler(ctx, request)
+}
+
+// SendBatch sends requests through the middlewares. If base is a
+// BatchProvider, the requests reaching it are sent in one round trip, once
+// every request of the batch either reached base or was answered by a
+// middleware.
+func (provider *ChainProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	return provider.SendBatchContext(context.Background(), requests)
+}
+
+// SendBatchContext is like SendBatch but with a context.
+func (provider *ChainProvider) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	batchProvider, ok := provider.base.(BatchProvider)
+	if !ok {
+		responses := make([]rpc.Response, len(requests))
+		for i, request := range requests {
+			response, err := provider.handler(ctx, request)
+			if err != nil {
+				return nil, err
+			}
+			responses[i] = response
+		}
+		return responses, nil
+	}
+
+	collector := &batchCollector{
+		base:    provider.base,
+		waiting: len(requests),
+		arrived: make([]bool, len(requests)),
+		ready:   make(chan struct{}),
+	}
+	if len(requests) == 0 {
+		close(collector.ready)
+	}
+
+	responses := make([]rpc.Response, len(requests))
+	errs := make([]error, len(requests))
+	var wg sync.WaitGroup
+	for i, request := range requests {
+		wg.Add(1)
+		go func(i int, request rpc.Request) {
+			defer wg.Done()
+			handler := provider.chain(func(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+				return collector.send(ctx, i, request)
+			})
+			responses[i], errs[i] = handler(ctx, request)
+			collector.done(i)
+		}(i, request)
+	}
+
+	select {
+	case <-collector.ready:
+	case <-ctx.Done():
+	}
+	calls := collector.take()
+	if len(calls) > 0 {
+		if err := ctx.Err(); err != nil {
+			for _, call := range calls {
+				call.resultCh <- &result{err: err}
+			}
+		} else {
+			batch := make([]rpc.Request, len(calls))
+			for j, call := range calls {
+				batch[j] = call.request
+			}
+			batchResponses, err := batchProvider.SendBatchContext(ctx, batch)
+			for j, call := range calls {
+				r := &result{err: err}
+				if err == nil {
+					r.response = batchResponses[j]
+					if r.response == nil {
+						r.err = errNoResponse
+					}
+				}
+				call.resultCh <- r
+			}
+		}
+	}
+	wg.Wait()
+
+	for i, err := range errs {
+		if errors.Is(err, errNoResponse) {
+			responses[i] = nil
+		} else if err != nil {
+			return nil, err
+		}
+	}
+	return responses, nil
+}
+
+func (provider *ChainProvider) GetRPCMethod() rpc.RPC {
+	return provider.base.GetRPCMethod()
+}
+
+// batchCollector gathers the requests of a batch reaching the end of the
+// middlewares. Requests arriving after the batch was sent, e.g. retried by a
+// middleware, are sent on their own.
+type batchCollector struct {
+	base Provider
+
+	mu      sync.Mutex
+	waiting int
+	arrived []bool
+	calls   []*batchCall
+	ready   chan struct{}
+	sent    bool
+}
+
+type batchCall struct {
+	request  rpc.Request
+	resultCh chan *result
+}
+
+func (collector *batchCollector) send(ctx context.Context, i int, request rpc.Request) (rpc.Response, error) {
+	collector.mu.Lock()
+	if collector.sent || collector.arrived[i] {
+		collector.mu.Unlock()
+		return SendContext(ctx, collector.base, request)
+	}
+	call := &batchCall{request: request, resultCh: make(chan *result, 1)}
+	collector.calls = append(collector.calls, call)
+	collector.arrive(i)
+	collector.mu.Unlock()
+
+	r := <-call.resultCh
+	return r.response, r.err
+}
+
+// done records that the request i went through the middlewares, whether it
+// reached base or not.
+func (collector *batchCollector) done(i int) {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	if !collector.sent && !collector.arrived[i] {
+		collector.arrive(i)
+	}
+}
+
+func (collector *batchCollector) arrive(i int) {
+	collector.arrived[i] = true
+	collector.waiting--
+	if collector.waiting == 0 {
+		close(collector.ready)
+	}
+}
+
+// take returns the collected requests, the later ones being sent on their own
+func (collector *batchCollector) take() []*batchCall {
+	collector.mu.Lock()
+	defer collector.mu.Unlock()
+	collector.sent = true
+	return collector.calls
+}
+
+// ForMethods applies middleware to the given methods only.
+func ForMethods(middleware Middleware, methods ...string) Middleware {
+	selected := make(map[string]bool, len(methods))
+	for _, method := range methods {
+		selected[method] = true
+	}
+
+	return func(next Handler) Handler {
+		decorated := middleware(next)
+		return func(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+			if method, _ := request.Get("method").(string); selected[method] {
+				return decorated(ctx, request)
+			}
+			return next(ctx, request)
+		}
+	}
+}
+
+// Intercept answers the requests for method with handler instead of passing
+// them on.
+func Intercept(method string, handler Handler) Middleware {
+	return ForMethods(func(Handler) Handler { return handler }, method)
+}
+
+// LoggingMiddleware logs requests and responses at LogLevelDebug and failures
+// at LogLevelWarn, like WithLogger does for HTTPProvider.
+func LoggingMiddleware(logger Logger) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+			method, id := request.Get("method"), request.ID()
+			sensitive := isSensitive(request)
+
+			logger.Log(LogLevelDebug, "send", "method", method, "id", id, "payload", logPayload(sensitive, []byte(request.String())))
+			start := time.Now()
+			response, err := next(ctx, request)
+			if err != nil {
+				logger.Log(LogLevelWarn, "request failed", "method", method, "id", id, "error", err)
+				return response, err
+			}
+
+			logger.Log(LogLevelDebug, "receive", "method", method, "id", id, "duration", time.Since(start), "payload", logPayload(sensitive, []byte(response.String())))
+			return response, err
+		}
+	}
+}
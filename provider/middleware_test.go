@@ -0,0 +1,196 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type MiddlewareTestSuite struct {
+	suite.Suite
+	base *blockingProvider
+}
+
+// trace appends name to calls around the next handler
+func trace(name string, calls *[]string) Middleware {
+	return func(next Handler) Handler {
+		return func(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+			*calls = append(*calls, name+">")
+			response, err := next(ctx, request)
+			*calls = append(*calls, "<"+name)
+			return response, err
+		}
+	}
+}
+
+func (suite *MiddlewareTestSuite) Test_Chain() {
+	calls := []string{}
+	provider := Chain(suite.base, trace("a", &calls), trace("b", &calls))
+
+	resp, err := provider.Send(suite.base.rpc.NewRequest("mc_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "ok", resp.Get("result"), "should be equal")
+	assert.EqualValues(suite.T(), []string{"a>", "b>", "<b", "<a"}, calls, "should be equal")
+	assert.Equal(suite.T(), suite.base.rpc, provider.GetRPCMethod(), "should be equal")
+	assert.True(suite.T(), provider.IsConnected(), "should be connected")
+}
+
+func (suite *MiddlewareTestSuite) Test_ForMethods() {
+	calls := []string{}
+	provider := Chain(suite.base, ForMethods(trace("a", &calls), "mc_getLogs", "mc_call"))
+
+	provider.Send(suite.base.rpc.NewRequest("mc_blockNumber"))
+	assert.Empty(suite.T(), calls, "should be skipped")
+	provider.Send(suite.base.rpc.NewRequest("mc_call"))
+	assert.EqualValues(suite.T(), []string{"a>", "<a"}, calls, "should be equal")
+}
+
+func (suite *MiddlewareTestSuite) Test_Intercept() {
+	provider := Chain(suite.base, Intercept("mc_gasPrice", func(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+		return &rpc.JSONRPCResponse{Version: "2.0", Identifier: request.ID(), Result: "0x1"}, nil
+	}))
+
+	resp, err := provider.Send(suite.base.rpc.NewRequest("mc_gasPrice"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "0x1", resp.Get("result"), "should be equal")
+
+	resp, err = provider.Send(suite.base.rpc.NewRequest("mc_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "ok", resp.Get("result"), "should be equal")
+}
+
+func (suite *MiddlewareTestSuite) Test_LoggingMiddleware() {
+	entries := []string{}
+	logger := LoggerFunc(func(level LogLevel, msg string, keyvals ...interface{}) {
+		entries = append(entries, fmt.Sprintln(append([]interface{}{level, msg}, keyvals...)...))
+	})
+	provider := Chain(suite.base, LoggingMiddleware(logger))
+
+	request := suite.base.rpc.NewRequest("mc_sendRawTransaction")
+	request.Set("params", []string{"0xsigned"})
+	_, err := provider.Send(request)
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	if assert.Len(suite.T(), entries, 2) {
+		assert.True(suite.T(), strings.HasPrefix(entries[0], "DEBUG send"), "should be equal")
+		assert.NotContains(suite.T(), entries[0], "0xsigned", "should redact the payload")
+		assert.Contains(suite.T(), entries[1], "duration", "should log the duration")
+	}
+}
+
+func (suite *MiddlewareTestSuite) Test_IsSensitiveMethod() {
+	assert.True(suite.T(), IsSensitiveMethod("personal_unlockAccount"), "should be sensitive")
+	assert.True(suite.T(), IsSensitiveMethod("mc_sendRawTransaction"), "should be sensitive")
+	assert.False(suite.T(), IsSensitiveMethod("mc_blockNumber"), "should not be sensitive")
+}
+
+// batchingProvider answers batches in one call, leaving "test_silent"
+// unanswered
+type batchingProvider struct {
+	blockingProvider
+	batches [][]string
+}
+
+func (provider *batchingProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	return provider.SendBatchContext(context.Background(), requests)
+}
+
+func (provider *batchingProvider) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	methods := []string{}
+	responses := make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		method := request.Get("method").(string)
+		methods = append(methods, method)
+		if method != "test_silent" {
+			responses[i] = &rpc.JSONRPCResponse{Version: "2.0", Identifier: request.ID(), Result: method}
+		}
+	}
+	provider.batches = append(provider.batches, methods)
+	return responses, nil
+}
+
+func (suite *MiddlewareTestSuite) Test_SendBatch() {
+	base := &batchingProvider{blockingProvider: *suite.base}
+	var mu sync.Mutex
+	seen := []string{}
+	provider := Chain(base, func(next Handler) Handler {
+		return func(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+			mu.Lock()
+			seen = append(seen, request.Get("method").(string))
+			mu.Unlock()
+			return next(ctx, request)
+		}
+	}, Intercept("mc_gasPrice", func(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+		return &rpc.JSONRPCResponse{Version: "2.0", Identifier: request.ID(), Result: "0x1"}, nil
+	}))
+
+	requests := []rpc.Request{
+		base.rpc.NewRequest("mc_blockNumber"),
+		base.rpc.NewRequest("mc_gasPrice"),
+		base.rpc.NewRequest("test_silent"),
+		base.rpc.NewRequest("mc_getBalance"),
+	}
+	resps, err := provider.SendBatch(requests)
+	suite.Require().NoError(err)
+	if assert.Len(suite.T(), resps, 4) {
+		assert.EqualValues(suite.T(), "mc_blockNumber", resps[0].Get("result"), "should be equal")
+		assert.EqualValues(suite.T(), "0x1", resps[1].Get("result"), "should be intercepted")
+		assert.Nil(suite.T(), resps[2], "should be nil")
+		assert.EqualValues(suite.T(), "mc_getBalance", resps[3].Get("result"), "should be equal")
+	}
+	assert.Len(suite.T(), seen, 4, "should go through the middlewares")
+	if assert.Len(suite.T(), base.batches, 1, "should be one round trip") {
+		assert.ElementsMatch(suite.T(), []string{"mc_blockNumber", "test_silent", "mc_getBalance"}, base.batches[0], "should be equal")
+	}
+}
+
+func (suite *MiddlewareTestSuite) Test_Unwrap() {
+	ws := &WebSocketProvider{}
+	subscriber, ok := AsSubscriber(Chain(ws))
+	assert.True(suite.T(), ok, "should be a subscriber")
+	assert.Equal(suite.T(), ws, subscriber, "should be equal")
+}
+
+func (suite *MiddlewareTestSuite) SetupTest() {
+	suite.base = &blockingProvider{rpc: rpc.GetDefaultMethod(), release: make(chan struct{})}
+	close(suite.base.release)
+}
+
+func Test_MiddlewareTestSuite(t *testing.T) {
+	suite.Run(t, new(MiddlewareTestSuite))
+}
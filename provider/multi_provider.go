@@ -0,0 +1,454 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+var (
+	// ErrNoProviders is returned by a MultiProvider without providers
+	ErrNoProviders = errors.New("No providers")
+	// ErrNoSubscriber is returned when subscribing through a node which cannot
+	// receive notifications
+	ErrNoSubscriber = errors.New("The node does not support subscriptions")
+)
+
+// stickyCreateMethods return an identifier only known to the node which
+// created it.
+var stickyCreateMethods = map[string]bool{
+	"mc_newFilter":                   true,
+	"mc_newBlockFilter":              true,
+	"mc_newPendingTransactionFilter": true,
+	"mc_subscribe":                   true,
+}
+
+// stickyUseMethods take an identifier returned by a stickyCreateMethods as
+// first parameter. The value tells whether they release the identifier.
+var stickyUseMethods = map[string]bool{
+	"mc_getFilterChanges": false,
+	"mc_getFilterLogs":    false,
+	"mc_uninstallFilter":  true,
+	"mc_unsubscribe":      true,
+}
+
+// MultiOption configures a MultiProvider
+type MultiOption func(*MultiProvider)
+
+// WithHealthCheckInterval sets how often the nodes are checked in the
+// background. Zero disables the checks. Defaults to 15s.
+func WithHealthCheckInterval(interval time.Duration) MultiOption {
+	return func(provider *MultiProvider) {
+		provider.interval = interval
+	}
+}
+
+// WithHealthCheckTimeout bounds the duration of a node check. Defaults to 5s.
+func WithHealthCheckTimeout(timeout time.Duration) MultiOption {
+	return func(provider *MultiProvider) {
+		provider.timeout = timeout
+	}
+}
+
+// MultiProvider spreads requests over several nodes.
+//
+// Idempotent reads (see IdempotentMethods) are sent to the healthy nodes in
+// turn and fail over to the next healthy node on error. Other requests are
+// sent to the first healthy node, without failover since they may have been
+// applied. Filters and subscriptions stick to the node which created them.
+// A node failing a request is left out until a health check, calling
+// net_listening and mc_blockNumber, succeeds. When no node is healthy, all of
+// them are tried.
+type MultiProvider struct {
+	providers []Provider
+	interval  time.Duration
+	timeout   time.Duration
+
+	mu      sync.Mutex
+	healthy []bool
+	next    int
+	sticky  map[string]int
+
+	stop      chan struct{}
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewMultiProvider creates a provider over the given nodes and starts checking
+// their health. Close stops the checks.
+func NewMultiProvider(providers []Provider, options ...MultiOption) *MultiProvider {
+	provider := &MultiProvider{
+		providers: providers,
+		interval:  15 * time.Second,
+		timeout:   5 * time.Second,
+		healthy:   make([]bool, len(providers)),
+		sticky:    make(map[string]int),
+		stop:      make(chan struct{}),
+	}
+	for _, option := range options {
+		option(provider)
+	}
+	for i := range provider.healthy {
+		provider.healthy[i] = true
+	}
+
+	if provider.interval > 0 && len(providers) > 0 {
+		provider.wg.Add(1)
+		go provider.checkHealth()
+	}
+	return provider
+}
+
+// Providers returns the nodes, in the order given to NewMultiProvider
+func (provider *MultiProvider) Providers() []Provider {
+	return provider.providers
+}
+
+// Healthy reports whether the node at index i is considered healthy
+func (provider *MultiProvider) Healthy(i int) bool {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	return provider.healthy[i]
+}
+
+func (provider *MultiProvider) IsConnected() bool {
+	for i, p := range provider.providers {
+		if provider.Healthy(i) && p.IsConnected() {
+			return true
+		}
+	}
+	return false
+}
+
+// Send sends request to a node chosen by its method
+func (provider *MultiProvider) Send(request rpc.Request) (rpc.Response, error) {
+	return provider.SendContext(context.Background(), request)
+}
+
+// SendContext is like Send but with a context.
+func (provider *MultiProvider) SendContext(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+	if len(provider.providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	method, _ := request.Get("method").(string)
+	if release, ok := stickyUseMethods[method]; ok {
+		if id, ok := stickyID(request); ok {
+			if i, ok := provider.stickyNode(id); ok {
+				response, err := provider.sendTo(ctx, i, request)
+				// the identifier is forgotten even if the node failed, it
+				// can't be used anymore either way
+				if release {
+					provider.mu.Lock()
+					delete(provider.sticky, id)
+					provider.mu.Unlock()
+				}
+				return response, err
+			}
+		}
+	}
+
+	var (
+		response rpc.Response
+		node     int
+		err      error
+	)
+	if IdempotentMethods[method] {
+		response, node, err = provider.sendAny(ctx, provider.roundRobin(), request)
+	} else {
+		node = provider.primary()[0]
+		response, err = provider.sendTo(ctx, node, request)
+	}
+
+	if stickyCreateMethods[method] && err == nil && response.Error() == nil {
+		if id, ok := response.Get("result").(string); ok {
+			provider.mu.Lock()
+			provider.sticky[id] = node
+			provider.mu.Unlock()
+		}
+	}
+	return response, err
+}
+
+// SendBatch sends requests to a single node, chosen like for a request. A
+// batch with filter or subscription calls is sent one request at a time so
+// that each one reaches the node owning its identifier.
+func (provider *MultiProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	return provider.SendBatchContext(context.Background(), requests)
+}
+
+// SendBatchContext is like SendBatch but with a context.
+func (provider *MultiProvider) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	if len(provider.providers) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	idempotent := true
+	for _, request := range requests {
+		method, _ := request.Get("method").(string)
+		_, use := stickyUseMethods[method]
+		if use || stickyCreateMethods[method] {
+			return provider.sendEach(ctx, requests)
+		}
+		idempotent = idempotent && IdempotentMethods[method]
+	}
+
+	var nodes []int
+	if idempotent {
+		nodes = provider.roundRobin()
+	} else {
+		nodes = provider.primary()[:1]
+	}
+
+	var err error
+	for _, i := range nodes {
+		batchProvider, ok := provider.providers[i].(BatchProvider)
+		if !ok {
+			return provider.sendEach(ctx, requests)
+		}
+
+		var responses []rpc.Response
+		responses, err = batchProvider.SendBatchContext(ctx, requests)
+		if err == nil {
+			return responses, nil
+		}
+		provider.setHealthy(i, false)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return nil, err
+}
+
+func (provider *MultiProvider) sendEach(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	responses := make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		response, err := provider.SendContext(ctx, request)
+		if err != nil {
+			return nil, err
+		}
+		responses[i] = response
+	}
+	return responses, nil
+}
+
+func (provider *MultiProvider) GetRPCMethod() rpc.RPC {
+	if len(provider.providers) == 0 {
+		return rpc.GetDefaultMethod()
+	}
+	return provider.providers[0].GetRPCMethod()
+}
+
+// Subscribe routes notifications from the node which created the
+// subscription.
+func (provider *MultiProvider) Subscribe(id string) (*rpc.Subscription, error) {
+	i, ok := provider.stickyNode(id)
+	if !ok {
+		i = provider.primary()[0]
+	}
+	subscriber, ok := AsSubscriber(provider.providers[i])
+	if !ok {
+		return nil, ErrNoSubscriber
+	}
+	return subscriber.Subscribe(id)
+}
+
+// Unsubscribe stops routing notifications of the subscription
+func (provider *MultiProvider) Unsubscribe(id string) {
+	i, ok := provider.stickyNode(id)
+	if !ok {
+		return
+	}
+	if subscriber, ok := AsSubscriber(provider.providers[i]); ok {
+		subscriber.Unsubscribe(id)
+	}
+}
+
+// Close stops the health checks
+func (provider *MultiProvider) Close() error {
+	provider.closeOnce.Do(func() {
+		close(provider.stop)
+	})
+	provider.wg.Wait()
+	return nil
+}
+
+// sendAny sends request to nodes in order until one answers.
+func (provider *MultiProvider) sendAny(ctx context.Context, nodes []int, request rpc.Request) (response rpc.Response, node int, err error) {
+	for _, node = range nodes {
+		response, err = provider.sendTo(ctx, node, request)
+		if err == nil || ctx.Err() != nil {
+			break
+		}
+	}
+	return response, node, err
+}
+
+// sendTo sends request to the node at index i, which is marked unhealthy if it
+// cannot be reached.
+func (provider *MultiProvider) sendTo(ctx context.Context, i int, request rpc.Request) (rpc.Response, error) {
+	response, err := SendContext(ctx, provider.providers[i], request)
+	if err != nil && ctx.Err() == nil {
+		provider.setHealthy(i, false)
+	}
+	return response, err
+}
+
+// roundRobin returns the healthy nodes, starting from the next one in turn,
+// followed by the unhealthy ones.
+func (provider *MultiProvider) roundRobin() []int {
+	provider.mu.Lock()
+	start := provider.next % len(provider.providers)
+	provider.next++
+	provider.mu.Unlock()
+
+	nodes := provider.primary()
+	for i, node := range nodes {
+		if node >= start {
+			return append(nodes[i:], nodes[:i]...)
+		}
+	}
+	return nodes
+}
+
+// primary returns the healthy nodes followed by the unhealthy ones, each in
+// list order.
+func (provider *MultiProvider) primary() []int {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	nodes := make([]int, 0, len(provider.providers))
+	for i, healthy := range provider.healthy {
+		if healthy {
+			nodes = append(nodes, i)
+		}
+	}
+	healthyCount := len(nodes)
+	for i, healthy := range provider.healthy {
+		if !healthy {
+			nodes = append(nodes, i)
+		}
+	}
+	if healthyCount == 0 {
+		return nodes
+	}
+	return nodes[:healthyCount]
+}
+
+func (provider *MultiProvider) stickyNode(id string) (int, bool) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	i, ok := provider.sticky[id]
+	return i, ok
+}
+
+// setHealthy marks the node at index i up or down. The filters and
+// subscriptions of a node marked down are forgotten, as they're lost if the
+// node restarted.
+func (provider *MultiProvider) setHealthy(i int, healthy bool) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	provider.healthy[i] = healthy
+	if healthy {
+		return
+	}
+	for id, node := range provider.sticky {
+		if node == i {
+			delete(provider.sticky, id)
+		}
+	}
+}
+
+// stickyID returns the identifier passed as first parameter of request.
+func stickyID(request rpc.Request) (string, bool) {
+	switch params := request.Get("params").(type) {
+	case []string:
+		if len(params) > 0 {
+			return params[0], true
+		}
+	case []interface{}:
+		if len(params) > 0 {
+			id, ok := params[0].(string)
+			return id, ok
+		}
+	}
+	return "", false
+}
+
+func (provider *MultiProvider) checkHealth() {
+	defer provider.wg.Done()
+
+	ticker := time.NewTicker(provider.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-provider.stop:
+			return
+		case <-ticker.C:
+			provider.CheckHealth()
+		}
+	}
+}
+
+// CheckHealth checks every node now. It is called periodically in the
+// background.
+func (provider *MultiProvider) CheckHealth() {
+	var wg sync.WaitGroup
+	for i := range provider.providers {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			provider.setHealthy(i, provider.check(provider.providers[i]))
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (provider *MultiProvider) check(p Provider) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), provider.timeout)
+	defer cancel()
+
+	method := p.GetRPCMethod()
+	response, err := SendContext(ctx, p, method.NewRequest("net_listening"))
+	if err != nil || response.Error() != nil {
+		return false
+	}
+	if listening, _ := response.Get("result").(bool); !listening {
+		return false
+	}
+
+	response, err = SendContext(ctx, p, method.NewRequest("mc_blockNumber"))
+	return err == nil && response.Error() == nil
+}
@@ -0,0 +1,282 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// fakeNode answers every request with its name, and filter calls only for the
+// filters it created
+type fakeNode struct {
+	name   string
+	server *httptest.Server
+	mu     sync.Mutex
+	down   bool
+	calls  map[string]int
+}
+
+func newFakeNode(name string) *fakeNode {
+	node := &fakeNode{name: name, calls: make(map[string]int)}
+	node.server = httptest.NewServer(http.HandlerFunc(node.serveHTTP))
+	return node
+}
+
+func (node *fakeNode) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	if node.down {
+		http.Error(w, "down", http.StatusBadGateway)
+		return
+	}
+
+	body, _ := ioutil.ReadAll(r.Body)
+	reqs := []rpc.JSONRPCRequest{}
+	if err := json.Unmarshal(body, &reqs); err != nil {
+		req := rpc.JSONRPCRequest{}
+		json.Unmarshal(body, &req)
+		jsonBlob, _ := json.Marshal(node.answer(req))
+		w.Write(jsonBlob)
+		return
+	}
+
+	resps := []rpc.JSONRPCResponse{}
+	for _, req := range reqs {
+		resps = append(resps, node.answer(req))
+	}
+	jsonBlob, _ := json.Marshal(resps)
+	w.Write(jsonBlob)
+}
+
+func (node *fakeNode) answer(req rpc.JSONRPCRequest) rpc.JSONRPCResponse {
+	node.calls[req.Method]++
+	resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier, Result: node.name}
+	switch req.Method {
+	case "net_listening":
+		resp.Result = true
+	case "mc_newFilter":
+		resp.Result = "0x" + node.name
+	case "mc_getFilterChanges", "mc_uninstallFilter":
+		if len(req.Params) == 0 || req.Params[0] != "0x"+node.name {
+			resp.Result = nil
+			resp.Err = &rpc.JSONRPCError{Code: -32000, Message: "filter not found"}
+		}
+	}
+	return resp
+}
+
+func (node *fakeNode) setDown(down bool) {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	node.down = down
+}
+
+func (node *fakeNode) count(method string) int {
+	node.mu.Lock()
+	defer node.mu.Unlock()
+	return node.calls[method]
+}
+
+type MultiProviderTestSuite struct {
+	suite.Suite
+	nodes    []*fakeNode
+	provider *MultiProvider
+}
+
+func (suite *MultiProviderTestSuite) send(method string, params ...interface{}) (rpc.Response, error) {
+	request := suite.provider.GetRPCMethod().NewRequest(method)
+	if len(params) > 0 {
+		request.Set("params", params)
+	}
+	return suite.provider.Send(request)
+}
+
+func (suite *MultiProviderTestSuite) Test_RoundRobin() {
+	results := []interface{}{}
+	for i := 0; i < 4; i++ {
+		resp, err := suite.send("mc_blockNumber")
+		assert.NoError(suite.T(), err, "Should be no error")
+		results = append(results, resp.Get("result"))
+	}
+
+	assert.EqualValues(suite.T(), []interface{}{"a", "b", "a", "b"}, results, "should be equal")
+}
+
+func (suite *MultiProviderTestSuite) Test_Failover() {
+	suite.nodes[0].setDown(true)
+
+	for i := 0; i < 3; i++ {
+		resp, err := suite.send("mc_blockNumber")
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.EqualValues(suite.T(), "b", resp.Get("result"), "should be equal")
+	}
+	assert.False(suite.T(), suite.provider.Healthy(0), "should be unhealthy")
+	assert.True(suite.T(), suite.provider.Healthy(1), "should be healthy")
+}
+
+func (suite *MultiProviderTestSuite) Test_Writes() {
+	resp, err := suite.send("mc_sendTransaction")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "a", resp.Get("result"), "should be equal")
+
+	suite.nodes[0].setDown(true)
+	_, err = suite.send("mc_sendTransaction")
+	assert.Error(suite.T(), err, "should not fail over")
+	assert.EqualValues(suite.T(), 0, suite.nodes[1].count("mc_sendTransaction"), "should be equal")
+
+	resp, err = suite.send("mc_sendTransaction")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "b", resp.Get("result"), "should be equal")
+}
+
+func (suite *MultiProviderTestSuite) Test_StickyFilters() {
+	ids := []string{}
+	for i := 0; i < 2; i++ {
+		// Move the first filter to the other node.
+		suite.nodes[0].setDown(i == 0)
+		suite.provider.CheckHealth()
+
+		resp, err := suite.send("mc_newFilter")
+		assert.NoError(suite.T(), err, "Should be no error")
+		ids = append(ids, resp.Get("result").(string))
+	}
+	assert.EqualValues(suite.T(), []string{"0xb", "0xa"}, ids, "should be equal")
+
+	for i := 0; i < 4; i++ {
+		for _, id := range ids {
+			resp, err := suite.send("mc_getFilterChanges", id)
+			assert.NoError(suite.T(), err, "Should be no error")
+			assert.NoError(suite.T(), resp.Error(), "should reach the node owning the filter")
+		}
+	}
+
+	resp, err := suite.send("mc_uninstallFilter", ids[0])
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.NoError(suite.T(), resp.Error(), "should reach the node owning the filter")
+	_, ok := suite.provider.stickyNode(ids[0])
+	assert.False(suite.T(), ok, "should be released")
+
+	// the filters of a node marked down are forgotten
+	suite.nodes[0].setDown(true)
+	suite.provider.CheckHealth()
+	_, ok = suite.provider.stickyNode(ids[1])
+	assert.False(suite.T(), ok, "should be released")
+	assert.Empty(suite.T(), suite.provider.sticky, "should be empty")
+}
+
+func (suite *MultiProviderTestSuite) Test_StickyReleasedOnError() {
+	resp, err := suite.send("mc_newFilter")
+	suite.Require().NoError(err)
+	id := resp.Get("result").(string)
+
+	suite.nodes[0].setDown(true)
+	_, err = suite.send("mc_uninstallFilter", id)
+	assert.Error(suite.T(), err, "should fail")
+	_, ok := suite.provider.stickyNode(id)
+	assert.False(suite.T(), ok, "should be released")
+}
+
+func (suite *MultiProviderTestSuite) Test_SendBatch() {
+	method := suite.provider.GetRPCMethod()
+	requests := []rpc.Request{method.NewRequest("mc_blockNumber"), method.NewRequest("mc_gasPrice")}
+
+	resps, err := suite.provider.SendBatch(requests)
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), resps, 2) {
+		assert.EqualValues(suite.T(), resps[0].Get("result"), resps[1].Get("result"), "should reach a single node")
+	}
+
+	suite.nodes[0].setDown(true)
+	suite.nodes[1].setDown(true)
+	_, err = suite.provider.SendBatch(requests)
+	assert.Error(suite.T(), err, "should fail")
+}
+
+func (suite *MultiProviderTestSuite) Test_CheckHealth() {
+	suite.nodes[0].setDown(true)
+	suite.provider.CheckHealth()
+	assert.False(suite.T(), suite.provider.Healthy(0), "should be unhealthy")
+	assert.True(suite.T(), suite.provider.IsConnected(), "should be connected")
+
+	suite.nodes[0].setDown(false)
+	suite.provider.CheckHealth()
+	assert.True(suite.T(), suite.provider.Healthy(0), "should be healthy")
+}
+
+func (suite *MultiProviderTestSuite) Test_BackgroundHealthCheck() {
+	provider := NewMultiProvider([]Provider{NewHTTPProvider(suite.nodes[0].server.URL, nil)},
+		WithHealthCheckInterval(5*time.Millisecond))
+	defer provider.Close()
+
+	suite.nodes[0].setDown(true)
+	assert.Eventually(suite.T(), func() bool { return !provider.Healthy(0) }, time.Second, 5*time.Millisecond, "should be unhealthy")
+	suite.nodes[0].setDown(false)
+	assert.Eventually(suite.T(), func() bool { return provider.Healthy(0) }, time.Second, 5*time.Millisecond, "should be healthy")
+	assert.NoError(suite.T(), provider.Close(), "Should be no error")
+}
+
+func (suite *MultiProviderTestSuite) Test_NoProviders() {
+	provider := NewMultiProvider(nil)
+	defer provider.Close()
+
+	_, err := provider.Send(provider.GetRPCMethod().NewRequest("mc_blockNumber"))
+	assert.Equal(suite.T(), ErrNoProviders, err, "should be equal")
+	assert.False(suite.T(), provider.IsConnected(), "should not be connected")
+}
+
+func (suite *MultiProviderTestSuite) SetupTest() {
+	suite.nodes = []*fakeNode{newFakeNode("a"), newFakeNode("b")}
+	providers := []Provider{}
+	for _, node := range suite.nodes {
+		providers = append(providers, NewHTTPProvider(node.server.URL, nil))
+	}
+	suite.provider = NewMultiProvider(providers, WithHealthCheckInterval(0))
+}
+
+func (suite *MultiProviderTestSuite) TearDownTest() {
+	suite.provider.Close()
+	for _, node := range suite.nodes {
+		node.server.Close()
+	}
+}
+
+func Test_MultiProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(MultiProviderTestSuite))
+}
@@ -30,6 +30,8 @@
 package provider
 
 import (
+	"context"
+
 	"github.com/caivega/chain3go/rpc"
 )
 
@@ -39,3 +41,88 @@ type Provider interface {
 	Send(rpc.Request) (rpc.Response, error)
 	GetRPCMethod() rpc.RPC
 }
+
+// Subscriber is implemented by providers on streaming transports, which
+// receive notifications pushed by the node for subscriptions
+type Subscriber interface {
+	Subscribe(id string) (*rpc.Subscription, error)
+	Unsubscribe(id string)
+}
+
+// ContextProvider is implemented by providers able to abandon a request when
+// its context is done
+type ContextProvider interface {
+	SendContext(context.Context, rpc.Request) (rpc.Response, error)
+}
+
+// BatchProvider is implemented by providers able to send several requests in
+// one round trip. The responses are returned in the order of the requests,
+// with nil for a request the node did not answer.
+type BatchProvider interface {
+	SendBatch([]rpc.Request) ([]rpc.Response, error)
+	SendBatchContext(context.Context, []rpc.Request) ([]rpc.Response, error)
+}
+
+// Wrapper is implemented by providers decorating another provider, such as
+// RetryProvider
+type Wrapper interface {
+	Unwrap() Provider
+}
+
+// AsSubscriber returns the Subscriber found by unwrapping provider, if any.
+// Notifications bypass the wrappers.
+func AsSubscriber(provider Provider) (Subscriber, bool) {
+	for provider != nil {
+		if subscriber, ok := provider.(Subscriber); ok {
+			return subscriber, true
+		}
+		wrapper, ok := provider.(Wrapper)
+		if !ok {
+			break
+		}
+		provider = wrapper.Unwrap()
+	}
+	return nil, false
+}
+
+// SendContext sends a request through provider, giving up when ctx is done. If
+// the provider does not implement ContextProvider, the request keeps running
+// in the background after ctx is done.
+func SendContext(ctx context.Context, provider Provider, request rpc.Request) (rpc.Response, error) {
+	if p, ok := provider.(ContextProvider); ok {
+		return p.SendContext(ctx, request)
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if ctx.Done() == nil {
+		return provider.Send(request)
+	}
+
+	resultCh := make(chan *result, 1)
+	go func() {
+		response, err := provider.Send(request)
+		resultCh <- &result{response: response, err: err}
+	}()
+
+	select {
+	case r := <-resultCh:
+		return r.response, r.err
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// orderResponses matches responses to their requests by ID.
+func orderResponses(requests []rpc.Request, responses []rpc.Response) []rpc.Response {
+	byID := make(map[uint64]rpc.Response, len(responses))
+	for _, response := range responses {
+		byID[response.ID()] = response
+	}
+
+	ordered := make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		ordered[i] = byID[request.ID()]
+	}
+	return ordered
+}
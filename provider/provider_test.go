@@ -0,0 +1,91 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// blockingProvider answers requests once released
+type blockingProvider struct {
+	rpc     rpc.RPC
+	release chan struct{}
+}
+
+func (provider *blockingProvider) IsConnected() bool {
+	return true
+}
+
+func (provider *blockingProvider) Send(request rpc.Request) (rpc.Response, error) {
+	<-provider.release
+	return &rpc.JSONRPCResponse{Version: "2.0", Identifier: request.ID(), Result: "ok"}, nil
+}
+
+func (provider *blockingProvider) GetRPCMethod() rpc.RPC {
+	return provider.rpc
+}
+
+type ProviderTestSuite struct {
+	suite.Suite
+	provider *blockingProvider
+}
+
+func (suite *ProviderTestSuite) Test_SendContext() {
+	close(suite.provider.release)
+	resp, err := SendContext(context.Background(), suite.provider, suite.provider.rpc.NewRequest("test"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "ok", resp.Get("result"), "should be equal")
+}
+
+func (suite *ProviderTestSuite) Test_SendContextCanceled() {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := SendContext(ctx, suite.provider, suite.provider.rpc.NewRequest("test"))
+	assert.Equal(suite.T(), context.DeadlineExceeded, err, "should be equal")
+	close(suite.provider.release)
+
+	_, err = SendContext(ctx, suite.provider, suite.provider.rpc.NewRequest("test"))
+	assert.Equal(suite.T(), context.DeadlineExceeded, err, "should be equal")
+}
+
+func (suite *ProviderTestSuite) SetupTest() {
+	suite.provider = &blockingProvider{rpc: rpc.GetDefaultMethod(), release: make(chan struct{})}
+}
+
+func Test_ProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(ProviderTestSuite))
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+var (
+	// ErrNoQuorum is matched by the QuorumError returned when not enough
+	// nodes agree on a result
+	ErrNoQuorum = errors.New("No quorum")
+	// ErrInvalidQuorum is returned for a quorum lower than 1 or higher than
+	// the number of nodes
+	ErrInvalidQuorum = errors.New("Quorum must be between 1 and the number of nodes")
+)
+
+// QuorumNode is a node of a QuorumProvider
+type QuorumNode struct {
+	// Name identifies the node in reports
+	Name     string
+	Provider Provider
+}
+
+// QuorumAnswer is what a node answered to a request
+type QuorumAnswer struct {
+	Node string
+	// Result is the decoded result, valid if Err is nil
+	Result interface{}
+	// Err is the transport error or the error returned by the node
+	Err error
+}
+
+func (answer QuorumAnswer) String() string {
+	if answer.Err != nil {
+		return fmt.Sprintf("%s: error %v", answer.Node, answer.Err)
+	}
+	result, _ := json.Marshal(answer.Result)
+	return fmt.Sprintf("%s: %s", answer.Node, result)
+}
+
+// QuorumError reports the answers of the nodes when fewer than Quorum of them
+// agree on a result
+type QuorumError struct {
+	Method  string
+	Quorum  int
+	Answers []QuorumAnswer
+}
+
+func (err *QuorumError) Error() string {
+	answers := make([]string, len(err.Answers))
+	for i, answer := range err.Answers {
+		answers[i] = answer.String()
+	}
+	return fmt.Sprintf("No quorum of %d for %s, %s", err.Quorum, err.Method, strings.Join(answers, ", "))
+}
+
+// Is makes errors.Is(err, ErrNoQuorum) true
+func (err *QuorumError) Is(target error) bool {
+	return target == ErrNoQuorum
+}
+
+// QuorumOption configures a QuorumProvider
+type QuorumOption func(*QuorumProvider)
+
+// WithDisagreementHook sets a function called with the answers of the nodes
+// whenever they do not all agree, whether the quorum is reached or not.
+func WithDisagreementHook(hook func(method string, answers []QuorumAnswer)) QuorumOption {
+	return func(provider *QuorumProvider) {
+		provider.onDisagreement = hook
+	}
+}
+
+// QuorumProvider sends idempotent reads (see IdempotentMethods) to every node
+// and only returns a result once at least quorum nodes answered the same
+// decoded result, or the same error. Other requests are only sent to the first
+// node.
+type QuorumProvider struct {
+	nodes          []QuorumNode
+	quorum         int
+	onDisagreement func(string, []QuorumAnswer)
+}
+
+// NewQuorumProvider creates a provider requiring quorum of nodes to agree. It
+// fails with ErrInvalidQuorum unless 1 <= quorum <= len(nodes).
+func NewQuorumProvider(quorum int, nodes []QuorumNode, options ...QuorumOption) (*QuorumProvider, error) {
+	if quorum < 1 || quorum > len(nodes) {
+		return nil, ErrInvalidQuorum
+	}
+	provider := &QuorumProvider{nodes: nodes, quorum: quorum}
+	for _, option := range options {
+		option(provider)
+	}
+	return provider, nil
+}
+
+// IsConnected reports whether at least quorum nodes are connected
+func (provider *QuorumProvider) IsConnected() bool {
+	connected := 0
+	for _, node := range provider.nodes {
+		if node.Provider.IsConnected() {
+			connected++
+		}
+	}
+	return connected > 0 && connected >= provider.quorum
+}
+
+// Send sends request to the nodes and returns the response agreed on
+func (provider *QuorumProvider) Send(request rpc.Request) (rpc.Response, error) {
+	return provider.SendContext(context.Background(), request)
+}
+
+// SendContext is like Send but with a context.
+func (provider *QuorumProvider) SendContext(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+	if len(provider.nodes) == 0 {
+		return nil, ErrNoProviders
+	}
+
+	method, _ := request.Get("method").(string)
+	if !IdempotentMethods[method] {
+		return SendContext(ctx, provider.nodes[0].Provider, request)
+	}
+
+	answers := make([]QuorumAnswer, len(provider.nodes))
+	responses := make([]rpc.Response, len(provider.nodes))
+	var wg sync.WaitGroup
+	for i, node := range provider.nodes {
+		wg.Add(1)
+		go func(i int, node QuorumNode) {
+			defer wg.Done()
+			answers[i].Node = node.Name
+			responses[i], answers[i].Err = SendContext(ctx, node.Provider, cloneRequest(request))
+			if answers[i].Err != nil {
+				responses[i] = nil
+				return
+			}
+			if answers[i].Err = responses[i].Error(); answers[i].Err == nil {
+				answers[i].Result = responses[i].Get("result")
+			}
+		}(i, node)
+	}
+	wg.Wait()
+
+	// Group the answers by their canonical encoding. The errors returned by
+	// the nodes vote too, so that an agreed error such as a revert reaches
+	// the caller as it would from a single node.
+	votes := make(map[string][]int)
+	best := ""
+	for i, answer := range answers {
+		if responses[i] == nil {
+			continue
+		}
+		var encoded []byte
+		var err error
+		if answer.Err != nil {
+			var rpcErr *rpc.JSONRPCError
+			if errors.As(answer.Err, &rpcErr) {
+				encoded, err = json.Marshal(rpcErr)
+			} else {
+				encoded = []byte(answer.Err.Error())
+			}
+			encoded = append([]byte("error "), encoded...)
+		} else {
+			encoded, err = json.Marshal(answer.Result)
+		}
+		if err != nil {
+			answers[i].Err = err
+			continue
+		}
+		key := string(encoded)
+		votes[key] = append(votes[key], i)
+		if len(votes[key]) > len(votes[best]) {
+			best = key
+		}
+	}
+
+	if len(votes[best]) != len(answers) && provider.onDisagreement != nil {
+		provider.onDisagreement(method, answers)
+	}
+	if len(votes[best]) == 0 || len(votes[best]) < provider.quorum {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		return nil, &QuorumError{Method: method, Quorum: provider.quorum, Answers: answers}
+	}
+	return responses[votes[best][0]], nil
+}
+
+// cloneRequest copies request for a node, so that the nodes don't share it
+func cloneRequest(request rpc.Request) rpc.Request {
+	if req, ok := request.(*rpc.JSONRPCRequest); ok {
+		clone := *req
+		clone.Params = append([]interface{}(nil), req.Params...)
+		return &clone
+	}
+	return request
+}
+
+func (provider *QuorumProvider) GetRPCMethod() rpc.RPC {
+	if len(provider.nodes) == 0 {
+		return rpc.GetDefaultMethod()
+	}
+	return provider.nodes[0].Provider.GetRPCMethod()
+}
@@ -0,0 +1,193 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type QuorumProviderTestSuite struct {
+	suite.Suite
+	servers []*httptest.Server
+}
+
+// node starts a server answering every request with result, or failing if
+// result is nil. A *rpc.JSONRPCError result is returned as the error of the
+// node.
+func (suite *QuorumProviderTestSuite) node(name string, result interface{}) QuorumNode {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if result == nil {
+			http.Error(w, "down", http.StatusBadGateway)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		req := rpc.JSONRPCRequest{}
+		json.Unmarshal(body, &req)
+		resp := rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier, Result: result}
+		if rpcErr, ok := result.(*rpc.JSONRPCError); ok {
+			resp.Result, resp.Err = nil, rpcErr
+		}
+		jsonBlob, _ := json.Marshal(resp)
+		w.Write(jsonBlob)
+	}))
+	suite.servers = append(suite.servers, server)
+	return QuorumNode{Name: name, Provider: NewHTTPProvider(server.URL, nil)}
+}
+
+func (suite *QuorumProviderTestSuite) Test_Agree() {
+	disagreements := 0
+	provider, _ := NewQuorumProvider(2, []QuorumNode{
+		suite.node("a", "0x10"),
+		suite.node("b", "0x10"),
+		suite.node("c", "0x10"),
+	}, WithDisagreementHook(func(string, []QuorumAnswer) { disagreements++ }))
+
+	resp, err := provider.Send(provider.GetRPCMethod().NewRequest("mc_getBalance"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "0x10", resp.Get("result"), "should be equal")
+	assert.EqualValues(suite.T(), 0, disagreements, "should be equal")
+}
+
+func (suite *QuorumProviderTestSuite) Test_Majority() {
+	reported := []QuorumAnswer{}
+	provider, _ := NewQuorumProvider(2, []QuorumNode{
+		suite.node("a", map[string]interface{}{"balance": "0x10", "nonce": "0x1"}),
+		suite.node("b", map[string]interface{}{"balance": "0x20", "nonce": "0x1"}),
+		suite.node("c", map[string]interface{}{"nonce": "0x1", "balance": "0x10"}),
+	}, WithDisagreementHook(func(method string, answers []QuorumAnswer) {
+		assert.EqualValues(suite.T(), "mc_call", method, "should be equal")
+		reported = answers
+	}))
+
+	resp, err := provider.Send(provider.GetRPCMethod().NewRequest("mc_call"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "0x10", resp.Get("result").(map[string]interface{})["balance"], "should be equal")
+	if assert.Len(suite.T(), reported, 3) {
+		assert.EqualValues(suite.T(), "b", reported[1].Node, "should be equal")
+		assert.EqualValues(suite.T(), "0x20", reported[1].Result.(map[string]interface{})["balance"], "should be equal")
+	}
+}
+
+func (suite *QuorumProviderTestSuite) Test_AgreedError() {
+	reverted := &rpc.JSONRPCError{Code: 3, Message: "execution reverted", Data: json.RawMessage(`"0x08c379a0"`)}
+	provider, _ := NewQuorumProvider(2, []QuorumNode{
+		suite.node("a", reverted),
+		suite.node("b", "0x10"),
+		suite.node("c", reverted),
+	})
+
+	resp, err := provider.Send(provider.GetRPCMethod().NewRequest("mc_call"))
+	suite.Require().NoError(err)
+	assert.True(suite.T(), errors.Is(resp.Error(), rpc.ErrExecutionReverted), "should be the error of the nodes")
+
+	// errors only vote with the same ones
+	provider, _ = NewQuorumProvider(2, []QuorumNode{
+		suite.node("a", reverted),
+		suite.node("b", &rpc.JSONRPCError{Code: 3, Message: "execution reverted"}),
+		suite.node("c", nil),
+	})
+	_, err = provider.Send(provider.GetRPCMethod().NewRequest("mc_call"))
+	assert.True(suite.T(), errors.Is(err, ErrNoQuorum), "should be no quorum")
+}
+
+func (suite *QuorumProviderTestSuite) Test_NoQuorum() {
+	provider, _ := NewQuorumProvider(2, []QuorumNode{
+		suite.node("a", "0x10"),
+		suite.node("b", "0x20"),
+		suite.node("c", nil),
+	})
+
+	_, err := provider.Send(provider.GetRPCMethod().NewRequest("mc_getBalance"))
+	assert.True(suite.T(), errors.Is(err, ErrNoQuorum), "should be no quorum")
+
+	var quorumErr *QuorumError
+	if assert.True(suite.T(), errors.As(err, &quorumErr), "should be a QuorumError") {
+		assert.EqualValues(suite.T(), "mc_getBalance", quorumErr.Method, "should be equal")
+		assert.EqualValues(suite.T(), "0x10", quorumErr.Answers[0].Result, "should be equal")
+		assert.EqualValues(suite.T(), "0x20", quorumErr.Answers[1].Result, "should be equal")
+		assert.EqualValues(suite.T(), "c", quorumErr.Answers[2].Node, "should be equal")
+		assert.Error(suite.T(), quorumErr.Answers[2].Err, "should have failed")
+	}
+	assert.Contains(suite.T(), err.Error(), `a: "0x10", b: "0x20", c: error`, "should name the nodes")
+}
+
+func (suite *QuorumProviderTestSuite) Test_Writes() {
+	provider, _ := NewQuorumProvider(2, []QuorumNode{
+		suite.node("a", "0x1"),
+		suite.node("b", "0x2"),
+	})
+
+	resp, err := provider.Send(provider.GetRPCMethod().NewRequest("mc_sendRawTransaction"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "0x1", resp.Get("result"), "should be sent to the first node only")
+}
+
+func (suite *QuorumProviderTestSuite) Test_IsConnected() {
+	nodes := []QuorumNode{suite.node("a", true), suite.node("b", true)}
+	suite.servers[1].Close()
+	provider, _ := NewQuorumProvider(2, nodes)
+	assert.False(suite.T(), provider.IsConnected(), "should not be connected")
+
+	provider, _ = NewQuorumProvider(1, nodes)
+	assert.True(suite.T(), provider.IsConnected(), "should be connected")
+}
+
+func (suite *QuorumProviderTestSuite) Test_InvalidQuorum() {
+	nodes := []QuorumNode{suite.node("a", true), suite.node("b", true)}
+	for _, quorum := range []int{-1, 0, 3} {
+		_, err := NewQuorumProvider(quorum, nodes)
+		assert.Equal(suite.T(), ErrInvalidQuorum, err, "should be equal")
+	}
+	_, err := NewQuorumProvider(1, nil)
+	assert.Equal(suite.T(), ErrInvalidQuorum, err, "should be equal")
+}
+
+func (suite *QuorumProviderTestSuite) SetupTest() {
+	suite.servers = nil
+}
+
+func (suite *QuorumProviderTestSuite) TearDownTest() {
+	for _, server := range suite.servers {
+		server.Close()
+	}
+}
+
+func Test_QuorumProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(QuorumProviderTestSuite))
+}
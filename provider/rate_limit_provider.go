@@ -0,0 +1,263 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+// ErrRateLimited is returned in fail-fast mode when a request would exceed
+// the limits of a RateLimitProvider
+var ErrRateLimited = errors.New("Rate limit exceeded")
+
+// RateLimitOption configures a RateLimitProvider
+type RateLimitOption func(*RateLimitProvider)
+
+// WithRate limits requests to rps per second on average, with bursts of up to
+// burst requests. Zero rps disables the limit.
+func WithRate(rps float64, burst int) RateLimitOption {
+	return func(provider *RateLimitProvider) {
+		provider.rate = rps
+		provider.burst = float64(burst)
+	}
+}
+
+// WithMaxInFlight limits the number of requests waiting for a response. Zero
+// disables the limit.
+func WithMaxInFlight(n int) RateLimitOption {
+	return func(provider *RateLimitProvider) {
+		provider.maxInFlight = n
+	}
+}
+
+// WithMethodWeight sets how many requests of the rate a call to method counts
+// for. Methods default to 1.
+func WithMethodWeight(method string, weight float64) RateLimitOption {
+	return func(provider *RateLimitProvider) {
+		provider.weights[method] = weight
+	}
+}
+
+// WithFailFast makes requests exceeding the limits fail with ErrRateLimited
+// instead of waiting.
+func WithFailFast() RateLimitOption {
+	return func(provider *RateLimitProvider) {
+		provider.failFast = true
+	}
+}
+
+// RateLimitProvider limits the rate and the concurrency of the requests sent
+// through the wrapped provider. The rate is enforced with a token bucket.
+type RateLimitProvider struct {
+	provider    Provider
+	rate        float64
+	burst       float64
+	maxInFlight int
+	weights     map[string]float64
+	failFast    bool
+
+	mu       sync.Mutex
+	tokens   float64
+	last     time.Time
+	inFlight chan struct{}
+}
+
+// NewRateLimitProvider wraps provider with the given limits
+func NewRateLimitProvider(provider Provider, options ...RateLimitOption) *RateLimitProvider {
+	rateLimitProvider := &RateLimitProvider{
+		provider: provider,
+		weights:  make(map[string]float64),
+	}
+	for _, option := range options {
+		option(rateLimitProvider)
+	}
+	if rateLimitProvider.burst < 1 {
+		rateLimitProvider.burst = 1
+	}
+	rateLimitProvider.tokens = rateLimitProvider.burst
+	rateLimitProvider.last = time.Now()
+	if rateLimitProvider.maxInFlight > 0 {
+		rateLimitProvider.inFlight = make(chan struct{}, rateLimitProvider.maxInFlight)
+	}
+	return rateLimitProvider
+}
+
+// Unwrap returns the wrapped provider
+func (provider *RateLimitProvider) Unwrap() Provider {
+	return provider.provider
+}
+
+func (provider *RateLimitProvider) IsConnected() bool {
+	return provider.provider.IsConnected()
+}
+
+// Send sends request once the limits allow it
+func (provider *RateLimitProvider) Send(request rpc.Request) (rpc.Response, error) {
+	return provider.SendContext(context.Background(), request)
+}
+
+// SendContext is like Send but gives up waiting when ctx is done.
+func (provider *RateLimitProvider) SendContext(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+	release, err := provider.acquire(ctx, provider.weight(request))
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return SendContext(ctx, provider.provider, request)
+}
+
+// SendBatch sends requests in one round trip if the wrapped provider supports
+// it, or one after the other otherwise. A batch counts for the weights of all
+// its requests, but for a single request in flight.
+func (provider *RateLimitProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	return provider.SendBatchContext(context.Background(), requests)
+}
+
+// SendBatchContext is like SendBatch but with a context.
+func (provider *RateLimitProvider) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	batchProvider, ok := provider.provider.(BatchProvider)
+	if !ok {
+		responses := make([]rpc.Response, len(requests))
+		for i, request := range requests {
+			response, err := provider.SendContext(ctx, request)
+			if err != nil {
+				return nil, err
+			}
+			responses[i] = response
+		}
+		return responses, nil
+	}
+
+	weight := 0.0
+	for _, request := range requests {
+		weight += provider.weight(request)
+	}
+	release, err := provider.acquire(ctx, weight)
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	return batchProvider.SendBatchContext(ctx, requests)
+}
+
+func (provider *RateLimitProvider) GetRPCMethod() rpc.RPC {
+	return provider.provider.GetRPCMethod()
+}
+
+func (provider *RateLimitProvider) weight(request rpc.Request) float64 {
+	method, _ := request.Get("method").(string)
+	if weight, ok := provider.weights[method]; ok {
+		return weight
+	}
+	return 1
+}
+
+// acquire waits for a slot in flight and for weight tokens. The returned
+// function frees the slot.
+func (provider *RateLimitProvider) acquire(ctx context.Context, weight float64) (func(), error) {
+	release := func() {}
+	if provider.inFlight != nil {
+		if provider.failFast {
+			select {
+			case provider.inFlight <- struct{}{}:
+			default:
+				return nil, ErrRateLimited
+			}
+		} else {
+			select {
+			case provider.inFlight <- struct{}{}:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+		release = func() { <-provider.inFlight }
+	}
+
+	if err := provider.take(ctx, weight); err != nil {
+		release()
+		return nil, err
+	}
+	return release, nil
+}
+
+// take removes weight tokens from the bucket, waiting for them to be
+// refilled. Requests heavier than the burst are let through once the bucket
+// is full.
+func (provider *RateLimitProvider) take(ctx context.Context, weight float64) error {
+	if provider.rate <= 0 {
+		return nil
+	}
+
+	provider.mu.Lock()
+	now := time.Now()
+	provider.tokens += now.Sub(provider.last).Seconds() * provider.rate
+	if provider.tokens > provider.burst {
+		provider.tokens = provider.burst
+	}
+	provider.last = now
+
+	needed := weight
+	if needed > provider.burst {
+		needed = provider.burst
+	}
+	if provider.tokens >= needed {
+		provider.tokens -= weight
+		provider.mu.Unlock()
+		return nil
+	}
+	if provider.failFast {
+		provider.mu.Unlock()
+		return ErrRateLimited
+	}
+
+	// Reserve the tokens now so that later requests queue behind this one.
+	wait := time.Duration((needed - provider.tokens) / provider.rate * float64(time.Second))
+	provider.tokens -= weight
+	provider.mu.Unlock()
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		provider.mu.Lock()
+		provider.tokens += weight
+		provider.mu.Unlock()
+		return ctx.Err()
+	}
+}
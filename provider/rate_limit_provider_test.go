@@ -0,0 +1,142 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type RateLimitProviderTestSuite struct {
+	suite.Suite
+	blocking *blockingProvider
+}
+
+func (suite *RateLimitProviderTestSuite) request(method string) rpc.Request {
+	return suite.blocking.rpc.NewRequest(method)
+}
+
+func (suite *RateLimitProviderTestSuite) Test_Rate() {
+	close(suite.blocking.release)
+	provider := NewRateLimitProvider(suite.blocking, WithRate(100, 1))
+
+	start := time.Now()
+	for i := 0; i < 6; i++ {
+		_, err := provider.Send(suite.request("mc_blockNumber"))
+		assert.NoError(suite.T(), err, "Should be no error")
+	}
+	assert.True(suite.T(), time.Since(start) >= 45*time.Millisecond, "should be throttled")
+}
+
+func (suite *RateLimitProviderTestSuite) Test_FailFast() {
+	close(suite.blocking.release)
+	provider := NewRateLimitProvider(suite.blocking, WithRate(1, 2), WithFailFast())
+
+	for i := 0; i < 2; i++ {
+		_, err := provider.Send(suite.request("mc_blockNumber"))
+		assert.NoError(suite.T(), err, "Should be no error")
+	}
+	_, err := provider.Send(suite.request("mc_blockNumber"))
+	assert.Equal(suite.T(), ErrRateLimited, err, "should be equal")
+}
+
+func (suite *RateLimitProviderTestSuite) Test_MethodWeight() {
+	close(suite.blocking.release)
+	provider := NewRateLimitProvider(suite.blocking, WithRate(1, 5), WithFailFast(), WithMethodWeight("mc_getLogs", 4))
+
+	_, err := provider.Send(suite.request("mc_getLogs"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = provider.Send(suite.request("mc_getLogs"))
+	assert.Equal(suite.T(), ErrRateLimited, err, "should be equal")
+	_, err = provider.Send(suite.request("mc_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+}
+
+func (suite *RateLimitProviderTestSuite) Test_WaitCanceled() {
+	close(suite.blocking.release)
+	provider := NewRateLimitProvider(suite.blocking, WithRate(1, 1))
+	_, err := provider.Send(suite.request("mc_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = provider.SendContext(ctx, suite.request("mc_blockNumber"))
+	assert.Equal(suite.T(), context.DeadlineExceeded, err, "should be equal")
+}
+
+func (suite *RateLimitProviderTestSuite) Test_MaxInFlight() {
+	provider := NewRateLimitProvider(suite.blocking, WithMaxInFlight(1))
+	done := make(chan error)
+	go func() {
+		_, err := provider.Send(suite.request("mc_blockNumber"))
+		done <- err
+	}()
+
+	// Wait for the first request to hold the slot.
+	assert.Eventually(suite.T(), func() bool { return len(provider.inFlight) == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := provider.SendContext(ctx, suite.request("mc_blockNumber"))
+	assert.Equal(suite.T(), context.DeadlineExceeded, err, "should be equal")
+
+	failFast := NewRateLimitProvider(suite.blocking, WithMaxInFlight(1), WithFailFast())
+	failFast.inFlight <- struct{}{}
+	_, err = failFast.Send(suite.request("mc_blockNumber"))
+	assert.Equal(suite.T(), ErrRateLimited, err, "should be equal")
+
+	close(suite.blocking.release)
+	assert.NoError(suite.T(), <-done, "Should be no error")
+	_, err = provider.Send(suite.request("mc_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+}
+
+func (suite *RateLimitProviderTestSuite) Test_SendBatch() {
+	close(suite.blocking.release)
+	provider := NewRateLimitProvider(suite.blocking, WithRate(1, 2), WithFailFast())
+
+	_, err := provider.SendBatch([]rpc.Request{suite.request("mc_blockNumber"), suite.request("mc_gasPrice")})
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = provider.SendBatch([]rpc.Request{suite.request("mc_blockNumber")})
+	assert.Equal(suite.T(), ErrRateLimited, err, "should be equal")
+}
+
+func (suite *RateLimitProviderTestSuite) SetupTest() {
+	suite.blocking = &blockingProvider{rpc: rpc.GetDefaultMethod(), release: make(chan struct{})}
+}
+
+func Test_RateLimitProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(RateLimitProviderTestSuite))
+}
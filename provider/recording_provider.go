@@ -0,0 +1,277 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+// cassetteEntry is a line of a cassette: a request and what it got back, or a
+// batch of requests sent in one round trip
+type cassetteEntry struct {
+	Method   string          `json:"method,omitempty"`
+	Params   json.RawMessage `json:"params,omitempty"`
+	Response json.RawMessage `json:"response,omitempty"`
+	// Error is the message of the error returned instead of a response
+	Error string `json:"error,omitempty"`
+	// ErrorKind tells which error of errorKinds, HTTPError ("http") or
+	// rpc.JSONRPCError ("rpc") the error was, so that the replayed one
+	// matches it too
+	ErrorKind string `json:"errorKind,omitempty"`
+	// ErrorDetail holds the fields of an HTTPError or rpc.JSONRPCError
+	ErrorDetail json.RawMessage `json:"errorDetail,omitempty"`
+	// Batch holds the requests of a batch, the error being that of the
+	// whole batch
+	Batch []*cassetteEntry `json:"batch,omitempty"`
+
+	// key identifies the request(s) when replaying
+	key string
+}
+
+// errorKinds are the sentinel errors recorded by name
+var errorKinds = map[string]error{
+	"connection_lost":   ErrConnectionLost,
+	"provider_closed":   ErrProviderClosed,
+	"no_quorum":         ErrNoQuorum,
+	"deadline_exceeded": context.DeadlineExceeded,
+	"canceled":          context.Canceled,
+}
+
+// setError records err in entry
+func (entry *cassetteEntry) setError(err error) error {
+	entry.Error = err.Error()
+
+	var httpErr *HTTPError
+	var rpcErr *rpc.JSONRPCError
+	var detail interface{}
+	switch {
+	case errors.As(err, &httpErr):
+		entry.ErrorKind, detail = "http", httpErr
+	case errors.As(err, &rpcErr):
+		entry.ErrorKind, detail = "rpc", rpcErr
+	default:
+		for kind, kindErr := range errorKinds {
+			if errors.Is(err, kindErr) {
+				entry.ErrorKind = kind
+				break
+			}
+		}
+		return nil
+	}
+
+	var marshalErr error
+	entry.ErrorDetail, marshalErr = json.Marshal(detail)
+	return marshalErr
+}
+
+// replayedError is a recorded error, matching the error of its kind
+type replayedError struct {
+	message string
+	kind    error
+}
+
+func (err *replayedError) Error() string {
+	return err.message
+}
+
+func (err *replayedError) Unwrap() error {
+	return err.kind
+}
+
+// err rebuilds the recorded error
+func (entry *cassetteEntry) err() error {
+	var kind error
+	switch entry.ErrorKind {
+	case "http":
+		httpErr := &HTTPError{}
+		if json.Unmarshal(entry.ErrorDetail, httpErr) == nil {
+			kind = httpErr
+		}
+	case "rpc":
+		rpcErr := &rpc.JSONRPCError{}
+		if json.Unmarshal(entry.ErrorDetail, rpcErr) == nil {
+			kind = rpcErr
+		}
+	default:
+		kind = errorKinds[entry.ErrorKind]
+	}
+
+	if kind == nil {
+		return errors.New(entry.Error)
+	}
+	if kind.Error() == entry.Error {
+		return kind
+	}
+	return &replayedError{message: entry.Error, kind: kind}
+}
+
+// RecordingProvider writes every request sent through the wrapped provider,
+// with its response, to a cassette of JSON lines which ReplayProvider serves
+// back. Notifications of subscriptions are not recorded. Like in logs, the
+// params and results of sensitive methods (see IsSensitiveMethod) are
+// redacted.
+type RecordingProvider struct {
+	provider Provider
+
+	mu      sync.Mutex
+	encoder *json.Encoder
+	err     error
+}
+
+// NewRecordingProvider records the requests sent through provider to w
+func NewRecordingProvider(provider Provider, w io.Writer) *RecordingProvider {
+	return &RecordingProvider{provider: provider, encoder: json.NewEncoder(w)}
+}
+
+// Unwrap returns the wrapped provider
+func (provider *RecordingProvider) Unwrap() Provider {
+	return provider.provider
+}
+
+func (provider *RecordingProvider) IsConnected() bool {
+	return provider.provider.IsConnected()
+}
+
+// Send sends request through the wrapped provider and records it
+func (provider *RecordingProvider) Send(request rpc.Request) (rpc.Response, error) {
+	return provider.SendContext(context.Background(), request)
+}
+
+// SendContext is like Send but with a context.
+func (provider *RecordingProvider) SendContext(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+	response, err := SendContext(ctx, provider.provider, request)
+	provider.record(request, response, err)
+	return response, err
+}
+
+// SendBatch sends requests in one round trip if the wrapped provider supports
+// it, or one after the other otherwise, and records each of them.
+func (provider *RecordingProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	return provider.SendBatchContext(context.Background(), requests)
+}
+
+// SendBatchContext is like SendBatch but with a context.
+func (provider *RecordingProvider) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	batchProvider, ok := provider.provider.(BatchProvider)
+	if !ok {
+		responses := make([]rpc.Response, len(requests))
+		for i, request := range requests {
+			response, err := provider.SendContext(ctx, request)
+			if err != nil {
+				return nil, err
+			}
+			responses[i] = response
+		}
+		return responses, nil
+	}
+
+	responses, err := batchProvider.SendBatchContext(ctx, requests)
+	batch := &cassetteEntry{Batch: make([]*cassetteEntry, len(requests))}
+	var recordErr error
+	for i, request := range requests {
+		var response rpc.Response
+		if err == nil {
+			response = responses[i]
+		}
+		if batch.Batch[i], recordErr = provider.entry(request, response, nil); recordErr != nil {
+			break
+		}
+	}
+	if err != nil && recordErr == nil {
+		recordErr = batch.setError(err)
+	}
+	provider.write(batch, recordErr)
+	return responses, err
+}
+
+func (provider *RecordingProvider) GetRPCMethod() rpc.RPC {
+	return provider.provider.GetRPCMethod()
+}
+
+// Err returns the first error met writing the cassette
+func (provider *RecordingProvider) Err() error {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	return provider.err
+}
+
+func (provider *RecordingProvider) record(request rpc.Request, response rpc.Response, err error) {
+	entry, recordErr := provider.entry(request, response, err)
+	provider.write(entry, recordErr)
+}
+
+// entry returns the cassette entry of a request and what it got back
+func (provider *RecordingProvider) entry(request rpc.Request, response rpc.Response, err error) (*cassetteEntry, error) {
+	method, _ := request.Get("method").(string)
+	entry := &cassetteEntry{Method: method}
+
+	params, marshalErr := json.Marshal(cassetteParams(method, request.Get("params")))
+	if marshalErr != nil {
+		return nil, marshalErr
+	}
+	entry.Params = params
+	if err != nil {
+		marshalErr = entry.setError(err)
+	} else if response != nil && IsSensitiveMethod(method) && response.Error() == nil {
+		entry.Response, marshalErr = json.Marshal(&rpc.JSONRPCResponse{Version: "2.0", Identifier: response.ID(), Result: redacted})
+	} else if response != nil {
+		entry.Response = json.RawMessage(response.String())
+	}
+	return entry, marshalErr
+}
+
+// write appends entry to the cassette, unless building it failed with err
+func (provider *RecordingProvider) write(entry *cassetteEntry, err error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	if err != nil {
+		if provider.err == nil {
+			provider.err = err
+		}
+		return
+	}
+	if encodeErr := provider.encoder.Encode(entry); encodeErr != nil && provider.err == nil {
+		provider.err = encodeErr
+	}
+}
+
+// cassetteParams returns the params of a request as recorded in cassettes.
+func cassetteParams(method string, params interface{}) interface{} {
+	if IsSensitiveMethod(method) {
+		return redacted
+	}
+	return params
+}
@@ -0,0 +1,339 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+// ErrUnmatchedRequest is matched by the UnmatchedRequestError returned when a
+// request is not in the cassette
+var ErrUnmatchedRequest = errors.New("Unmatched request")
+
+// UnmatchedRequestError describes a request ReplayProvider cannot answer
+type UnmatchedRequestError struct {
+	Method string
+	Params string
+	// Expected describes the next recorded request in strict mode, if any
+	Expected string
+}
+
+func (err *UnmatchedRequestError) Error() string {
+	msg := fmt.Sprintf("Unmatched request %s %s", err.Method, err.Params)
+	if err.Expected != "" {
+		msg += ", expected " + err.Expected
+	}
+	return msg
+}
+
+// Is makes errors.Is(err, ErrUnmatchedRequest) true
+func (err *UnmatchedRequestError) Is(target error) bool {
+	return target == ErrUnmatchedRequest
+}
+
+// ReplayOption configures a ReplayProvider
+type ReplayOption func(*ReplayProvider)
+
+// WithStrictReplay requires the requests to come in the order they were
+// recorded, each one once.
+func WithStrictReplay() ReplayOption {
+	return func(provider *ReplayProvider) {
+		provider.strict = true
+	}
+}
+
+// WithReplayRPCMethod sets the RPC used to decode the recorded responses.
+// Defaults to rpc.GetDefaultMethod().
+func WithReplayRPCMethod(method rpc.RPC) ReplayOption {
+	return func(provider *ReplayProvider) {
+		provider.rpc = method
+	}
+}
+
+// ReplayProvider answers requests from a cassette written by
+// RecordingProvider, matching them on method and params.
+//
+// By default, matching is lenient: requests may come in any order, the
+// recorded answers to identical requests are served in turn and the last one
+// is repeated once they are exhausted, which suits polling. In strict mode,
+// requests must come exactly in the recorded order.
+type ReplayProvider struct {
+	rpc    rpc.RPC
+	strict bool
+
+	mu      sync.Mutex
+	entries []*cassetteEntry
+	used    []bool
+	next    int
+}
+
+// NewReplayProvider reads a cassette from r
+func NewReplayProvider(r io.Reader, options ...ReplayOption) (*ReplayProvider, error) {
+	provider := &ReplayProvider{rpc: rpc.GetDefaultMethod()}
+	for _, option := range options {
+		option(provider)
+	}
+
+	decoder := json.NewDecoder(r)
+	for decoder.More() {
+		entry := &cassetteEntry{}
+		if err := decoder.Decode(entry); err != nil {
+			return nil, err
+		}
+		entries := []*cassetteEntry{entry}
+		if entry.Batch != nil {
+			entries = entry.Batch
+		}
+		keys := make([]string, len(entries))
+		for i, e := range entries {
+			params, err := canonicalParams(e.Params)
+			if err != nil {
+				return nil, err
+			}
+			e.Params = params
+			e.key = e.Method + " " + string(params)
+			keys[i] = e.key
+		}
+		if entry.Batch != nil {
+			entry.key = batchKey(keys)
+		}
+		provider.entries = append(provider.entries, entry)
+	}
+	provider.used = make([]bool, len(provider.entries))
+	return provider, nil
+}
+
+// LoadReplayProvider reads the cassette at path
+func LoadReplayProvider(path string, options ...ReplayOption) (*ReplayProvider, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return NewReplayProvider(file, options...)
+}
+
+func (provider *ReplayProvider) IsConnected() bool {
+	return true
+}
+
+// Send answers request from the cassette
+func (provider *ReplayProvider) Send(request rpc.Request) (rpc.Response, error) {
+	key, err := requestKey(request)
+	if err != nil {
+		return nil, err
+	}
+
+	entry, err := provider.match(key)
+	if err != nil {
+		return nil, err
+	}
+	if entry.Error != "" {
+		return nil, entry.err()
+	}
+	return provider.response(request, entry)
+}
+
+// SendBatch answers requests from a batch of the cassette, with nil for the
+// requests which were not answered. Batches recorded as single requests, from
+// a provider without batches, are answered one request after the other.
+func (provider *ReplayProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	keys := make([]string, len(requests))
+	for i, request := range requests {
+		key, err := requestKey(request)
+		if err != nil {
+			return nil, err
+		}
+		keys[i] = key
+	}
+
+	entry, err := provider.match(batchKey(keys))
+	if errors.Is(err, ErrUnmatchedRequest) && !provider.batchNext() {
+		responses := make([]rpc.Response, len(requests))
+		for i, request := range requests {
+			if responses[i], err = provider.Send(request); err != nil {
+				return nil, err
+			}
+		}
+		return responses, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if entry.Error != "" {
+		return nil, entry.err()
+	}
+
+	responses := make([]rpc.Response, len(requests))
+	for i, request := range requests {
+		if len(entry.Batch[i].Response) == 0 {
+			continue
+		}
+		if responses[i], err = provider.response(request, entry.Batch[i]); err != nil {
+			return nil, err
+		}
+	}
+	return responses, nil
+}
+
+// SendBatchContext is like SendBatch, the context is not used.
+func (provider *ReplayProvider) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	return provider.SendBatch(requests)
+}
+
+func (provider *ReplayProvider) GetRPCMethod() rpc.RPC {
+	return provider.rpc
+}
+
+// Remaining returns the number of recorded requests which were not replayed
+func (provider *ReplayProvider) Remaining() int {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	remaining := 0
+	for _, used := range provider.used {
+		if !used {
+			remaining++
+		}
+	}
+	return remaining
+}
+
+// requestKey identifies request as in the cassette
+func requestKey(request rpc.Request) (string, error) {
+	method, _ := request.Get("method").(string)
+	encoded, err := json.Marshal(cassetteParams(method, request.Get("params")))
+	if err != nil {
+		return "", err
+	}
+	params, err := canonicalParams(encoded)
+	if err != nil {
+		return "", err
+	}
+	return method + " " + string(params), nil
+}
+
+// batchKey identifies a batch from the keys of its requests
+func batchKey(keys []string) string {
+	return "batch " + strings.Join(keys, ", ")
+}
+
+// batchNext reports whether a strict replay expects a batch next
+func (provider *ReplayProvider) batchNext() bool {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	return provider.strict && provider.next < len(provider.entries) && provider.entries[provider.next].Batch != nil
+}
+
+func (provider *ReplayProvider) match(key string) (*cassetteEntry, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	unmatched := func(expected string) error {
+		method, params := key, ""
+		if i := strings.Index(key, " "); i >= 0 {
+			method, params = key[:i], key[i+1:]
+		}
+		return &UnmatchedRequestError{Method: method, Params: params, Expected: expected}
+	}
+
+	if provider.strict {
+		if provider.next >= len(provider.entries) {
+			return nil, unmatched("end of cassette")
+		}
+		entry := provider.entries[provider.next]
+		if entry.key != key {
+			return nil, unmatched(entry.key)
+		}
+		provider.used[provider.next] = true
+		provider.next++
+		return entry, nil
+	}
+
+	last := -1
+	for i, entry := range provider.entries {
+		if entry.key != key {
+			continue
+		}
+		if !provider.used[i] {
+			provider.used[i] = true
+			return entry, nil
+		}
+		last = i
+	}
+	if last < 0 {
+		return nil, unmatched("")
+	}
+	return provider.entries[last], nil
+}
+
+// response decodes the recorded response with the ID of request.
+func (provider *ReplayProvider) response(request rpc.Request, entry *cassetteEntry) (rpc.Response, error) {
+	fields := make(map[string]json.RawMessage)
+	if err := json.Unmarshal(entry.Response, &fields); err != nil {
+		return nil, fmt.Errorf("Malformed recorded response for %s, %v", entry.Method, err)
+	}
+	id, _ := json.Marshal(request.ID())
+	fields["id"] = id
+
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, err
+	}
+	response := provider.rpc.NewResponse(data)
+	if response == nil {
+		return nil, fmt.Errorf("Malformed recorded response for %s", entry.Method)
+	}
+	return response, nil
+}
+
+// canonicalParams re-encodes params so that equal values compare equal. No
+// params and empty params are the same.
+func canonicalParams(params json.RawMessage) (json.RawMessage, error) {
+	var value interface{}
+	if len(params) > 0 {
+		if err := json.Unmarshal(params, &value); err != nil {
+			return nil, err
+		}
+	}
+	if list, ok := value.([]interface{}); value == nil || ok && len(list) == 0 {
+		return json.RawMessage("[]"), nil
+	}
+	return json.Marshal(value)
+}
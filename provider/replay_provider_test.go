@@ -0,0 +1,229 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type ReplayProviderTestSuite struct {
+	suite.Suite
+	node     *fakeNode
+	cassette *bytes.Buffer
+}
+
+func (suite *ReplayProviderTestSuite) send(provider Provider, method string, params ...interface{}) (rpc.Response, error) {
+	request := provider.GetRPCMethod().NewRequest(method)
+	if len(params) > 0 {
+		request.Set("params", params)
+	}
+	return provider.Send(request)
+}
+
+// record sends requests to the fake node through a RecordingProvider
+func (suite *ReplayProviderTestSuite) record() {
+	recorder := NewRecordingProvider(NewHTTPProvider(suite.node.server.URL, nil), suite.cassette)
+	_, err := suite.send(recorder, "mc_newFilter", map[string]interface{}{"fromBlock": "0x1"})
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = suite.send(recorder, "mc_getFilterChanges", "0xa")
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = suite.send(recorder, "mc_getFilterChanges", "0xb")
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	suite.node.setDown(true)
+	_, err = suite.send(recorder, "mc_blockNumber")
+	assert.Error(suite.T(), err, "should fail")
+	assert.NoError(suite.T(), recorder.Err(), "Should be no error")
+}
+
+func (suite *ReplayProviderTestSuite) Test_Record() {
+	suite.record()
+	lines := strings.Split(strings.TrimSpace(suite.cassette.String()), "\n")
+	if assert.Len(suite.T(), lines, 4) {
+		assert.Contains(suite.T(), lines[0], `"method":"mc_newFilter","params":[{"fromBlock":"0x1"}]`, "should record the request")
+		assert.Contains(suite.T(), lines[0], `"result":"0xa"`, "should record the response")
+		assert.Contains(suite.T(), lines[3], `"error":"HTTP error 502 Bad Gateway"`, "should record the error")
+	}
+}
+
+func (suite *ReplayProviderTestSuite) Test_RecordSensitive() {
+	recorder := NewRecordingProvider(NewHTTPProvider(suite.node.server.URL, nil), suite.cassette)
+	_, err := suite.send(recorder, "personal_unlockAccount", "0x8b14c0f1de8159204f841850606bf6ac36ed89b3", "hunter2", 300)
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = suite.send(recorder, "mc_sendRawTransaction", "0xf86b808504a817c800825208")
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	cassette := suite.cassette.String()
+	assert.NotContains(suite.T(), cassette, "hunter2", "should redact the password")
+	assert.NotContains(suite.T(), cassette, "0xf86b808504a817c800825208", "should redact the transaction")
+	assert.NotContains(suite.T(), cassette, `"result":"a"`, "should redact the result")
+	assert.Contains(suite.T(), cassette, `"method":"personal_unlockAccount","params":"[REDACTED]"`, "should be redacted")
+
+	provider, err := NewReplayProvider(suite.cassette, WithStrictReplay())
+	suite.Require().NoError(err)
+	resp, err := suite.send(provider, "personal_unlockAccount", "0x8b14c0f1de8159204f841850606bf6ac36ed89b3", "other", 300)
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), redacted, resp.Get("result"), "should be equal")
+}
+
+func (suite *ReplayProviderTestSuite) Test_Lenient() {
+	suite.record()
+	provider, err := NewReplayProvider(suite.cassette)
+	suite.Require().NoError(err)
+
+	resp, err := suite.send(provider, "mc_getFilterChanges", "0xb")
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), -32000, resp.(*rpc.JSONRPCResponse).Err.Code, "should replay node errors")
+
+	for i := 0; i < 2; i++ {
+		request := provider.GetRPCMethod().NewRequest("mc_newFilter")
+		request.Set("params", []interface{}{map[string]interface{}{"fromBlock": "0x1"}})
+		resp, err = provider.Send(request)
+		assert.NoError(suite.T(), err, "Should be no error")
+		assert.EqualValues(suite.T(), request.ID(), resp.ID(), "should be equal")
+		assert.EqualValues(suite.T(), "0xa", resp.Get("result"), "should be equal")
+	}
+
+	_, err = suite.send(provider, "mc_blockNumber")
+	assert.EqualError(suite.T(), err, "HTTP error 502 Bad Gateway", "should replay transport errors")
+	var httpErr *HTTPError
+	if assert.True(suite.T(), errors.As(err, &httpErr), "should be an HTTPError") {
+		assert.EqualValues(suite.T(), 502, httpErr.StatusCode, "should be equal")
+	}
+	assert.EqualValues(suite.T(), 1, provider.Remaining(), "should be equal")
+
+	_, err = suite.send(provider, "mc_getFilterChanges", "0xc")
+	assert.True(suite.T(), errors.Is(err, ErrUnmatchedRequest), "should be unmatched")
+	assert.EqualError(suite.T(), err, `Unmatched request mc_getFilterChanges ["0xc"]`, "should be equal")
+
+	// single requests answer batches too
+	request := provider.GetRPCMethod().NewRequest("mc_getFilterChanges")
+	request.Set("params", []string{"0xa"})
+	resps, err := provider.SendBatch([]rpc.Request{request})
+	suite.Require().NoError(err)
+	assert.EqualValues(suite.T(), request.ID(), resps[0].ID(), "should be equal")
+}
+
+func (suite *ReplayProviderTestSuite) Test_Strict() {
+	suite.record()
+	provider, err := NewReplayProvider(suite.cassette, WithStrictReplay())
+	suite.Require().NoError(err)
+
+	_, err = suite.send(provider, "mc_newFilter", map[string]interface{}{"fromBlock": "0x1"})
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	_, err = suite.send(provider, "mc_getFilterChanges", "0xb")
+	var unmatched *UnmatchedRequestError
+	if assert.True(suite.T(), errors.As(err, &unmatched), "should be unmatched") {
+		assert.EqualValues(suite.T(), `mc_getFilterChanges ["0xa"]`, unmatched.Expected, "should be equal")
+	}
+
+	_, err = suite.send(provider, "mc_getFilterChanges", "0xa")
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = suite.send(provider, "mc_getFilterChanges", "0xb")
+	assert.NoError(suite.T(), err, "Should be no error")
+	_, err = suite.send(provider, "mc_blockNumber")
+	assert.Error(suite.T(), err, "should replay the error")
+
+	_, err = suite.send(provider, "mc_blockNumber")
+	assert.True(suite.T(), errors.Is(err, ErrUnmatchedRequest), "should be unmatched")
+	assert.EqualValues(suite.T(), 0, provider.Remaining(), "should be equal")
+}
+
+func (suite *ReplayProviderTestSuite) Test_ErrorKinds() {
+	lost := fmt.Errorf("%w: read tcp: connection reset by peer", ErrConnectionLost)
+	recorder := NewRecordingProvider(&flakyProvider{rpc: rpc.GetDefaultMethod(), err: lost, failures: 1}, suite.cassette)
+	_, err := suite.send(recorder, "mc_blockNumber")
+	assert.Equal(suite.T(), lost, err, "should be equal")
+	_, err = suite.send(recorder, "mc_gasPrice")
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	provider, err := NewReplayProvider(suite.cassette, WithStrictReplay())
+	suite.Require().NoError(err)
+	_, err = suite.send(provider, "mc_blockNumber")
+	assert.True(suite.T(), errors.Is(err, ErrConnectionLost), "should be a lost connection")
+	assert.EqualError(suite.T(), err, lost.Error(), "should be equal")
+}
+
+func (suite *ReplayProviderTestSuite) Test_Batch() {
+	recorder := NewRecordingProvider(NewHTTPProvider(suite.node.server.URL, nil), suite.cassette)
+	method := recorder.GetRPCMethod()
+	_, err := recorder.SendBatch([]rpc.Request{method.NewRequest("mc_blockNumber"), method.NewRequest("mc_newFilter")})
+	assert.NoError(suite.T(), err, "Should be no error")
+	suite.node.setDown(true)
+	_, err = recorder.SendBatch([]rpc.Request{method.NewRequest("mc_gasPrice")})
+	assert.Error(suite.T(), err, "should fail")
+	assert.NoError(suite.T(), recorder.Err(), "Should be no error")
+	assert.Len(suite.T(), strings.Split(strings.TrimSpace(suite.cassette.String()), "\n"), 2, "should record a line per batch")
+
+	provider, err := NewReplayProvider(suite.cassette, WithStrictReplay())
+	suite.Require().NoError(err)
+	var batchProvider BatchProvider = provider
+	requests := []rpc.Request{method.NewRequest("mc_blockNumber"), method.NewRequest("mc_newFilter")}
+	resps, err := batchProvider.SendBatch(requests)
+	suite.Require().NoError(err)
+	if assert.Len(suite.T(), resps, 2) {
+		assert.EqualValues(suite.T(), requests[1].ID(), resps[1].ID(), "should be equal")
+		assert.EqualValues(suite.T(), "0xa", resps[1].Get("result"), "should be equal")
+	}
+
+	_, err = batchProvider.SendBatch([]rpc.Request{method.NewRequest("mc_blockNumber")})
+	assert.True(suite.T(), errors.Is(err, ErrUnmatchedRequest), "should be unmatched")
+	_, err = batchProvider.SendBatch([]rpc.Request{method.NewRequest("mc_gasPrice")})
+	var httpErr *HTTPError
+	assert.True(suite.T(), errors.As(err, &httpErr), "should replay the error of the batch")
+	assert.EqualValues(suite.T(), 0, provider.Remaining(), "should be equal")
+}
+
+func (suite *ReplayProviderTestSuite) Test_Malformed() {
+	_, err := NewReplayProvider(strings.NewReader("{"))
+	assert.Error(suite.T(), err, "should fail")
+}
+
+func (suite *ReplayProviderTestSuite) SetupTest() {
+	suite.node = newFakeNode("a")
+	suite.cassette = new(bytes.Buffer)
+}
+
+func (suite *ReplayProviderTestSuite) TearDownTest() {
+	suite.node.server.Close()
+}
+
+func Test_ReplayProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(ReplayProviderTestSuite))
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+// IdempotentMethods lists the read methods which RetryProvider retries by
+// default. Methods changing state on the node, like mc_sendTransaction or
+// mc_newFilter, and mc_getFilterChanges, whose result is consumed when
+// returned, are left out.
+var IdempotentMethods = map[string]bool{
+	"web3_clientVersion":                     true,
+	"web3_sha3":                              true,
+	"net_version":                            true,
+	"net_listening":                          true,
+	"net_peerCount":                          true,
+	"mc_protocolVersion":                     true,
+	"mc_syncing":                             true,
+	"mc_coinbase":                            true,
+	"mc_mining":                              true,
+	"mc_hashrate":                            true,
+	"mc_gasPrice":                            true,
+	"mc_accounts":                            true,
+	"mc_blockNumber":                         true,
+	"mc_getBalance":                          true,
+	"mc_getStorageAt":                        true,
+	"mc_getTransactionCount":                 true,
+	"mc_getBlockTransactionCountByHash":      true,
+	"mc_getBlockTransactionCountByNumber":    true,
+	"mc_getUncleCountByBlockHash":            true,
+	"mc_getUncleCountByBlockNumber":          true,
+	"mc_getCode":                             true,
+	"mc_call":                                true,
+	"mc_estimateGas":                         true,
+	"mc_getBlockByHash":                      true,
+	"mc_getBlockByNumber":                    true,
+	"mc_getTransactionByHash":                true,
+	"mc_getTransactionByBlockHashAndIndex":   true,
+	"mc_getTransactionByBlockNumberAndIndex": true,
+	"mc_getTransactionReceipt":               true,
+	"mc_getUncleByBlockHashAndIndex":         true,
+	"mc_getUncleByBlockNumberAndIndex":       true,
+	"mc_getCompilers":                        true,
+	"mc_getFilterLogs":                       true,
+	"mc_getLogs":                             true,
+}
+
+// RetryInfo describes a failed attempt about to be retried
+type RetryInfo struct {
+	Request rpc.Request
+	// Attempt is the number of the failed attempt, starting at 1
+	Attempt int
+	Err     error
+	// Delay is the time waited before the next attempt
+	Delay time.Duration
+}
+
+// RetryOption configures a RetryProvider
+type RetryOption func(*RetryProvider)
+
+// WithAttempts sets the maximum number of attempts per request, including the
+// first one. Defaults to 3.
+func WithAttempts(attempts int) RetryOption {
+	return func(provider *RetryProvider) {
+		provider.attempts = attempts
+	}
+}
+
+// WithBackoff sets the delay before the first retry, doubled after every
+// attempt up to max. Defaults to 100ms and 5s.
+func WithBackoff(initial, max time.Duration) RetryOption {
+	return func(provider *RetryProvider) {
+		provider.initialBackoff = initial
+		provider.maxBackoff = max
+	}
+}
+
+// WithJitter randomizes each delay by up to the given fraction of it, in
+// either direction, so that clients do not retry in lockstep. Defaults to 0.2.
+func WithJitter(fraction float64) RetryOption {
+	return func(provider *RetryProvider) {
+		provider.jitter = fraction
+	}
+}
+
+// WithRetryClassifier sets the function deciding whether a failed request is
+// retried. Defaults to IsRetryable.
+func WithRetryClassifier(classifier func(rpc.Request, error) bool) RetryOption {
+	return func(provider *RetryProvider) {
+		provider.classifier = classifier
+	}
+}
+
+// WithRetryHook adds a function called before every retry.
+func WithRetryHook(hook func(RetryInfo)) RetryOption {
+	return func(provider *RetryProvider) {
+		provider.hooks = append(provider.hooks, hook)
+	}
+}
+
+// RetryProvider retries the requests failing on transient errors through the
+// wrapped provider, waiting longer after every attempt. Only failures to get
+// a response are retried: errors returned by the node are final.
+type RetryProvider struct {
+	provider       Provider
+	attempts       int
+	initialBackoff time.Duration
+	maxBackoff     time.Duration
+	jitter         float64
+	classifier     func(rpc.Request, error) bool
+	hooks          []func(RetryInfo)
+
+	randMu sync.Mutex
+	rand   *rand.Rand
+}
+
+// NewRetryProvider wraps provider with retries
+func NewRetryProvider(provider Provider, options ...RetryOption) *RetryProvider {
+	retryProvider := &RetryProvider{
+		provider:       provider,
+		attempts:       3,
+		initialBackoff: 100 * time.Millisecond,
+		maxBackoff:     5 * time.Second,
+		jitter:         0.2,
+		classifier:     IsRetryable,
+		rand:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, option := range options {
+		option(retryProvider)
+	}
+	return retryProvider
+}
+
+// IsRetryable reports whether request is an idempotent read which failed on
+// a transient error: a network error, a lost connection, a timed out attempt
+// or a 5xx or 429 HTTP status.
+func IsRetryable(request rpc.Request, err error) bool {
+	method, _ := request.Get("method").(string)
+	if !IdempotentMethods[method] {
+		return false
+	}
+	return isTransient(err)
+}
+
+func isTransient(err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	// the deadline of the caller is checked by retry, this one is the
+	// timeout of a single attempt
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+	if errors.Is(err, ErrConnectionLost) || errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return true
+	}
+
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr.StatusCode >= 500 || httpErr.StatusCode == 429
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
+
+// Unwrap returns the wrapped provider
+func (provider *RetryProvider) Unwrap() Provider {
+	return provider.provider
+}
+
+func (provider *RetryProvider) IsConnected() bool {
+	return provider.provider.IsConnected()
+}
+
+// Send sends request, retrying on transient errors
+func (provider *RetryProvider) Send(request rpc.Request) (rpc.Response, error) {
+	return provider.SendContext(context.Background(), request)
+}
+
+// SendContext is like Send but gives up, including waiting for a retry, when
+// ctx is done.
+func (provider *RetryProvider) SendContext(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+	var response rpc.Response
+	err := provider.retry(ctx, func(err error) bool {
+		return provider.classifier(request, err)
+	}, func(attempt int, err error, delay time.Duration) {
+		provider.notify(RetryInfo{Request: request, Attempt: attempt, Err: err, Delay: delay})
+	}, func() (err error) {
+		response, err = SendContext(ctx, provider.provider, request)
+		return err
+	})
+	return response, err
+}
+
+// SendBatch sends requests in one round trip if the wrapped provider supports
+// it, or one after the other otherwise. A batch is only retried as a whole,
+// if every request in it may be.
+func (provider *RetryProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	return provider.SendBatchContext(context.Background(), requests)
+}
+
+// SendBatchContext is like SendBatch but with a context.
+func (provider *RetryProvider) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	batchProvider, ok := provider.provider.(BatchProvider)
+	if !ok {
+		responses := make([]rpc.Response, len(requests))
+		for i, request := range requests {
+			response, err := provider.SendContext(ctx, request)
+			if err != nil {
+				return nil, err
+			}
+			responses[i] = response
+		}
+		return responses, nil
+	}
+
+	var responses []rpc.Response
+	err := provider.retry(ctx, func(err error) bool {
+		for _, request := range requests {
+			if !provider.classifier(request, err) {
+				return false
+			}
+		}
+		return true
+	}, func(attempt int, err error, delay time.Duration) {
+		for _, request := range requests {
+			provider.notify(RetryInfo{Request: request, Attempt: attempt, Err: err, Delay: delay})
+		}
+	}, func() (err error) {
+		responses, err = batchProvider.SendBatchContext(ctx, requests)
+		return err
+	})
+	return responses, err
+}
+
+func (provider *RetryProvider) GetRPCMethod() rpc.RPC {
+	return provider.provider.GetRPCMethod()
+}
+
+// retry calls send until it succeeds, fails on an error retryable rejects,
+// runs out of attempts or ctx is done.
+func (provider *RetryProvider) retry(ctx context.Context, retryable func(error) bool, onRetry func(int, error, time.Duration), send func() error) error {
+	for attempt := 1; ; attempt++ {
+		err := send()
+		if err == nil || ctx.Err() != nil || attempt >= provider.attempts || !retryable(err) {
+			return err
+		}
+
+		delay := provider.backoff(attempt)
+		onRetry(attempt, err, delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+// backoff returns the delay after the given failed attempt.
+func (provider *RetryProvider) backoff(attempt int) time.Duration {
+	delay := provider.initialBackoff
+	for i := 1; i < attempt && delay < provider.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > provider.maxBackoff {
+		delay = provider.maxBackoff
+	}
+
+	if provider.jitter > 0 {
+		provider.randMu.Lock()
+		delta := (provider.rand.Float64()*2 - 1) * provider.jitter
+		provider.randMu.Unlock()
+		delay += time.Duration(float64(delay) * delta)
+	}
+	return delay
+}
+
+func (provider *RetryProvider) notify(info RetryInfo) {
+	for _, hook := range provider.hooks {
+		hook(info)
+	}
+}
@@ -0,0 +1,295 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// flakyProvider fails the first failures requests with err
+type flakyProvider struct {
+	rpc      rpc.RPC
+	err      error
+	mu       sync.Mutex
+	failures int
+	calls    int
+}
+
+func (provider *flakyProvider) IsConnected() bool {
+	return true
+}
+
+func (provider *flakyProvider) Send(request rpc.Request) (rpc.Response, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	provider.calls++
+	if provider.calls <= provider.failures {
+		return nil, provider.err
+	}
+	return &rpc.JSONRPCResponse{Version: "2.0", Identifier: request.ID(), Result: "ok"}, nil
+}
+
+func (provider *flakyProvider) GetRPCMethod() rpc.RPC {
+	return provider.rpc
+}
+
+type RetryProviderTestSuite struct {
+	suite.Suite
+	flaky *flakyProvider
+}
+
+func (suite *RetryProviderTestSuite) newProvider(options ...RetryOption) *RetryProvider {
+	return NewRetryProvider(suite.flaky, append([]RetryOption{WithBackoff(time.Millisecond, 4*time.Millisecond)}, options...)...)
+}
+
+func (suite *RetryProviderTestSuite) Test_RetryRead() {
+	infos := []RetryInfo{}
+	provider := suite.newProvider(WithRetryHook(func(info RetryInfo) {
+		infos = append(infos, info)
+	}))
+
+	resp, err := provider.Send(suite.flaky.rpc.NewRequest("mc_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "ok", resp.Get("result"), "should be equal")
+	assert.EqualValues(suite.T(), 3, suite.flaky.calls, "should be equal")
+	if assert.Len(suite.T(), infos, 2) {
+		assert.EqualValues(suite.T(), 1, infos[0].Attempt, "should be equal")
+		assert.EqualValues(suite.T(), 2, infos[1].Attempt, "should be equal")
+		assert.Equal(suite.T(), ErrConnectionLost, infos[1].Err, "should be equal")
+	}
+}
+
+func (suite *RetryProviderTestSuite) Test_NoRetryWrite() {
+	provider := suite.newProvider()
+
+	_, err := provider.Send(suite.flaky.rpc.NewRequest("mc_sendTransaction"))
+	assert.Equal(suite.T(), ErrConnectionLost, err, "should be equal")
+	assert.EqualValues(suite.T(), 1, suite.flaky.calls, "should be equal")
+}
+
+func (suite *RetryProviderTestSuite) Test_Attempts() {
+	suite.flaky.failures = 5
+	provider := suite.newProvider(WithAttempts(4))
+
+	_, err := provider.Send(suite.flaky.rpc.NewRequest("mc_blockNumber"))
+	assert.Equal(suite.T(), ErrConnectionLost, err, "should be equal")
+	assert.EqualValues(suite.T(), 4, suite.flaky.calls, "should be equal")
+}
+
+func (suite *RetryProviderTestSuite) Test_Classifier() {
+	suite.flaky.err = errors.New("permanent")
+	provider := suite.newProvider()
+
+	_, err := provider.Send(suite.flaky.rpc.NewRequest("mc_blockNumber"))
+	assert.EqualError(suite.T(), err, "permanent", "should be equal")
+	assert.EqualValues(suite.T(), 1, suite.flaky.calls, "should be equal")
+
+	provider = suite.newProvider(WithRetryClassifier(func(rpc.Request, error) bool { return true }))
+	_, err = provider.Send(suite.flaky.rpc.NewRequest("mc_sendTransaction"))
+	assert.NoError(suite.T(), err, "Should be no error")
+}
+
+func (suite *RetryProviderTestSuite) Test_Backoff() {
+	provider := NewRetryProvider(suite.flaky, WithBackoff(10*time.Millisecond, 30*time.Millisecond), WithJitter(0))
+	assert.EqualValues(suite.T(), 10*time.Millisecond, provider.backoff(1), "should be equal")
+	assert.EqualValues(suite.T(), 20*time.Millisecond, provider.backoff(2), "should be equal")
+	assert.EqualValues(suite.T(), 30*time.Millisecond, provider.backoff(3), "should be equal")
+
+	provider = NewRetryProvider(suite.flaky, WithBackoff(10*time.Millisecond, 30*time.Millisecond), WithJitter(0.5))
+	for i := 0; i < 100; i++ {
+		delay := provider.backoff(1)
+		assert.True(suite.T(), delay >= 5*time.Millisecond && delay <= 15*time.Millisecond, "should be within jitter")
+	}
+}
+
+func (suite *RetryProviderTestSuite) Test_SendContextCanceled() {
+	suite.flaky.failures = 5
+	provider := NewRetryProvider(suite.flaky, WithBackoff(time.Second, time.Second))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := provider.SendContext(ctx, suite.flaky.rpc.NewRequest("mc_blockNumber"))
+	assert.Equal(suite.T(), context.DeadlineExceeded, err, "should be equal")
+	assert.EqualValues(suite.T(), 1, suite.flaky.calls, "should be equal")
+}
+
+func (suite *RetryProviderTestSuite) Test_HTTPStatus() {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+		if call == 1 {
+			http.Error(w, "upstream unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer server.Close()
+
+	provider := NewRetryProvider(NewHTTPProvider(server.URL, nil), WithBackoff(time.Millisecond, time.Millisecond))
+	resp, err := provider.Send(provider.GetRPCMethod().NewRequest("mc_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "0x10", resp.Get("result"), "should be equal")
+	assert.EqualValues(suite.T(), 2, calls, "should be equal")
+}
+
+func (suite *RetryProviderTestSuite) Test_HTTPTimeout() {
+	var mu sync.Mutex
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		calls++
+		call := calls
+		mu.Unlock()
+		if call == 1 {
+			time.Sleep(100 * time.Millisecond)
+		}
+		w.Write([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x10"}`))
+	}))
+	defer server.Close()
+
+	provider := NewRetryProvider(NewHTTPProviderWithOptions(server.URL, WithTimeout(20*time.Millisecond)), WithBackoff(time.Millisecond, time.Millisecond))
+	resp, err := provider.Send(provider.GetRPCMethod().NewRequest("mc_blockNumber"))
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), "0x10", resp.Get("result"), "should be equal")
+	mu.Lock()
+	assert.EqualValues(suite.T(), 2, calls, "should be equal")
+	mu.Unlock()
+
+	// the deadline of the caller is final
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	mu.Lock()
+	calls = 0
+	mu.Unlock()
+	_, err = provider.SendContext(ctx, provider.GetRPCMethod().NewRequest("mc_blockNumber"))
+	assert.True(suite.T(), errors.Is(err, context.DeadlineExceeded), "should be equal")
+	mu.Lock()
+	assert.EqualValues(suite.T(), 1, calls, "should be equal")
+	mu.Unlock()
+}
+
+func (suite *RetryProviderTestSuite) Test_WebSocketDropped() {
+	var mu sync.Mutex
+	conns := 0
+	upgrader := websocket.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		mu.Lock()
+		conns++
+		first := conns == 1
+		mu.Unlock()
+
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			// drop the first connection in the middle of the request,
+			// without a close frame
+			if first {
+				conn.UnderlyingConn().Close()
+				return
+			}
+			req := rpc.JSONRPCRequest{}
+			json.Unmarshal(data, &req)
+			jsonBlob, _ := json.Marshal(rpc.JSONRPCResponse{Version: "2.0", Identifier: req.Identifier, Result: "0x10"})
+			conn.WriteMessage(websocket.TextMessage, jsonBlob)
+		}
+	}))
+	defer server.Close()
+
+	ws := NewWebSocketProvider(strings.Replace(server.URL, "http://", "ws://", 1), nil).(*WebSocketProvider)
+	defer ws.Close()
+	infos := []RetryInfo{}
+	provider := NewRetryProvider(ws, WithBackoff(time.Millisecond, time.Millisecond), WithRetryHook(func(info RetryInfo) {
+		infos = append(infos, info)
+	}))
+
+	resp, err := provider.Send(provider.GetRPCMethod().NewRequest("mc_blockNumber"))
+	suite.Require().NoError(err)
+	assert.EqualValues(suite.T(), "0x10", resp.Get("result"), "should be equal")
+	if assert.Len(suite.T(), infos, 1) {
+		assert.True(suite.T(), errors.Is(infos[0].Err, ErrConnectionLost), "should be a lost connection")
+	}
+}
+
+func (suite *RetryProviderTestSuite) Test_SendBatch() {
+	provider := suite.newProvider()
+	requests := []rpc.Request{
+		suite.flaky.rpc.NewRequest("mc_blockNumber"),
+		suite.flaky.rpc.NewRequest("mc_gasPrice"),
+	}
+
+	resps, err := provider.SendBatch(requests)
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), resps, 2) {
+		assert.EqualValues(suite.T(), requests[1].ID(), resps[1].ID(), "should be equal")
+	}
+}
+
+func (suite *RetryProviderTestSuite) Test_Unwrap() {
+	provider := suite.newProvider()
+	_, ok := AsSubscriber(provider)
+	assert.False(suite.T(), ok, "should not be a subscriber")
+
+	ws := &WebSocketProvider{}
+	subscriber, ok := AsSubscriber(NewRetryProvider(ws))
+	assert.True(suite.T(), ok, "should be a subscriber")
+	assert.Equal(suite.T(), ws, subscriber, "should be equal")
+}
+
+func (suite *RetryProviderTestSuite) SetupTest() {
+	suite.flaky = &flakyProvider{rpc: rpc.GetDefaultMethod(), err: ErrConnectionLost, failures: 2}
+}
+
+func Test_RetryProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(RetryProviderTestSuite))
+}
@@ -0,0 +1,508 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/caivega/chain3go/rpc"
+)
+
+var (
+	// ErrProviderClosed is returned when sending through a closed provider
+	ErrProviderClosed = errors.New("Provider is closed")
+	// ErrConnectionLost ends the subscriptions of a dropped connection
+	ErrConnectionLost = errors.New("Connection lost")
+)
+
+// messageConn is a connection exchanging whole JSON RPC messages
+type messageConn interface {
+	ReadMessage() ([]byte, error)
+	WriteMessage(data []byte) error
+	Close() error
+}
+
+// streamProvider implements the request/response correlation shared by the
+// providers that keep a persistent connection to the node. Concurrent
+// requests are multiplexed on the same connection and responses are routed
+// back to their callers by request ID, while notifications are dispatched to
+// subscriptions by subscription ID.
+type streamProvider struct {
+	rpc        rpc.RPC
+	dial       func(context.Context) (messageConn, error)
+	dispatcher *rpc.Dispatcher
+	logger     Logger
+
+	mu      sync.Mutex
+	conn    messageConn
+	dialing chan struct{}
+	pending map[uint64]chan *result
+	batches map[*pendingBatch]struct{}
+	closed  bool
+
+	writeMu sync.Mutex
+}
+
+type result struct {
+	response rpc.Response
+	err      error
+}
+
+// pendingBatch is a batch waiting for its response message. The node either
+// answers it with an array, after which the elements left unanswered get
+// nil, or rejects it with a single error.
+type pendingBatch struct {
+	ids      map[uint64]struct{}
+	answered chan struct{}
+	rejected chan error
+}
+
+// StreamOption configures a WebSocketProvider or an IPCProvider
+type StreamOption func(*streamProvider)
+
+// WithStreamLogger sets the logger receiving request and response payloads at
+// LogLevelDebug, and failures and lost connections at LogLevelWarn. Payloads of
+// sensitive methods (see IsSensitiveMethod) are redacted. Providers are silent
+// by default.
+func WithStreamLogger(logger Logger) StreamOption {
+	return func(provider *streamProvider) {
+		provider.logger = logger
+	}
+}
+
+func newStreamProvider(method rpc.RPC, dial func(context.Context) (messageConn, error), options ...StreamOption) *streamProvider {
+	if method == nil {
+		method = rpc.GetDefaultMethod()
+	}
+	provider := &streamProvider{
+		rpc:        method,
+		dial:       dial,
+		dispatcher: rpc.NewDispatcher(),
+		logger:     nopLogger{},
+		pending:    make(map[uint64]chan *result),
+		batches:    make(map[*pendingBatch]struct{}),
+	}
+	for _, option := range options {
+		option(provider)
+	}
+	return provider
+}
+
+// IsConnected ...
+func (provider *streamProvider) IsConnected() bool {
+	req := provider.rpc.NewRequest("net_listening")
+	resp, err := provider.Send(req)
+	if err != nil {
+		return false
+	}
+	listening, _ := resp.Get("result").(bool)
+	return listening
+}
+
+// Send JSON RPC request through the connection and waits for the response
+// carrying the same ID.
+func (provider *streamProvider) Send(request rpc.Request) (response rpc.Response, err error) {
+	return provider.SendContext(context.Background(), request)
+}
+
+// SendContext is like Send but stops waiting for the response when ctx is
+// done.
+func (provider *streamProvider) SendContext(ctx context.Context, request rpc.Request) (response rpc.Response, err error) {
+	method, id := request.Get("method"), request.ID()
+	sensitive := isSensitive(request)
+
+	provider.logger.Log(LogLevelDebug, "send", "method", method, "id", id, "payload", logPayload(sensitive, []byte(request.String())))
+	response, err = provider.send(ctx, request)
+	if err != nil {
+		provider.logger.Log(LogLevelWarn, "request failed", "method", method, "id", id, "error", err)
+		return nil, err
+	}
+	provider.logger.Log(LogLevelDebug, "receive", "method", method, "id", id, "payload", logPayload(sensitive, []byte(response.String())))
+	return response, nil
+}
+
+func (provider *streamProvider) send(ctx context.Context, request rpc.Request) (rpc.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resultCh := make(chan *result, 1)
+	conn, err := provider.register(ctx, request.ID(), resultCh)
+	if err != nil {
+		return nil, err
+	}
+
+	provider.writeMu.Lock()
+	err = conn.WriteMessage([]byte(request.String()))
+	provider.writeMu.Unlock()
+	if err != nil {
+		provider.unregister(request.ID())
+		return nil, provider.drop(conn, err)
+	}
+
+	select {
+	case r := <-resultCh:
+		return r.response, r.err
+	case <-ctx.Done():
+		provider.unregister(request.ID())
+		return nil, ctx.Err()
+	}
+}
+
+// SendBatch sends JSON RPC requests in a single message and waits for all of
+// their responses.
+func (provider *streamProvider) SendBatch(requests []rpc.Request) ([]rpc.Response, error) {
+	return provider.SendBatchContext(context.Background(), requests)
+}
+
+// SendBatchContext is like SendBatch but stops waiting for the responses when
+// ctx is done.
+func (provider *streamProvider) SendBatchContext(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	if len(requests) == 0 {
+		return nil, nil
+	}
+
+	// A batch containing any sensitive call is redacted as a whole.
+	sensitive := isSensitive(requests...)
+	provider.logger.Log(LogLevelDebug, "send batch", "size", len(requests), "payload", logPayload(sensitive, provider.rpc.EncodeBatch(requests)))
+	responses, err := provider.sendBatch(ctx, requests)
+	if err != nil {
+		provider.logger.Log(LogLevelWarn, "batch request failed", "size", len(requests), "error", err)
+		return nil, err
+	}
+	payloads := make([]string, len(responses))
+	for i, response := range responses {
+		payloads[i] = "null"
+		if response != nil {
+			payloads[i] = response.String()
+		}
+	}
+	provider.logger.Log(LogLevelDebug, "receive batch", "size", len(requests), "payload", logPayload(sensitive, []byte("["+strings.Join(payloads, ",")+"]")))
+	return responses, nil
+}
+
+func (provider *streamProvider) sendBatch(ctx context.Context, requests []rpc.Request) ([]rpc.Response, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	resultChs := make([]chan *result, len(requests))
+	var conn messageConn
+	for i, request := range requests {
+		resultChs[i] = make(chan *result, 1)
+		c, err := provider.register(ctx, request.ID(), resultChs[i])
+		if err != nil {
+			for _, r := range requests[:i] {
+				provider.unregister(r.ID())
+			}
+			return nil, err
+		}
+		conn = c
+	}
+
+	batch := &pendingBatch{
+		ids:      make(map[uint64]struct{}, len(requests)),
+		answered: make(chan struct{}, 1),
+		rejected: make(chan error, 1),
+	}
+	for _, request := range requests {
+		batch.ids[request.ID()] = struct{}{}
+	}
+	provider.mu.Lock()
+	provider.batches[batch] = struct{}{}
+	provider.mu.Unlock()
+	defer func() {
+		provider.mu.Lock()
+		delete(provider.batches, batch)
+		provider.mu.Unlock()
+	}()
+
+	provider.writeMu.Lock()
+	err := conn.WriteMessage(provider.rpc.EncodeBatch(requests))
+	provider.writeMu.Unlock()
+	if err != nil {
+		for _, request := range requests {
+			provider.unregister(request.ID())
+		}
+		return nil, provider.drop(conn, err)
+	}
+
+	responses := make([]rpc.Response, len(requests))
+	answered := false
+	for i, resultCh := range resultChs {
+		if answered {
+			// the response message has been delivered, what isn't there
+			// won't come
+			select {
+			case r := <-resultCh:
+				responses[i] = r.response
+			default:
+				provider.unregister(requests[i].ID())
+			}
+			continue
+		}
+		select {
+		case r := <-resultCh:
+			if r.err != nil {
+				return nil, r.err
+			}
+			responses[i] = r.response
+		case <-batch.answered:
+			answered = true
+			select {
+			case r := <-resultCh:
+				responses[i] = r.response
+			default:
+				provider.unregister(requests[i].ID())
+			}
+		case err := <-batch.rejected:
+			for _, request := range requests {
+				provider.unregister(request.ID())
+			}
+			return nil, err
+		case <-ctx.Done():
+			for _, request := range requests {
+				provider.unregister(request.ID())
+			}
+			return nil, ctx.Err()
+		}
+	}
+	return responses, nil
+}
+
+// Close closes the underlying connection. Requests waiting for a response
+// fail with ErrProviderClosed.
+func (provider *streamProvider) Close() error {
+	provider.mu.Lock()
+	if provider.closed {
+		provider.mu.Unlock()
+		return nil
+	}
+	provider.closed = true
+	conn := provider.conn
+	provider.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+	provider.drop(conn, ErrProviderClosed)
+	return nil
+}
+
+func (provider *streamProvider) GetRPCMethod() rpc.RPC {
+	return provider.rpc
+}
+
+// Subscribe returns the notifications of a subscription created on the
+// current connection. Subscriptions end when the connection is lost.
+func (provider *streamProvider) Subscribe(id string) (*rpc.Subscription, error) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+
+	if provider.closed {
+		return nil, ErrProviderClosed
+	}
+	if provider.conn == nil {
+		return nil, ErrConnectionLost
+	}
+	return provider.dispatcher.Subscribe(id), nil
+}
+
+// Unsubscribe stops delivering the notifications of a subscription
+func (provider *streamProvider) Unsubscribe(id string) {
+	provider.dispatcher.Unsubscribe(id)
+}
+
+// register records a pending request and returns the connection it should be
+// written to, dialing a new one if needed. The dial runs without holding the
+// lock, the requests arriving meanwhile wait for it or for their ctx.
+func (provider *streamProvider) register(ctx context.Context, id uint64, resultCh chan *result) (messageConn, error) {
+	provider.mu.Lock()
+	for {
+		if provider.closed {
+			provider.mu.Unlock()
+			return nil, ErrProviderClosed
+		}
+		if _, ok := provider.pending[id]; ok {
+			provider.mu.Unlock()
+			return nil, fmt.Errorf("Duplicate request id %d", id)
+		}
+
+		if conn := provider.conn; conn != nil {
+			provider.pending[id] = resultCh
+			provider.mu.Unlock()
+			return conn, nil
+		}
+
+		if dialing := provider.dialing; dialing != nil {
+			provider.mu.Unlock()
+			select {
+			case <-dialing:
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+			provider.mu.Lock()
+			continue
+		}
+
+		dialing := make(chan struct{})
+		provider.dialing = dialing
+		provider.mu.Unlock()
+		conn, err := provider.dial(ctx)
+		provider.mu.Lock()
+		provider.dialing = nil
+		close(dialing)
+		if err != nil {
+			provider.mu.Unlock()
+			provider.logger.Log(LogLevelWarn, "dial failed", "error", err)
+			return nil, err
+		}
+		if provider.closed {
+			provider.mu.Unlock()
+			conn.Close()
+			return nil, ErrProviderClosed
+		}
+		provider.conn = conn
+		go provider.read(conn)
+	}
+}
+
+func (provider *streamProvider) unregister(id uint64) {
+	provider.mu.Lock()
+	delete(provider.pending, id)
+	provider.mu.Unlock()
+}
+
+// read dispatches incoming messages to the pending requests until the
+// connection fails.
+func (provider *streamProvider) read(conn messageConn) {
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			provider.drop(conn, err)
+			return
+		}
+
+		if notification := provider.rpc.NewNotification(data); notification != nil {
+			provider.dispatcher.Dispatch(notification)
+			continue
+		}
+
+		if responses := provider.rpc.DecodeBatch(data); responses != nil {
+			for _, response := range responses {
+				provider.deliver(response)
+			}
+			provider.answered(responses)
+			continue
+		}
+
+		if response := provider.rpc.NewResponse(data); response != nil {
+			provider.deliver(response)
+		}
+	}
+}
+
+// deliver hands a response over to the request waiting for it. An error
+// response without an ID is the rejection of a batch, as the node doesn't tell
+// which one it was, the pending batches all fail. Other responses matching no
+// request, such as the late answer of an abandoned one, are dropped.
+func (provider *streamProvider) deliver(response rpc.Response) {
+	provider.mu.Lock()
+	resultCh, ok := provider.pending[response.ID()]
+	delete(provider.pending, response.ID())
+	var rejected []*pendingBatch
+	if !ok && response.ID() == 0 && response.Error() != nil {
+		for batch := range provider.batches {
+			rejected = append(rejected, batch)
+			delete(provider.batches, batch)
+		}
+	}
+	provider.mu.Unlock()
+
+	if ok {
+		resultCh <- &result{response: response}
+	}
+	for _, batch := range rejected {
+		batch.rejected <- response.Error()
+	}
+}
+
+// answered tells the batches found in a delivered response message that no
+// other response is coming for them.
+func (provider *streamProvider) answered(responses []rpc.Response) {
+	provider.mu.Lock()
+	defer provider.mu.Unlock()
+	for batch := range provider.batches {
+		for _, response := range responses {
+			if _, ok := batch.ids[response.ID()]; ok {
+				delete(provider.batches, batch)
+				batch.answered <- struct{}{}
+				break
+			}
+		}
+	}
+}
+
+// drop closes a broken connection and fails every request still waiting on it.
+// Unless the provider was closed, the error of the connection is returned as
+// an ErrConnectionLost, which is the same for every transport.
+func (provider *streamProvider) drop(conn messageConn, err error) error {
+	if err != ErrProviderClosed {
+		err = fmt.Errorf("%w: %v", ErrConnectionLost, err)
+	}
+
+	provider.mu.Lock()
+	if provider.conn != conn {
+		provider.mu.Unlock()
+		return err
+	}
+	provider.conn = nil
+	pending := provider.pending
+	provider.pending = make(map[uint64]chan *result)
+	provider.mu.Unlock()
+
+	conn.Close()
+	if err != ErrProviderClosed {
+		provider.logger.Log(LogLevelWarn, "connection lost", "error", err)
+	}
+	for _, resultCh := range pending {
+		resultCh <- &result{err: err}
+	}
+	if err == ErrProviderClosed {
+		provider.dispatcher.Close(ErrProviderClosed)
+	} else {
+		provider.dispatcher.Close(ErrConnectionLost)
+	}
+	return err
+}
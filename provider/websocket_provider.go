@@ -0,0 +1,82 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"strings"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/gorilla/websocket"
+)
+
+// WebSocketProvider provides web3 interface over a persistent websocket
+// connection.
+type WebSocketProvider struct {
+	*streamProvider
+	host string
+}
+
+// NewWebSocketProvider creates a websocket provider. The connection is
+// established on the first request and re-established after it is lost.
+func NewWebSocketProvider(host string, method rpc.RPC, options ...StreamOption) Provider {
+	if !strings.HasPrefix(host, "ws://") && !strings.HasPrefix(host, "wss://") {
+		host = "ws://" + host
+	}
+	provider := &WebSocketProvider{host: host}
+	provider.streamProvider = newStreamProvider(method, provider.dial, options...)
+	return provider
+}
+
+func (provider *WebSocketProvider) dial(ctx context.Context) (messageConn, error) {
+	conn, _, err := websocket.DefaultDialer.DialContext(ctx, provider.host, nil)
+	if err != nil {
+		return nil, err
+	}
+	return &wsConn{conn: conn}, nil
+}
+
+// wsConn carries one JSON RPC message per websocket text frame
+type wsConn struct {
+	conn *websocket.Conn
+}
+
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	_, data, err := c.conn.ReadMessage()
+	return data, err
+}
+
+func (c *wsConn) WriteMessage(data []byte) error {
+	return c.conn.WriteMessage(websocket.TextMessage, data)
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
@@ -0,0 +1,356 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/caivega/chain3go/rpc"
+	"github.com/gorilla/websocket"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type WebSocketProviderTestSuite struct {
+	suite.Suite
+	server   *httptest.Server
+	provider Provider
+}
+
+func (suite *WebSocketProviderTestSuite) Test_IsConnected() {
+	provider := suite.provider
+	assert.EqualValues(suite.T(), true, provider.IsConnected(), "should be equal")
+}
+
+func (suite *WebSocketProviderTestSuite) Test_Send() {
+	provider := suite.provider
+	req := &rpc.JSONRPCRequest{
+		Version:    "2.0",
+		Method:     "test_method",
+		Params:     nil,
+		Identifier: 10}
+	resp, err := provider.Send(req)
+
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.EqualValues(suite.T(), req.Identifier, resp.ID(), "should be equal")
+	assert.EqualValues(suite.T(), "test_method", resp.Get("result").(string), "should be equal")
+}
+
+func (suite *WebSocketProviderTestSuite) Test_SendConcurrently() {
+	provider := suite.provider
+	method := provider.GetRPCMethod()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := "method_" + strings.Repeat("x", i)
+			req := method.NewRequest(name)
+			resp, err := provider.Send(req)
+			if assert.NoError(suite.T(), err, "Should be no error") {
+				assert.EqualValues(suite.T(), req.ID(), resp.ID(), "should be equal")
+				assert.EqualValues(suite.T(), name, resp.Get("result").(string), "should be equal")
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+func (suite *WebSocketProviderTestSuite) Test_SendBatch() {
+	provider := suite.provider.(BatchProvider)
+	method := suite.provider.GetRPCMethod()
+	requests := []rpc.Request{
+		method.NewRequest("test_method1"),
+		method.NewRequest("test_method2"),
+		method.NewRequest("test_method3"),
+	}
+	resps, err := provider.SendBatch(requests)
+
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), resps, 3) {
+		for i, resp := range resps {
+			assert.EqualValues(suite.T(), requests[i].ID(), resp.ID(), "should be equal")
+			assert.EqualValues(suite.T(), requests[i].Get("method"), resp.Get("result"), "should be equal")
+		}
+	}
+}
+
+func (suite *WebSocketProviderTestSuite) Test_SendBatchRejected() {
+	provider := suite.provider.(*WebSocketProvider)
+	method := suite.provider.GetRPCMethod()
+	requests := []rpc.Request{
+		method.NewRequest("test_method1"),
+		method.NewRequest("test_reject"),
+	}
+	_, err := provider.SendBatch(requests)
+	assert.True(suite.T(), errors.Is(err, rpc.ErrInvalidRequest), "should be the error of the node")
+	provider.mu.Lock()
+	assert.Len(suite.T(), provider.pending, 0, "should be unregistered")
+	assert.Len(suite.T(), provider.batches, 0, "should be unregistered")
+	provider.mu.Unlock()
+
+	resps, err := provider.SendBatch(requests[:1])
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Len(suite.T(), resps, 1)
+}
+
+func (suite *WebSocketProviderTestSuite) Test_SendBatchUnanswered() {
+	provider := suite.provider.(BatchProvider)
+	method := suite.provider.GetRPCMethod()
+	requests := []rpc.Request{
+		method.NewRequest("test_method1"),
+		method.NewRequest("test_silent"),
+	}
+	resps, err := provider.SendBatchContext(context.Background(), requests)
+	suite.Require().NoError(err)
+	if assert.Len(suite.T(), resps, 2) {
+		assert.EqualValues(suite.T(), "test_method1", resps[0].Get("result"), "should be equal")
+		assert.Nil(suite.T(), resps[1], "should be nil")
+	}
+	p := suite.provider.(*WebSocketProvider)
+	p.mu.Lock()
+	assert.Len(suite.T(), p.pending, 0, "should be unregistered")
+	p.mu.Unlock()
+}
+
+func (suite *WebSocketProviderTestSuite) Test_LateErrorKeepsBatches() {
+	provider := suite.provider.(*WebSocketProvider)
+	method := suite.provider.GetRPCMethod()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Millisecond)
+	defer cancel()
+	_, err := provider.SendContext(ctx, method.NewRequest("test_late_error"))
+	assert.Equal(suite.T(), context.DeadlineExceeded, err, "should be equal")
+
+	// the error of the abandoned request arrives while the batch is pending
+	resps, err := provider.SendBatch([]rpc.Request{method.NewRequest("test_wait")})
+	assert.NoError(suite.T(), err, "Should be no error")
+	if assert.Len(suite.T(), resps, 1) {
+		assert.EqualValues(suite.T(), "test_wait", resps[0].Get("result"), "should be equal")
+	}
+}
+
+func (suite *WebSocketProviderTestSuite) Test_SendContext() {
+	provider := suite.provider.(*WebSocketProvider)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	_, err := provider.SendContext(ctx, provider.GetRPCMethod().NewRequest("test_silent"))
+	assert.Equal(suite.T(), context.DeadlineExceeded, err, "should be equal")
+	provider.mu.Lock()
+	assert.Len(suite.T(), provider.pending, 0, "should be unregistered")
+	provider.mu.Unlock()
+}
+
+func (suite *WebSocketProviderTestSuite) Test_SlowDial() {
+	dialed := make(chan struct{})
+	provider := newStreamProvider(nil, func(ctx context.Context) (messageConn, error) {
+		close(dialed)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	errCh := make(chan error, 1)
+	go func() {
+		_, err := provider.SendContext(ctx, provider.GetRPCMethod().NewRequest("net_listening"))
+		errCh <- err
+	}()
+
+	// the provider isn't locked while dialing
+	<-dialed
+	_, err := provider.Subscribe("0x1")
+	assert.Equal(suite.T(), ErrConnectionLost, err, "should be equal")
+	assert.Equal(suite.T(), context.DeadlineExceeded, <-errCh, "should give up with its context")
+}
+
+func (suite *WebSocketProviderTestSuite) Test_Reconnect() {
+	provider := suite.provider.(*WebSocketProvider)
+	assert.True(suite.T(), provider.IsConnected(), "should be connected")
+
+	// Break the connection underneath, the next request dials again.
+	provider.mu.Lock()
+	provider.conn.Close()
+	provider.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+	assert.True(suite.T(), provider.IsConnected(), "should be reconnected")
+}
+
+func (suite *WebSocketProviderTestSuite) Test_Close() {
+	provider := suite.provider.(*WebSocketProvider)
+	assert.True(suite.T(), provider.IsConnected(), "should be connected")
+	assert.NoError(suite.T(), provider.Close(), "Should be no error")
+
+	_, err := provider.Send(provider.GetRPCMethod().NewRequest("net_listening"))
+	assert.Equal(suite.T(), ErrProviderClosed, err, "should be equal")
+}
+
+func (suite *WebSocketProviderTestSuite) Test_Logger() {
+	var mu sync.Mutex
+	entries := []string{}
+	logger := LoggerFunc(func(level LogLevel, msg string, keyvals ...interface{}) {
+		mu.Lock()
+		defer mu.Unlock()
+		entries = append(entries, fmt.Sprint(append([]interface{}{level, msg}, keyvals...)...))
+	})
+	provider := NewWebSocketProvider(strings.Replace(suite.server.URL, "http://", "ws://", 1), nil, WithStreamLogger(logger)).(*WebSocketProvider)
+	defer provider.Close()
+	method := provider.GetRPCMethod()
+
+	request := method.NewRequest("test_method")
+	request.Set("params", []string{"visible"})
+	_, err := provider.Send(request)
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	request = method.NewRequest("personal_unlockAccount")
+	request.Set("params", []string{"0x01", "secret"})
+	_, err = provider.SendBatch([]rpc.Request{method.NewRequest("test_method"), request})
+	assert.NoError(suite.T(), err, "Should be no error")
+
+	provider.mu.Lock()
+	provider.conn.Close()
+	provider.mu.Unlock()
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if assert.Len(suite.T(), entries, 5) {
+		assert.Contains(suite.T(), entries[0], "visible", "should log the payload")
+		for _, entry := range entries[2:4] {
+			assert.NotContains(suite.T(), entry, "secret", "should redact the payload")
+			assert.Contains(suite.T(), entry, "[REDACTED]", "should redact the payload")
+		}
+		assert.Contains(suite.T(), entries[4], "connection lost", "should log the lost connection")
+	}
+}
+
+func (suite *WebSocketProviderTestSuite) Test_GetRPCMethod() {
+	provider := suite.provider
+	assert.NotNil(suite.T(), provider.GetRPCMethod(), "should be equal")
+}
+
+func (suite *WebSocketProviderTestSuite) SetupTest() {
+	upgrader := websocket.Upgrader{}
+	suite.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		// Answer every request from its own goroutine, after a random delay,
+		// so that responses come back out of order.
+		var writeMu sync.Mutex
+		for {
+			_, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			reqs := []rpc.JSONRPCRequest{}
+			if err := json.Unmarshal(data, &reqs); err == nil {
+				if len(reqs) > 1 && reqs[1].Method == "test_reject" {
+					writeMu.Lock()
+					conn.WriteMessage(websocket.TextMessage, []byte(`{"jsonrpc":"2.0","id":null,"error":{"code":-32600,"message":"Invalid request"}}`))
+					writeMu.Unlock()
+					continue
+				}
+				// Answer batches in reverse order.
+				resps := []rpc.JSONRPCResponse{}
+				wait := false
+				for i := len(reqs) - 1; i >= 0; i-- {
+					switch reqs[i].Method {
+					case "test_silent":
+						continue
+					case "test_wait":
+						wait = true
+					}
+					resps = append(resps, rpc.JSONRPCResponse{Version: "2.0", Identifier: reqs[i].Identifier, Result: reqs[i].Method})
+				}
+				go func() {
+					if wait {
+						time.Sleep(50 * time.Millisecond)
+					}
+					jsonBlob, _ := json.Marshal(resps)
+					writeMu.Lock()
+					conn.WriteMessage(websocket.TextMessage, jsonBlob)
+					writeMu.Unlock()
+				}()
+				continue
+			}
+			go func(data []byte) {
+				req := rpc.JSONRPCRequest{}
+				resp := rpc.JSONRPCResponse{Version: "2.0"}
+				if err := json.Unmarshal(data, &req); err != nil {
+					resp.Result = "error"
+				} else {
+					resp.Identifier = req.Identifier
+					switch req.Method {
+					case "test_silent":
+						return
+					case "test_late_error":
+						time.Sleep(20 * time.Millisecond)
+						resp.Err = &rpc.JSONRPCError{Code: -32000, Message: "late"}
+					case "net_listening":
+						resp.Result = true
+					default:
+						resp.Result = req.Method
+					}
+				}
+				time.Sleep(time.Duration(rand.Intn(5)) * time.Millisecond)
+				jsonBlob, _ := json.Marshal(resp)
+				writeMu.Lock()
+				conn.WriteMessage(websocket.TextMessage, jsonBlob)
+				writeMu.Unlock()
+			}(data)
+		}
+	}))
+	suite.provider = NewWebSocketProvider(strings.Replace(suite.server.URL, "http://", "ws://", 1), rpc.GetDefaultMethod())
+}
+
+func (suite *WebSocketProviderTestSuite) TearDownTest() {
+	suite.provider.(*WebSocketProvider).Close()
+	suite.server.Close()
+}
+
+func Test_WebSocketProviderTestSuite(t *testing.T) {
+	suite.Run(t, new(WebSocketProviderTestSuite))
+}
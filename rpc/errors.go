@@ -0,0 +1,178 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+// Standard JSON-RPC error codes
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+	CodeLimitExceeded  = -32005
+	// CodeExecutionReverted is returned by nodes reporting revert data
+	CodeExecutionReverted = 3
+)
+
+// Sentinel errors matched by a JSONRPCError with errors.Is
+var (
+	ErrParse                  = errors.New("parse error")
+	ErrInvalidRequest         = errors.New("invalid request")
+	ErrMethodNotFound         = errors.New("method not found")
+	ErrInvalidParams          = errors.New("invalid params")
+	ErrInternal               = errors.New("internal error")
+	ErrLimitExceeded          = errors.New("limit exceeded")
+	ErrNonceTooLow            = errors.New("nonce too low")
+	ErrNonceTooHigh           = errors.New("nonce too high")
+	ErrInsufficientFunds      = errors.New("insufficient funds")
+	ErrIntrinsicGas           = errors.New("intrinsic gas too low")
+	ErrGasLimit               = errors.New("exceeds block gas limit")
+	ErrUnderpriced            = errors.New("transaction underpriced")
+	ErrReplacementUnderpriced = errors.New("replacement transaction underpriced")
+	ErrAlreadyKnown           = errors.New("already known")
+	ErrExecutionReverted      = errors.New("execution reverted")
+	ErrFilterNotFound         = errors.New("filter not found")
+)
+
+var codeErrors = map[int64]error{
+	CodeParseError:        ErrParse,
+	CodeInvalidRequest:    ErrInvalidRequest,
+	CodeMethodNotFound:    ErrMethodNotFound,
+	CodeInvalidParams:     ErrInvalidParams,
+	CodeInternalError:     ErrInternal,
+	CodeLimitExceeded:     ErrLimitExceeded,
+	CodeExecutionReverted: ErrExecutionReverted,
+}
+
+// messageErrors are recognized by the message of the node, as nodes report
+// them with a generic code. The order matters where messages overlap.
+var messageErrors = []struct {
+	fragment string
+	err      error
+}{
+	{"nonce too low", ErrNonceTooLow},
+	{"nonce too high", ErrNonceTooHigh},
+	{"insufficient funds", ErrInsufficientFunds},
+	{"intrinsic gas too low", ErrIntrinsicGas},
+	{"exceeds block gas limit", ErrGasLimit},
+	{"replacement transaction underpriced", ErrReplacementUnderpriced},
+	{"transaction underpriced", ErrUnderpriced},
+	{"already known", ErrAlreadyKnown},
+	{"known transaction", ErrAlreadyKnown},
+	{"execution reverted", ErrExecutionReverted},
+	{"filter not found", ErrFilterNotFound},
+	{"method not found", ErrMethodNotFound},
+	{"query returned more than", ErrLimitExceeded},
+	{"block range", ErrLimitExceeded},
+	{"response size exceeded", ErrLimitExceeded},
+	{"too many results", ErrLimitExceeded},
+}
+
+// Kind returns the sentinel error matching err, or nil if it has none.
+func (err *JSONRPCError) Kind() error {
+	message := strings.ToLower(err.Message)
+	for _, m := range messageErrors {
+		if strings.Contains(message, m.fragment) {
+			return m.err
+		}
+	}
+	return codeErrors[err.Code]
+}
+
+// Is makes errors.Is match err with the sentinel error of its kind
+func (err *JSONRPCError) Is(target error) bool {
+	kind := err.Kind()
+	return kind != nil && kind == target
+}
+
+// RevertData returns the data returned by a reverted call, if the node
+// reported it.
+func (err *JSONRPCError) RevertData() ([]byte, bool) {
+	if err.Kind() != ErrExecutionReverted || len(err.Data) == 0 {
+		return nil, false
+	}
+
+	var data string
+	if json.Unmarshal(err.Data, &data) != nil || !strings.HasPrefix(data, "0x") {
+		return nil, false
+	}
+	decoded, decodeErr := hex.DecodeString(data[2:])
+	if decodeErr != nil {
+		return nil, false
+	}
+	return decoded, true
+}
+
+// revertSelector is the selector of Error(string), used by require and revert
+// with a reason
+var revertSelector = []byte{0x08, 0xc3, 0x79, 0xa0}
+
+// RevertReason returns the reason given to require or revert by a reverted
+// call, if the node reported it.
+func (err *JSONRPCError) RevertReason() (string, bool) {
+	data, ok := err.RevertData()
+	if !ok || len(data) < 4+64 || string(data[:4]) != string(revertSelector) {
+		return "", false
+	}
+
+	// The reason is ABI encoded as an offset, a length and the bytes.
+	data = data[4:]
+	size := uint64(len(data))
+	offset := binary.BigEndian.Uint64(data[24:32])
+	if offset > size-32 {
+		return "", false
+	}
+	length := binary.BigEndian.Uint64(data[offset+24 : offset+32])
+	if length > size-offset-32 {
+		return "", false
+	}
+	return string(data[offset+32 : offset+32+length]), true
+}
+
+// IsNodeError reports whether err was returned by the node, as opposed to a
+// transport error, which means that no response was received.
+func IsNodeError(err error) bool {
+	var rpcErr *JSONRPCError
+	return errors.As(err, &rpcErr)
+}
+
+// IsTransportError reports whether err means that no response was received
+// from the node.
+func IsTransportError(err error) bool {
+	return err != nil && !IsNodeError(err)
+}
@@ -0,0 +1,131 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+// revertData is the revert data of require(false, "not enough")
+const revertData = "0x08c379a0" +
+	"0000000000000000000000000000000000000000000000000000000000000020" +
+	"000000000000000000000000000000000000000000000000000000000000000a" +
+	"6e6f7420656e6f75676800000000000000000000000000000000000000000000"
+
+type ErrorsTestSuite struct {
+	suite.Suite
+	rpc RPC
+}
+
+func (suite *ErrorsTestSuite) decode(body string) error {
+	resp := suite.rpc.NewResponse([]byte(body))
+	suite.Require().NotNil(resp)
+	return resp.Error()
+}
+
+func (suite *ErrorsTestSuite) Test_Kinds() {
+	cases := []struct {
+		code    int64
+		message string
+		kind    error
+	}{
+		{-32000, "nonce too low", ErrNonceTooLow},
+		{-32000, "Nonce too high", ErrNonceTooHigh},
+		{-32000, "insufficient funds for gas * price + value", ErrInsufficientFunds},
+		{-32000, "replacement transaction underpriced", ErrReplacementUnderpriced},
+		{-32000, "transaction underpriced", ErrUnderpriced},
+		{-32000, "known transaction: 0x01", ErrAlreadyKnown},
+		{-32000, "filter not found", ErrFilterNotFound},
+		{-32601, "The method mc_foo does not exist/is not available", ErrMethodNotFound},
+		{-32602, "invalid argument 0", ErrInvalidParams},
+		{-32005, "limit exceeded", ErrLimitExceeded},
+		{-32000, "query returned more than 10000 results", ErrLimitExceeded},
+		{-32000, "something else", nil},
+	}
+
+	for _, c := range cases {
+		err := &JSONRPCError{Code: c.code, Message: c.message}
+		assert.Equal(suite.T(), c.kind, err.Kind(), c.message)
+		if c.kind != nil {
+			assert.True(suite.T(), errors.Is(err, c.kind), c.message)
+			assert.True(suite.T(), errors.Is(fmt.Errorf("wrapped: %w", err), c.kind), c.message)
+		}
+	}
+	assert.False(suite.T(), errors.Is(&JSONRPCError{Code: -32000, Message: "nonce too low"}, ErrNonceTooHigh), "should not match")
+}
+
+func (suite *ErrorsTestSuite) Test_Data() {
+	err := suite.decode(`{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"execution reverted: not enough","data":"` + revertData + `"}}`)
+	assert.True(suite.T(), errors.Is(err, ErrExecutionReverted), "should be reverted")
+
+	var rpcErr *JSONRPCError
+	if assert.True(suite.T(), errors.As(err, &rpcErr), "should be a JSONRPCError") {
+		assert.EqualValues(suite.T(), `"`+revertData+`"`, string(rpcErr.Data), "should be equal")
+		data, ok := rpcErr.RevertData()
+		assert.True(suite.T(), ok, "should have revert data")
+		assert.Len(suite.T(), data, 100, "should be equal")
+		reason, ok := rpcErr.RevertReason()
+		assert.True(suite.T(), ok, "should have a reason")
+		assert.EqualValues(suite.T(), "not enough", reason, "should be equal")
+	}
+
+	err = suite.decode(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"execution reverted"}}`)
+	_, ok := err.(*JSONRPCError).RevertData()
+	assert.False(suite.T(), ok, "should have no revert data")
+
+	err = suite.decode(`{"jsonrpc":"2.0","id":1,"error":{"code":3,"message":"execution reverted","data":"0x08c379a0ffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffffff"}}`)
+	_, ok = err.(*JSONRPCError).RevertReason()
+	assert.False(suite.T(), ok, "should reject malformed data")
+
+	err = suite.decode(`{"jsonrpc":"2.0","id":1,"error":{"code":-32000,"message":"nonce too low","data":{"nonce":5}}}`)
+	assert.EqualValues(suite.T(), `{"nonce":5}`, string(err.(*JSONRPCError).Data), "should preserve the data")
+	assert.Contains(suite.T(), (&JSONRPCResponse{Err: err.(*JSONRPCError)}).String(), `"data":{"nonce":5}`, "should be equal")
+}
+
+func (suite *ErrorsTestSuite) Test_Transport() {
+	nodeErr := &JSONRPCError{Code: -32000, Message: "nonce too low"}
+	assert.True(suite.T(), IsNodeError(fmt.Errorf("send: %w", nodeErr)), "should be a node error")
+	assert.False(suite.T(), IsTransportError(nodeErr), "should not be a transport error")
+	assert.True(suite.T(), IsTransportError(errors.New("connection refused")), "should be a transport error")
+	assert.False(suite.T(), IsTransportError(nil), "should not be a transport error")
+}
+
+func (suite *ErrorsTestSuite) SetupTest() {
+	suite.rpc = GetDefaultMethod()
+}
+
+func Test_ErrorsTestSuite(t *testing.T) {
+	suite.Run(t, new(ErrorsTestSuite))
+}
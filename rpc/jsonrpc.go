@@ -30,6 +30,7 @@
 package rpc
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"math/big"
@@ -62,9 +63,9 @@ func (req *JSONRPCRequest) Set(key string, value interface{}) {
 		paramType := reflect.TypeOf(value)
 		switch paramType.Kind() {
 		case reflect.Slice, reflect.Array:
-			args := value.([]interface{})
-			for _, arg := range args {
-				req.Params = append(req.Params, arg)
+			args := reflect.ValueOf(value)
+			for i := 0; i < args.Len(); i++ {
+				req.Params = append(req.Params, args.Index(i).Interface())
 			}
 		default:
 			req.Params = append(req.Params, value)
@@ -102,10 +103,13 @@ func (req *JSONRPCRequest) ID() uint64 {
 
 // -----------------------------------------------------------------------------
 
-// JSONRPCError ...
+// JSONRPCError is an error returned by the node. It matches the sentinel
+// errors of this package with errors.Is.
 type JSONRPCError struct {
 	Code    int64  `json:"code"`
 	Message string `json:"message"`
+	// Data holds the optional data of the error, such as revert data
+	Data json.RawMessage `json:"data,omitempty"`
 }
 
 func (err *JSONRPCError) Error() string {
@@ -157,6 +161,49 @@ func (resp *JSONRPCResponse) Error() error {
 
 // -----------------------------------------------------------------------------
 
+// JSONRPCNotificationParams ...
+type JSONRPCNotificationParams struct {
+	Subscription string      `json:"subscription"`
+	Result       interface{} `json:"result"`
+}
+
+// JSONRPCNotification ...
+type JSONRPCNotification struct {
+	Version string                    `json:"jsonrpc"`
+	Method  string                    `json:"method"`
+	Params  JSONRPCNotificationParams `json:"params"`
+}
+
+// Get ...
+func (n *JSONRPCNotification) Get(key string) interface{} {
+	k := strings.ToLower(key)
+	switch k {
+	case "version":
+		return n.Version
+	case "method":
+		return n.Method
+	case "subscription":
+		return n.Params.Subscription
+	case "result":
+		return n.Params.Result
+	}
+
+	return nil
+}
+
+// String ...
+func (n *JSONRPCNotification) String() string {
+	jsonBytes, _ := json.Marshal(n)
+	return string(jsonBytes)
+}
+
+// Subscription ...
+func (n *JSONRPCNotification) Subscription() string {
+	return n.Params.Subscription
+}
+
+// -----------------------------------------------------------------------------
+
 // JSONRPC ...
 type JSONRPC struct {
 	messageID uint64
@@ -192,6 +239,48 @@ func (rpc *JSONRPC) NewResponse(data []byte) Response {
 	return nil
 }
 
+// EncodeBatch encodes requests as a JSON array to be sent in one round trip.
+func (rpc *JSONRPC) EncodeBatch(requests []Request) []byte {
+	buffer := new(bytes.Buffer)
+	buffer.WriteByte('[')
+	for i, request := range requests {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		buffer.WriteString(request.String())
+	}
+	buffer.WriteByte(']')
+	return buffer.Bytes()
+}
+
+// DecodeBatch returns nil if data is not a JSON array of responses. The
+// responses are in the order the server sent them, which may differ from the
+// order of the requests.
+func (rpc *JSONRPC) DecodeBatch(data []byte) []Response {
+	var resps []*JSONRPCResponse
+	if err := json.Unmarshal(data, &resps); err != nil || resps == nil {
+		return nil
+	}
+
+	responses := make([]Response, 0, len(resps))
+	for _, resp := range resps {
+		if resp != nil {
+			responses = append(responses, resp)
+		}
+	}
+	return responses
+}
+
+// NewNotification returns nil if data is not a subscription notification.
+func (rpc *JSONRPC) NewNotification(data []byte) Notification {
+	n := &JSONRPCNotification{}
+	if err := json.Unmarshal(data, &n); err == nil && strings.HasSuffix(n.Method, "_subscription") {
+		return n
+	}
+
+	return nil
+}
+
 func (rpc *JSONRPC) newID() uint64 {
 	return atomic.AddUint64(&rpc.messageID, 1)
 }
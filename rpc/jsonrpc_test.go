@@ -99,6 +99,26 @@ func (suite *JSONRPCTestSuite) Test_NewResponse() {
 	assert.Nil(suite.T(), resp)
 }
 
+func (suite *JSONRPCTestSuite) Test_Batch() {
+	rpc := suite.rpc
+	req1 := rpc.NewRequest("test1", "arg1")
+	req2 := rpc.NewRequest("test2")
+	assert.EqualValues(suite.T(),
+		`[{"jsonrpc":"2.0","method":"test1","params":["arg1"],"id":1},{"jsonrpc":"2.0","method":"test2","params":[],"id":2}]`,
+		string(rpc.EncodeBatch([]Request{req1, req2})), "Should be equal")
+
+	resps := rpc.DecodeBatch([]byte(`[{"jsonrpc":"2.0","id":2,"result":"result2"},{"jsonrpc":"2.0","id":1,"error":{"code":-32601,"message":"method not found"}}]`))
+	if assert.Len(suite.T(), resps, 2) {
+		assert.EqualValues(suite.T(), 2, resps[0].ID(), "Should be equal")
+		assert.EqualValues(suite.T(), "result2", resps[0].Get("result"), "Should be equal")
+		assert.EqualValues(suite.T(), 1, resps[1].ID(), "Should be equal")
+		assert.EqualError(suite.T(), resps[1].Error(), "method not found")
+	}
+
+	assert.Nil(suite.T(), rpc.DecodeBatch([]byte(`{"jsonrpc":"2.0","id":1,"result":"result1"}`)))
+	assert.Nil(suite.T(), rpc.DecodeBatch([]byte("xxx")))
+}
+
 func (suite *JSONRPCTestSuite) SetupTest() {
 	suite.rpc = NewJSONRPC()
 }
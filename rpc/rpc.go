@@ -45,11 +45,22 @@ type Response interface {
 	Error() error
 }
 
+// Notification defines basic methods of a notification pushed by the server
+// for a subscription
+type Notification interface {
+	Get(key string) interface{}
+	String() string
+	Subscription() string
+}
+
 // RPC defines basic methods of variety RPCs
 type RPC interface {
 	Name() string
 	NewRequest(method string, args ...interface{}) Request
 	NewResponse(data []byte) Response
+	NewNotification(data []byte) Notification
+	EncodeBatch(requests []Request) []byte
+	DecodeBatch(data []byte) []Response
 }
 
 // GetDefaultMethod ...
@@ -0,0 +1,189 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrSubscriptionQueueOverflow is reported when notifications are not
+	// consumed fast enough
+	ErrSubscriptionQueueOverflow = errors.New("Subscription queue overflow")
+)
+
+const (
+	subscriptionBufferSize = 128
+	earlyTimeout           = 10 * time.Second
+)
+
+// Subscription receives the notifications pushed by the server for one
+// subscription ID.
+type Subscription struct {
+	id   string
+	ch   chan Notification
+	err  error
+	once sync.Once
+}
+
+// ID returns the subscription identifier
+func (sub *Subscription) ID() string {
+	return sub.id
+}
+
+// Notifications returns the channel notifications are delivered on. It is
+// closed when the subscription ends.
+func (sub *Subscription) Notifications() <-chan Notification {
+	return sub.ch
+}
+
+// Err returns the reason the subscription ended, or nil if it was
+// unsubscribed. It is only meaningful once the notification channel is closed.
+func (sub *Subscription) Err() error {
+	return sub.err
+}
+
+func (sub *Subscription) close(err error) {
+	sub.once.Do(func() {
+		sub.err = err
+		close(sub.ch)
+	})
+}
+
+// Dispatcher routes notifications to subscriptions by subscription ID.
+// Notifications arriving before their subscription is registered, which may
+// happen right after the subscribe call returns, are kept for a while in case
+// it is.
+type Dispatcher struct {
+	mu            sync.Mutex
+	subscriptions map[string]*Subscription
+	early         map[string]*earlyNotifications
+}
+
+type earlyNotifications struct {
+	since         time.Time
+	notifications []Notification
+}
+
+// NewDispatcher creates an empty dispatcher
+func NewDispatcher() *Dispatcher {
+	return &Dispatcher{
+		subscriptions: make(map[string]*Subscription),
+		early:         make(map[string]*earlyNotifications),
+	}
+}
+
+// Subscribe registers a subscription for the given ID
+func (d *Dispatcher) Subscribe(id string) *Subscription {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if sub, ok := d.subscriptions[id]; ok {
+		return sub
+	}
+
+	sub := &Subscription{id: id, ch: make(chan Notification, subscriptionBufferSize)}
+	d.subscriptions[id] = sub
+	if early, ok := d.early[id]; ok {
+		for _, n := range early.notifications {
+			sub.ch <- n
+		}
+		delete(d.early, id)
+	}
+	return sub
+}
+
+// Unsubscribe removes the subscription for the given ID and closes its
+// channel.
+func (d *Dispatcher) Unsubscribe(id string) {
+	d.mu.Lock()
+	sub, ok := d.subscriptions[id]
+	delete(d.subscriptions, id)
+	d.mu.Unlock()
+
+	if ok {
+		sub.close(nil)
+	}
+}
+
+// Dispatch delivers a notification to its subscription. It never blocks, a
+// subscription whose buffer is full is ended with
+// ErrSubscriptionQueueOverflow.
+func (d *Dispatcher) Dispatch(n Notification) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	id := n.Subscription()
+	sub, ok := d.subscriptions[id]
+	if !ok {
+		d.keepEarly(id, n)
+		return
+	}
+
+	select {
+	case sub.ch <- n:
+	default:
+		delete(d.subscriptions, id)
+		sub.close(ErrSubscriptionQueueOverflow)
+	}
+}
+
+// Close ends every subscription with the given error
+func (d *Dispatcher) Close(err error) {
+	d.mu.Lock()
+	subscriptions := d.subscriptions
+	d.subscriptions = make(map[string]*Subscription)
+	d.early = make(map[string]*earlyNotifications)
+	d.mu.Unlock()
+
+	for _, sub := range subscriptions {
+		sub.close(err)
+	}
+}
+
+func (d *Dispatcher) keepEarly(id string, n Notification) {
+	now := time.Now()
+	for earlyID, early := range d.early {
+		if now.Sub(early.since) > earlyTimeout {
+			delete(d.early, earlyID)
+		}
+	}
+
+	early, ok := d.early[id]
+	if !ok {
+		early = &earlyNotifications{since: now}
+		d.early[id] = early
+	}
+	if len(early.notifications) < subscriptionBufferSize {
+		early.notifications = append(early.notifications, n)
+	}
+}
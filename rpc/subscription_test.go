@@ -0,0 +1,121 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package rpc
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+type DispatcherTestSuite struct {
+	suite.Suite
+	rpc        RPC
+	dispatcher *Dispatcher
+}
+
+func (suite *DispatcherTestSuite) notification(id string, result interface{}) Notification {
+	return &JSONRPCNotification{
+		Version: "2.0",
+		Method:  "mc_subscription",
+		Params:  JSONRPCNotificationParams{Subscription: id, Result: result},
+	}
+}
+
+func (suite *DispatcherTestSuite) Test_NewNotification() {
+	n := suite.rpc.NewNotification([]byte(`{"jsonrpc":"2.0","method":"mc_subscription","params":{"subscription":"0x1","result":"0x2"}}`))
+	if assert.NotNil(suite.T(), n) {
+		assert.EqualValues(suite.T(), "0x1", n.Subscription(), "Should be equal")
+		assert.EqualValues(suite.T(), "0x2", n.Get("result"), "Should be equal")
+		assert.EqualValues(suite.T(), "mc_subscription", n.Get("method"), "Should be equal")
+	}
+
+	assert.Nil(suite.T(), suite.rpc.NewNotification([]byte(`{"jsonrpc":"2.0","id":1,"result":"0x2"}`)))
+	assert.Nil(suite.T(), suite.rpc.NewNotification([]byte("xxx")))
+}
+
+func (suite *DispatcherTestSuite) Test_Dispatch() {
+	sub := suite.dispatcher.Subscribe("0x1")
+	suite.dispatcher.Dispatch(suite.notification("0x1", 1))
+	suite.dispatcher.Dispatch(suite.notification("0x2", 2))
+
+	n := <-sub.Notifications()
+	assert.EqualValues(suite.T(), 1, n.Get("result"), "Should be equal")
+	assert.Len(suite.T(), sub.Notifications(), 0, "Should be empty")
+
+	suite.dispatcher.Unsubscribe("0x1")
+	_, ok := <-sub.Notifications()
+	assert.False(suite.T(), ok, "Should be closed")
+	assert.NoError(suite.T(), sub.Err(), "Should be no error")
+}
+
+func (suite *DispatcherTestSuite) Test_EarlyNotifications() {
+	suite.dispatcher.Dispatch(suite.notification("0x1", 1))
+	suite.dispatcher.Dispatch(suite.notification("0x1", 2))
+
+	sub := suite.dispatcher.Subscribe("0x1")
+	assert.EqualValues(suite.T(), 1, (<-sub.Notifications()).Get("result"), "Should be equal")
+	assert.EqualValues(suite.T(), 2, (<-sub.Notifications()).Get("result"), "Should be equal")
+}
+
+func (suite *DispatcherTestSuite) Test_Overflow() {
+	sub := suite.dispatcher.Subscribe("0x1")
+	for i := 0; i <= subscriptionBufferSize; i++ {
+		suite.dispatcher.Dispatch(suite.notification("0x1", i))
+	}
+
+	count := 0
+	for range sub.Notifications() {
+		count++
+	}
+	assert.EqualValues(suite.T(), subscriptionBufferSize, count, "Should be equal")
+	assert.Equal(suite.T(), ErrSubscriptionQueueOverflow, sub.Err(), "Should be equal")
+}
+
+func (suite *DispatcherTestSuite) Test_Close() {
+	sub := suite.dispatcher.Subscribe("0x1")
+	err := errors.New("closed")
+	suite.dispatcher.Close(err)
+
+	_, ok := <-sub.Notifications()
+	assert.False(suite.T(), ok, "Should be closed")
+	assert.Equal(suite.T(), err, sub.Err(), "Should be equal")
+}
+
+func (suite *DispatcherTestSuite) SetupTest() {
+	suite.rpc = NewJSONRPC()
+	suite.dispatcher = NewDispatcher()
+}
+
+func Test_DispatcherTestSuite(t *testing.T) {
+	suite.Run(t, new(DispatcherTestSuite))
+}
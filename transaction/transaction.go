@@ -0,0 +1,246 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+// Package transaction implements MOAC transactions, which are signed locally
+// and sent with Mc.SendRawTransaction.
+package transaction
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"math/big"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/common/rlp"
+	"github.com/caivega/chain3go/crypto"
+)
+
+// Chain identifiers of the MOAC networks
+var (
+	MainnetChainID = big.NewInt(99)
+	TestnetChainID = big.NewInt(101)
+)
+
+var (
+	// ErrInvalidSig is returned for transactions with invalid signature values
+	ErrInvalidSig = errors.New("Invalid transaction v, r, s values")
+	// ErrUnsigned is returned when the sender of an unsigned transaction is
+	// requested
+	ErrUnsigned = errors.New("Transaction is not signed")
+)
+
+// Transaction is a MOAC transaction. Besides the fields of an Ethereum
+// transaction, it has the system contract flag SysCnt, the ShardingFlag
+// selecting a MicroChain call, and the Via address of the MicroChain proxy.
+type Transaction struct {
+	Nonce    uint64
+	SysCnt   uint64
+	GasPrice *big.Int
+	Gas      uint64
+	// To is nil for contract creations
+	To           *common.Address `rlp:"nil"`
+	Value        *big.Int
+	Data         common.Data
+	ShardingFlag uint64
+	Via          *common.Address `rlp:"nil"`
+
+	// V, R and S are the signature values, with V holding the chain ID
+	V *big.Int
+	R *big.Int
+	S *big.Int
+}
+
+// NewTransaction creates an unsigned transaction
+func NewTransaction(nonce uint64, to common.Address, value *big.Int, gas uint64, gasPrice *big.Int, data []byte) *Transaction {
+	return &Transaction{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      gas,
+		To:       &to,
+		Value:    value,
+		Data:     data,
+	}
+}
+
+// NewContractCreation creates an unsigned transaction deploying a contract
+func NewContractCreation(nonce uint64, value *big.Int, gas uint64, gasPrice *big.Int, data []byte) *Transaction {
+	return &Transaction{
+		Nonce:    nonce,
+		GasPrice: gasPrice,
+		Gas:      gas,
+		Value:    value,
+		Data:     data,
+	}
+}
+
+// DecodeTransaction decodes a raw signed transaction
+func DecodeTransaction(raw []byte) (*Transaction, error) {
+	tx := &Transaction{}
+	if err := tx.UnmarshalBinary(raw); err != nil {
+		return nil, err
+	}
+	return tx, nil
+}
+
+// SignTx returns a copy of tx signed with key, protected against replays on
+// other chains when chainID isn't nil
+func SignTx(tx *Transaction, chainID *big.Int, key *ecdsa.PrivateKey) (*Transaction, error) {
+	hash, err := tx.SigHash(chainID)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := crypto.Sign(hash[:], key)
+	if err != nil {
+		return nil, err
+	}
+	return tx.WithSignature(sig, chainID)
+}
+
+// SigHash returns the hash which is signed. With a chain ID it also covers
+// the chain ID (EIP-155).
+func (tx *Transaction) SigHash(chainID *big.Int) (common.Hash, error) {
+	fields := []interface{}{
+		tx.Nonce,
+		tx.SysCnt,
+		tx.GasPrice,
+		tx.Gas,
+		tx.To,
+		tx.Value,
+		tx.Data,
+		tx.ShardingFlag,
+		tx.Via,
+	}
+	if chainID != nil && chainID.Sign() != 0 {
+		fields = append(fields, chainID, uint(0), uint(0))
+	}
+	return rlpHash(fields)
+}
+
+// WithSignature returns a copy of tx with the signature R || S || V, as
+// returned by crypto.Sign for SigHash(chainID)
+func (tx *Transaction) WithSignature(sig []byte, chainID *big.Int) (*Transaction, error) {
+	if len(sig) != crypto.SignatureLength {
+		return nil, ErrInvalidSig
+	}
+	signed := *tx
+	signed.R = new(big.Int).SetBytes(sig[:32])
+	signed.S = new(big.Int).SetBytes(sig[32:64])
+	signed.V = big.NewInt(int64(sig[64]) + 27)
+	if chainID != nil && chainID.Sign() != 0 {
+		signed.V = new(big.Int).Mul(chainID, big.NewInt(2))
+		signed.V.Add(signed.V, big.NewInt(int64(sig[64])+35))
+	}
+	return &signed, nil
+}
+
+// Signed reports whether tx has signature values
+func (tx *Transaction) Signed() bool {
+	return tx.V != nil && tx.V.Sign() != 0
+}
+
+// Protected reports whether the signature of tx covers a chain ID
+func (tx *Transaction) Protected() bool {
+	if !tx.Signed() {
+		return false
+	}
+	return !tx.V.IsUint64() || tx.V.Uint64() != 27 && tx.V.Uint64() != 28
+}
+
+// ChainID returns the chain ID of a protected transaction, or nil. It returns
+// ErrInvalidSig when V is neither 27, 28 nor at least 35.
+func (tx *Transaction) ChainID() (*big.Int, error) {
+	if !tx.Protected() {
+		return nil, nil
+	}
+	chainID := new(big.Int).Sub(tx.V, big.NewInt(35))
+	if chainID.Sign() < 0 {
+		return nil, ErrInvalidSig
+	}
+	return chainID.Rsh(chainID, 1), nil
+}
+
+// Sender recovers the address which signed tx
+func (tx *Transaction) Sender() (common.Address, error) {
+	if !tx.Signed() {
+		return common.Address{}, ErrUnsigned
+	}
+
+	chainID, err := tx.ChainID()
+	if err != nil {
+		return common.Address{}, err
+	}
+	v := new(big.Int).Sub(tx.V, big.NewInt(27))
+	if chainID != nil {
+		v.Sub(tx.V, big.NewInt(35))
+		v.Sub(v, new(big.Int).Lsh(chainID, 1))
+	}
+	if !v.IsUint64() || !crypto.ValidateSignatureValues(byte(v.Uint64()), tx.R, tx.S) {
+		return common.Address{}, ErrInvalidSig
+	}
+
+	sig := make([]byte, crypto.SignatureLength)
+	tx.R.FillBytes(sig[:32])
+	tx.S.FillBytes(sig[32:64])
+	sig[64] = byte(v.Uint64())
+
+	hash, err := tx.SigHash(chainID)
+	if err != nil {
+		return common.Address{}, err
+	}
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return common.Address{}, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Hash returns the hash of the signed transaction, which identifies it
+func (tx *Transaction) Hash() (common.Hash, error) {
+	return rlpHash(tx)
+}
+
+// rlpHash hashes the RLP encoding of v, which fails on negative numbers
+func rlpHash(v interface{}) (common.Hash, error) {
+	b, err := rlp.EncodeToBytes(v)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return crypto.Keccak256Hash(b), nil
+}
+
+// MarshalBinary returns the raw transaction, as sent to
+// Mc.SendRawTransaction
+func (tx *Transaction) MarshalBinary() ([]byte, error) {
+	return rlp.EncodeToBytes(tx)
+}
+
+// UnmarshalBinary decodes a raw transaction
+func (tx *Transaction) UnmarshalBinary(b []byte) error {
+	return rlp.DecodeBytes(b, tx)
+}
@@ -0,0 +1,219 @@
+// Copyright (c) 2016, Alan Chen
+// All rights reserved.
+//
+// Redistribution and use in source and binary forms, with or without
+// modification, are permitted provided that the following conditions are met:
+//
+// 1. Redistributions of source code must retain the above copyright notice,
+//    this list of conditions and the following disclaimer.
+//
+// 2. Redistributions in binary form must reproduce the above copyright notice,
+//    this list of conditions and the following disclaimer in the documentation
+//    and/or other materials provided with the distribution.
+//
+// 3. Neither the name of the copyright holder nor the names of its contributors
+//    may be used to endorse or promote products derived from this software
+//    without specific prior written permission.
+//
+// THIS SOFTWARE IS PROVIDED BY THE COPYRIGHT HOLDERS AND CONTRIBUTORS "AS IS"
+// AND ANY EXPRESS OR IMPLIED WARRANTIES, INCLUDING, BUT NOT LIMITED TO, THE
+// IMPLIED WARRANTIES OF MERCHANTABILITY AND FITNESS FOR A PARTICULAR PURPOSE
+// ARE DISCLAIMED. IN NO EVENT SHALL THE COPYRIGHT HOLDER OR CONTRIBUTORS BE
+// LIABLE FOR ANY DIRECT, INDIRECT, INCIDENTAL, SPECIAL, EXEMPLARY, OR
+// CONSEQUENTIAL DAMAGES (INCLUDING, BUT NOT LIMITED TO, PROCUREMENT OF
+// SUBSTITUTE GOODS OR SERVICES; LOSS OF USE, DATA, OR PROFITS; OR BUSINESS
+// INTERRUPTION) HOWEVER CAUSED AND ON ANY THEORY OF LIABILITY, WHETHER IN
+// CONTRACT, STRICT LIABILITY, OR TORT (INCLUDING NEGLIGENCE OR OTHERWISE)
+// ARISING IN ANY WAY OUT OF THE USE OF THIS SOFTWARE, EVEN IF ADVISED OF THE
+// POSSIBILITY OF SUCH DAMAGE.
+
+package transaction
+
+import (
+	"encoding/hex"
+	"math/big"
+	"testing"
+
+	"github.com/caivega/chain3go/common"
+	"github.com/caivega/chain3go/common/rlp"
+	"github.com/caivega/chain3go/crypto"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/suite"
+)
+
+const testKey = "4646464646464646464646464646464646464646464646464646464646464646"
+
+const (
+	vectorSigHash = "0x64acd161f5f0fe9fc5c5c686cde5bfaf44dabc65e8312a8b43e67f314a14c1b6"
+	vectorRaw     = "f87f01808504a817c800830186a09435353535353535353535353535353535353535358082cafe01944848484848484848484848484848484848484848" +
+		"81eea0a0c3936d89d8d0d10e035d467ce25c3769e7f3778afddf2d3e4ac0cbb146151ca0253c685148ddf39998b5a24e37dca834df3c3fc195d96857ff0663b426f15dc6"
+)
+
+type TransactionTestSuite struct {
+	suite.Suite
+}
+
+// Test_EIP155 checks the signing scheme against the example of EIP-155, which
+// signs an Ethereum transaction: the MOAC fields aside, the hashing, the
+// deterministic signature and the v value are the same.
+func (suite *TransactionTestSuite) Test_EIP155() {
+	to := common.StringToAddress("0x3535353535353535353535353535353535353535")
+	value, _ := new(big.Int).SetString("1000000000000000000", 10)
+	fields := []interface{}{uint64(9), big.NewInt(20000000000), uint64(21000), to, value, []byte{}}
+
+	b, _ := rlp.EncodeToBytes(append(fields, uint64(1), uint64(0), uint64(0)))
+	hash := crypto.Keccak256(b)
+	assert.Equal(suite.T(), "daf5a779ae972f972197303d7b574746c7ef83eadac0f2791ad23db92e4c8e53",
+		hex.EncodeToString(hash), "should be equal")
+
+	key, _ := crypto.HexToECDSA(testKey)
+	sig, err := crypto.Sign(hash, key)
+	suite.Require().NoError(err)
+	r, _ := new(big.Int).SetString("18515461264373351373200002665853028612451056578545711640558177340181847433846", 10)
+	s, _ := new(big.Int).SetString("46948507304638947509940763649030358759909902576025900602547168820602576006531", 10)
+	assert.Equal(suite.T(), r, new(big.Int).SetBytes(sig[:32]), "should be equal")
+	assert.Equal(suite.T(), s, new(big.Int).SetBytes(sig[32:64]), "should be equal")
+
+	raw, _ := rlp.EncodeToBytes(append(fields, uint64(sig[64])+1*2+35, r, s))
+	assert.Equal(suite.T(), "f86c098504a817c800825208943535353535353535353535353535353535353535880de0b6b3a7640000"+
+		"8025a028ef61340bd939bc2195fe537567866003e1a15d3c71ff63e1590620aa636276a067cbe9d8997f761aecb703304b3800ccf555c9f3dc64214b297fb1966a3b6d83",
+		hex.EncodeToString(raw), "should be equal")
+}
+
+func (suite *TransactionTestSuite) Test_Sign() {
+	key, _ := crypto.HexToECDSA(testKey)
+	to := common.StringToAddress("0x3535353535353535353535353535353535353535")
+	tx := NewTransaction(9, to, big.NewInt(1000000000000000000), 21000, big.NewInt(20000000000), []byte{})
+
+	signed, err := SignTx(tx, MainnetChainID, key)
+	suite.Require().NoError(err)
+	assert.Nil(suite.T(), tx.V, "should not change the transaction")
+	assert.True(suite.T(), signed.Protected(), "should be protected")
+	chainID, err := signed.ChainID()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), MainnetChainID, chainID, "should be equal")
+	assert.True(suite.T(), signed.V.Int64() == 233 || signed.V.Int64() == 234, "should encode the chain ID")
+
+	sender, err := signed.Sender()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), crypto.PubkeyToAddress(key.PublicKey), sender, "should be equal")
+
+	raw, err := signed.MarshalBinary()
+	suite.Require().NoError(err)
+	decoded, err := DecodeTransaction(raw)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), signed, decoded, "should be equal")
+	hash, err := signed.Hash()
+	suite.Require().NoError(err)
+	decodedHash, err := decoded.Hash()
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), hash, decodedHash, "should be equal")
+
+	// without replay protection
+	signed, err = SignTx(tx, nil, key)
+	suite.Require().NoError(err)
+	assert.False(suite.T(), signed.Protected(), "should not be protected")
+	chainID, err = signed.ChainID()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Nil(suite.T(), chainID, "should be nil")
+	sender, err = signed.Sender()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), crypto.PubkeyToAddress(key.PublicKey), sender, "should be equal")
+}
+
+// Test_Vector pins the raw encoding of a MOAC transaction using every field,
+// laid out as nonce, syscnt, gasPrice, gas, to, value, data, shardingFlag,
+// via, v, r, s, with v = 2 * 101 + 35 + recovery id on the testnet
+func (suite *TransactionTestSuite) Test_Vector() {
+	key, _ := crypto.HexToECDSA(testKey)
+	to := common.StringToAddress("0x3535353535353535353535353535353535353535")
+	via := common.StringToAddress("0x4848484848484848484848484848484848484848")
+	tx := &Transaction{
+		Nonce:        1,
+		SysCnt:       0,
+		GasPrice:     big.NewInt(20000000000),
+		Gas:          100000,
+		To:           &to,
+		Value:        big.NewInt(0),
+		Data:         common.Data{0xca, 0xfe},
+		ShardingFlag: 1,
+		Via:          &via,
+	}
+
+	hash, err := tx.SigHash(TestnetChainID)
+	suite.Require().NoError(err)
+	assert.Equal(suite.T(), vectorSigHash, hash.String(), "should be equal")
+	signed, err := SignTx(tx, TestnetChainID, key)
+	suite.Require().NoError(err)
+	raw, _ := signed.MarshalBinary()
+	assert.Equal(suite.T(), vectorRaw, hex.EncodeToString(raw), "should be equal")
+
+	b, _ := hex.DecodeString(vectorRaw)
+	decoded, err := DecodeTransaction(b)
+	suite.Require().NoError(err)
+	sender, err := decoded.Sender()
+	assert.NoError(suite.T(), err, "Should be no error")
+	assert.Equal(suite.T(), "0x9d8a62f656a8d1615c1294fd71e9cfb3e4855a4f", sender.String(), "should be equal")
+	assert.Equal(suite.T(), via, *decoded.Via, "should be equal")
+	assert.EqualValues(suite.T(), 1, decoded.ShardingFlag, "should be equal")
+}
+
+func (suite *TransactionTestSuite) Test_ContractCreation() {
+	key, _ := crypto.HexToECDSA(testKey)
+	tx := NewContractCreation(0, big.NewInt(0), 1000000, big.NewInt(1), []byte{0x60, 0x60})
+	signed, err := SignTx(tx, MainnetChainID, key)
+	suite.Require().NoError(err)
+
+	raw, _ := signed.MarshalBinary()
+	decoded, err := DecodeTransaction(raw)
+	suite.Require().NoError(err)
+	assert.Nil(suite.T(), decoded.To, "should be nil")
+	assert.Nil(suite.T(), decoded.Via, "should be nil")
+}
+
+func (suite *TransactionTestSuite) Test_InvalidSignature() {
+	_, err := (&Transaction{}).Sender()
+	assert.Equal(suite.T(), ErrUnsigned, err, "should be equal")
+
+	key, _ := crypto.HexToECDSA(testKey)
+	signed, _ := SignTx(NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil), MainnetChainID, key)
+	signed.S = new(big.Int).Sub(crypto.S256().N, signed.S)
+	_, err = signed.Sender()
+	assert.Equal(suite.T(), ErrInvalidSig, err, "should reject high s values")
+
+	// a signature for another chain recovers another sender
+	signed, _ = SignTx(NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil), MainnetChainID, key)
+	signed.V.Add(signed.V, big.NewInt(4))
+	sender, err := signed.Sender()
+	if err == nil {
+		assert.NotEqual(suite.T(), crypto.PubkeyToAddress(key.PublicKey), sender, "should be another sender")
+	}
+
+	// V below 35 other than 27 and 28 has no chain ID
+	signed, _ = SignTx(NewTransaction(0, common.Address{}, big.NewInt(0), 21000, big.NewInt(1), nil), nil, key)
+	signed.V = big.NewInt(30)
+	raw, _ := signed.MarshalBinary()
+	decoded, err := DecodeTransaction(raw)
+	suite.Require().NoError(err)
+	_, err = decoded.ChainID()
+	assert.Equal(suite.T(), ErrInvalidSig, err, "should be equal")
+	_, err = decoded.Sender()
+	assert.Equal(suite.T(), ErrInvalidSig, err, "should be equal")
+
+	_, err = DecodeTransaction([]byte{0xc0})
+	assert.Error(suite.T(), err, "should be an error")
+}
+
+func (suite *TransactionTestSuite) Test_Unencodable() {
+	key, _ := crypto.HexToECDSA(testKey)
+	tx := NewTransaction(0, common.Address{}, big.NewInt(-1), 21000, big.NewInt(1), nil)
+
+	_, err := SignTx(tx, MainnetChainID, key)
+	assert.Error(suite.T(), err, "should be an error")
+	_, err = tx.Hash()
+	assert.Error(suite.T(), err, "should be an error")
+}
+
+func Test_TransactionTestSuite(t *testing.T) {
+	suite.Run(t, new(TransactionTestSuite))
+}